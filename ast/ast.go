@@ -1,8 +1,12 @@
 package ast
 
-// Node is the base interface for all AST nodes.
+import "strings"
+
+// Node is the base interface for all AST nodes. SourceSpan returns the span of
+// source text the node was parsed from.
 type Node interface {
 	node()
+	SourceSpan() Span
 }
 
 // Expr is the interface for all expression nodes.
@@ -16,3 +20,52 @@ type Stmt interface {
 	Node
 	stmtNode()
 }
+
+// Position is a place in the source text. Line and Column start at 1 and Offset
+// is the byte offset from the start of the input. A zero Position means the node
+// was not read from the source, such as the value of the required constant maxint.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// Span is the part of the source text a node was parsed from: Start is the
+// position of its first character and End the position just past its last.
+// Every node embeds a Span, which provides its SourceSpan method.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// SourceSpan returns s, so that every node embedding a Span implements Node.
+func (s Span) SourceSpan() Span { return s }
+
+// Dialect is the variant of Pascal a program is written in. The parser records
+// the dialect it was given on the Program, so that later stages accept the same
+// language.
+type Dialect int
+
+const (
+	// ISO is Standard Pascal as defined by ISO 7185.
+	ISO Dialect = iota
+	// Turbo adds the Turbo Pascal and Free Pascal extensions: string[N],
+	// inc and dec, exit, break, continue and halt, $FF hexadecimal literals,
+	// #13 character codes, typed constants, uses clauses and // comments.
+	Turbo
+)
+
+var dialectNames = map[string]Dialect{"iso": ISO, "turbo": Turbo, "fpc": Turbo}
+
+// LookupDialect returns the dialect called name: iso, or turbo or its alias fpc.
+func LookupDialect(name string) (Dialect, bool) {
+	d, ok := dialectNames[strings.ToLower(name)]
+	return d, ok
+}
+
+func (d Dialect) String() string {
+	if d == Turbo {
+		return "Turbo Pascal"
+	}
+	return "ISO Pascal"
+}
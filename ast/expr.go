@@ -4,6 +4,7 @@ import "pastel/token"
 
 // IntegerLiteral represents an integer literal value.
 type IntegerLiteral struct {
+	Span
 	Value int
 }
 
@@ -12,6 +13,7 @@ func (*IntegerLiteral) exprNode() {}
 
 // RealLiteral represents a floating-point literal value.
 type RealLiteral struct {
+	Span
 	Value float64
 }
 
@@ -20,6 +22,7 @@ func (*RealLiteral) exprNode() {}
 
 // BooleanLiteral represents a boolean literal value.
 type BooleanLiteral struct {
+	Span
 	Value bool
 }
 
@@ -28,6 +31,7 @@ func (*BooleanLiteral) exprNode() {}
 
 // CharLiteral represents a single character literal.
 type CharLiteral struct {
+	Span
 	Value rune
 }
 
@@ -36,14 +40,51 @@ func (*CharLiteral) exprNode() {}
 
 // StringLiteral represents a string literal value.
 type StringLiteral struct {
+	Span
 	Value string
 }
 
 func (*StringLiteral) node()     {}
 func (*StringLiteral) exprNode() {}
 
+// ArrayConstant is the value of a typed constant of an array type, such as
+// (1, 2, 3). Each element is a folded constant or a structured constant.
+type ArrayConstant struct {
+	Span
+	Elements []Expr
+}
+
+func (*ArrayConstant) node()     {}
+func (*ArrayConstant) exprNode() {}
+
+// RecordConstant is the value of a typed constant of a record type, such as
+// (x: 1; y: 2).
+type RecordConstant struct {
+	Span
+	Fields []*FieldConstant
+}
+
+func (*RecordConstant) node()     {}
+func (*RecordConstant) exprNode() {}
+
+// FieldConstant gives the value of one field of a RecordConstant.
+type FieldConstant struct {
+	Span
+	Name  string
+	Value Expr
+}
+
+// NilLiteral represents the pointer constant nil.
+type NilLiteral struct {
+	Span
+}
+
+func (*NilLiteral) node()     {}
+func (*NilLiteral) exprNode() {}
+
 // BinaryExpr represents a binary expression (e.g., a + b).
 type BinaryExpr struct {
+	Span
 	Left     Expr
 	Operator token.Token
 	Right    Expr
@@ -54,8 +95,87 @@ func (*BinaryExpr) exprNode() {}
 
 // Identifier represents a variable reference.
 type Identifier struct {
+	Span
 	Value string
 }
 
 func (*Identifier) node()     {}
 func (*Identifier) exprNode() {}
+
+// UnaryExpr represents a prefix operator applied to a single operand (e.g., not a, -x).
+type UnaryExpr struct {
+	Span
+	Operator token.Token
+	Operand  Expr
+}
+
+func (*UnaryExpr) node()     {}
+func (*UnaryExpr) exprNode() {}
+
+// CallExpr represents a function call with arguments (e.g., max(a, b)).
+type CallExpr struct {
+	Span
+	Name string
+	Args []Expr
+}
+
+func (*CallExpr) node()     {}
+func (*CallExpr) exprNode() {}
+
+// IndexExpr represents an array component access (e.g., a[i]).
+// A multi-dimensional access a[i, j] is parsed as a[i][j].
+type IndexExpr struct {
+	Span
+	Array Expr
+	Index Expr
+}
+
+func (*IndexExpr) node()     {}
+func (*IndexExpr) exprNode() {}
+
+// FieldExpr represents a record field access (e.g., r.x).
+type FieldExpr struct {
+	Span
+	Record Expr
+	Field  string
+}
+
+func (*FieldExpr) node()     {}
+func (*FieldExpr) exprNode() {}
+
+// SetExpr represents a set constructor (e.g., [1, 3..5, c]). The empty set [] has no elements.
+type SetExpr struct {
+	Span
+	Elements []*SetElement
+}
+
+func (*SetExpr) node()     {}
+func (*SetExpr) exprNode() {}
+
+// SetElement is a single member or, when High is set, a range Low..High of a set constructor.
+type SetElement struct {
+	Span
+	Low  Expr
+	High Expr
+}
+
+// FormatExpr represents a write argument with a field width and, for reals,
+// a number of decimal places (e.g., x:8:2). Decimals is nil when absent.
+type FormatExpr struct {
+	Span
+	Value    Expr
+	Width    Expr
+	Decimals Expr
+}
+
+func (*FormatExpr) node()     {}
+func (*FormatExpr) exprNode() {}
+
+// DerefExpr represents the variable a pointer points to (e.g., p^).
+type DerefExpr struct {
+	Span
+	Pointer Expr
+}
+
+func (*DerefExpr) node()     {}
+func (*DerefExpr) exprNode() {}
@@ -1,17 +1,23 @@
 package ast
 
-// AssignStmt represents an assignment statement (name := value).
+// AssignStmt represents an assignment statement (target := value).
+// Target is a variable access: an Identifier, IndexExpr, FieldExpr or DerefExpr.
 type AssignStmt struct {
-	Name  string
-	Value Expr
+	Span
+	Target Expr
+	Value  Expr
 }
 
 func (*AssignStmt) node()     {}
 func (*AssignStmt) stmtNode() {}
 
-// PrintStmt represents a writeln statement.
+// PrintStmt represents a write or writeln statement. If the first argument is a file
+// variable the values are written to that file instead of standard output.
+// Arguments with a field width are FormatExprs.
 type PrintStmt struct {
-	Argument Expr
+	Span
+	Args    []Expr
+	Newline bool
 }
 
 func (*PrintStmt) node()     {}
@@ -19,6 +25,7 @@ func (*PrintStmt) stmtNode() {}
 
 // CompoundStmt represents a begin...end block.
 type CompoundStmt struct {
+	Span
 	Statements []Stmt
 }
 
@@ -27,19 +34,209 @@ func (*CompoundStmt) stmtNode() {}
 
 // VarDecl represents a variable declaration.
 type VarDecl struct {
+	Span
 	Name string
-	Type string
+	Type TypeExpr
 }
 
 func (*VarDecl) node()     {}
 func (*VarDecl) stmtNode() {}
 
 // Program represents a complete Pascal program.
+// Params lists the program parameters of the heading, such as input and output.
+// Uses lists the units named by a Turbo Pascal uses clause, and Dialect is the
+// dialect the program was parsed in.
 type Program struct {
+	Span
 	Name         string
+	Params       []string
+	Uses         []string
 	Declarations []Stmt
 	Main         *CompoundStmt
+	Dialect      Dialect
 }
 
 func (*Program) node()     {}
 func (*Program) stmtNode() {}
+
+// IfStmt represents an if-then-else statement. Else is nil when absent.
+type IfStmt struct {
+	Span
+	Condition Expr
+	Then      Stmt
+	Else      Stmt
+}
+
+func (*IfStmt) node()     {}
+func (*IfStmt) stmtNode() {}
+
+// WhileStmt represents a while-do loop.
+type WhileStmt struct {
+	Span
+	Condition Expr
+	Body      Stmt
+}
+
+func (*WhileStmt) node()     {}
+func (*WhileStmt) stmtNode() {}
+
+// RepeatStmt represents a repeat-until loop. Its body is a statement sequence.
+type RepeatStmt struct {
+	Span
+	Body      []Stmt
+	Condition Expr
+}
+
+func (*RepeatStmt) node()     {}
+func (*RepeatStmt) stmtNode() {}
+
+// ForStmt represents a for-to or for-downto loop from Initial to Final.
+type ForStmt struct {
+	Span
+	Variable string
+	Initial  Expr
+	Final    Expr
+	Downto   bool
+	Body     Stmt
+}
+
+func (*ForStmt) node()     {}
+func (*ForStmt) stmtNode() {}
+
+// Param represents a formal parameter of a procedure or function.
+// IsVar marks a variable (by-reference) parameter. A procedural or functional
+// parameter has no Type; Routine holds its heading, which has no block. A
+// conformant array parameter has no Type either; Schema holds its schema, which
+// the parameters of one group share.
+type Param struct {
+	Span
+	Name    string
+	Type    string
+	IsVar   bool
+	Routine *ProcedureDecl
+	Schema  *ConformantArray
+}
+
+// ProcedureDecl represents a procedure or function declaration with its own block.
+// ReturnType is empty for procedures. A heading with the directive forward has no
+// block: a later declaration of the same name supplies it, and that heading may
+// leave out the parameter list and the result type.
+type ProcedureDecl struct {
+	Span
+	Name         string
+	Params       []*Param
+	ReturnType   string
+	Function     bool
+	Forward      bool
+	Declarations []Stmt
+	Body         *CompoundStmt
+}
+
+func (*ProcedureDecl) node()     {}
+func (*ProcedureDecl) stmtNode() {}
+
+// IsFunction reports whether the declaration is a function rather than a procedure.
+func (d *ProcedureDecl) IsFunction() bool {
+	return d.Function
+}
+
+// CallStmt represents a procedure call statement.
+type CallStmt struct {
+	Span
+	Name string
+	Args []Expr
+}
+
+func (*CallStmt) node()     {}
+func (*CallStmt) stmtNode() {}
+
+// CaseLabel is a single case constant, or the range Low..High when High is set.
+type CaseLabel struct {
+	Span
+	Low  Expr
+	High Expr
+}
+
+// CaseBranch is one arm of a case statement: a list of labels and the statement they select.
+type CaseBranch struct {
+	Span
+	Labels []*CaseLabel
+	Body   Stmt
+}
+
+// CaseStmt represents a case statement. Else holds the optional else/otherwise part and is nil when absent.
+type CaseStmt struct {
+	Span
+	Selector Expr
+	Branches []*CaseBranch
+	Else     *CompoundStmt
+}
+
+func (*CaseStmt) node()     {}
+func (*CaseStmt) stmtNode() {}
+
+// TypedConstDecl represents a Turbo Pascal typed constant such as
+// const limit: integer = 10. Despite its name it is a variable that starts out
+// with Value and keeps its value between calls of the routine declaring it.
+// Value is a folded constant, or an ArrayConstant or RecordConstant for a
+// structured type.
+type TypedConstDecl struct {
+	Span
+	Name  string
+	Type  TypeExpr
+	Value Expr
+}
+
+func (*TypedConstDecl) node()     {}
+func (*TypedConstDecl) stmtNode() {}
+
+// ConstDecl represents a constant definition. Value is the constant's value folded to a literal at parse time.
+type ConstDecl struct {
+	Span
+	Name  string
+	Value Expr
+}
+
+func (*ConstDecl) node()     {}
+func (*ConstDecl) stmtNode() {}
+
+// WithStmt represents a with statement that opens the fields of a record variable as a scope.
+// A list of records 'with a, b do S' is parsed as 'with a do with b do S'.
+type WithStmt struct {
+	Span
+	Record Expr
+	Body   Stmt
+}
+
+func (*WithStmt) node()     {}
+func (*WithStmt) stmtNode() {}
+
+// LabelDecl represents the declaration of one label in a label declaration part.
+type LabelDecl struct {
+	Span
+	Label int
+}
+
+func (*LabelDecl) node()     {}
+func (*LabelDecl) stmtNode() {}
+
+// LabeledStmt represents a statement prefixed by a label (10: S). Stmt is nil when
+// the label prefixes an empty statement.
+type LabeledStmt struct {
+	Span
+	Label int
+	Stmt  Stmt
+}
+
+func (*LabeledStmt) node()     {}
+func (*LabeledStmt) stmtNode() {}
+
+// GotoStmt represents a goto statement. The parser only accepts a goto whose label
+// prefixes a statement it may jump to.
+type GotoStmt struct {
+	Span
+	Label int
+}
+
+func (*GotoStmt) node()     {}
+func (*GotoStmt) stmtNode() {}
@@ -0,0 +1,215 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeExpr is the interface for all type denoter nodes.
+type TypeExpr interface {
+	Node
+	typeNode()
+	String() string
+}
+
+// NamedType refers to a type by name, either a required type such as integer or a declared type identifier.
+type NamedType struct {
+	Span
+	Name string
+}
+
+func (*NamedType) node()            {}
+func (*NamedType) typeNode()        {}
+func (t *NamedType) String() string { return t.Name }
+
+// StringType represents a Turbo Pascal string type string[N], which holds at
+// most Size characters.
+type StringType struct {
+	Span
+	Size int
+}
+
+func (*StringType) node()            {}
+func (*StringType) typeNode()        {}
+func (t *StringType) String() string { return fmt.Sprintf("string[%d]", t.Size) }
+
+// EnumType represents an enumerated type such as (Red, Green, Blue).
+type EnumType struct {
+	Span
+	Values []string
+}
+
+func (*EnumType) node()     {}
+func (*EnumType) typeNode() {}
+func (t *EnumType) String() string {
+	return "(" + strings.Join(t.Values, ", ") + ")"
+}
+
+// SubrangeType represents a subrange of an ordinal type such as 1..10 or 'a'..'z'.
+// Low and High are constants folded at parse time.
+type SubrangeType struct {
+	Span
+	Low  Expr
+	High Expr
+}
+
+func (*SubrangeType) node()     {}
+func (*SubrangeType) typeNode() {}
+func (t *SubrangeType) String() string {
+	return fmt.Sprintf("%s..%s", constantString(t.Low), constantString(t.High))
+}
+
+// ArrayType represents an array type such as array[1..10] of integer.
+// A multi-dimensional array[1..3, 1..3] of T is parsed as array[1..3] of array[1..3] of T.
+type ArrayType struct {
+	Span
+	Packed  bool
+	Index   TypeExpr
+	Element TypeExpr
+}
+
+func (*ArrayType) node()     {}
+func (*ArrayType) typeNode() {}
+func (t *ArrayType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sarray[%s] of %s", prefix, t.Index, t.Element)
+}
+
+// ConformantArray represents a conformant array schema such as
+// array[lo..hi: integer] of real. The bound identifiers Low and High name the
+// bounds of the array passed for the parameter. Element is a type identifier or,
+// for a multi-dimensional schema, another schema: array[i..j: T; k..l: T] of U
+// is parsed as array[i..j: T] of array[k..l: T] of U.
+type ConformantArray struct {
+	Span
+	Packed    bool
+	Low       string
+	High      string
+	IndexType string
+	Element   TypeExpr
+}
+
+func (*ConformantArray) node()     {}
+func (*ConformantArray) typeNode() {}
+func (t *ConformantArray) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sarray[%s..%s: %s] of %s", prefix, t.Low, t.High, t.IndexType, t.Element)
+}
+
+// SetType represents a set type such as set of char or set of 1..10.
+type SetType struct {
+	Span
+	Packed  bool
+	Element TypeExpr
+}
+
+func (*SetType) node()     {}
+func (*SetType) typeNode() {}
+func (t *SetType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sset of %s", prefix, t.Element)
+}
+
+// PointerType represents a pointer type ^T. Target is a type identifier, which may be
+// declared later in the same type section so that records can point to themselves.
+type PointerType struct {
+	Span
+	Target string
+}
+
+func (*PointerType) node()            {}
+func (*PointerType) typeNode()        {}
+func (t *PointerType) String() string { return "^" + t.Target }
+
+// FileType represents a file type: file of T.
+type FileType struct {
+	Span
+	Packed  bool
+	Element TypeExpr
+}
+
+func (*FileType) node()     {}
+func (*FileType) typeNode() {}
+func (t *FileType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sfile of %s", prefix, t.Element)
+}
+
+// RecordType represents a record type: a fixed list of fields optionally followed by a variant part.
+type RecordType struct {
+	Span
+	Packed  bool
+	Fields  []*FieldDecl
+	Variant *VariantPart
+}
+
+func (*RecordType) node()     {}
+func (*RecordType) typeNode() {}
+func (t *RecordType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return prefix + "record"
+}
+
+// FieldDecl represents one field of a record.
+type FieldDecl struct {
+	Span
+	Name string
+	Type TypeExpr
+}
+
+// VariantPart represents the 'case' part of a record. Tag names the tag field and is empty
+// when the variant part has only a tag type.
+type VariantPart struct {
+	Span
+	Tag      string
+	TagType  string
+	Variants []*Variant
+}
+
+// Variant represents one alternative of a variant part, selected by its constant labels.
+type Variant struct {
+	Span
+	Labels  []Expr
+	Fields  []*FieldDecl
+	Variant *VariantPart
+}
+
+// TypeDecl represents a type definition in a 'type' section.
+type TypeDecl struct {
+	Span
+	Name string
+	Type TypeExpr
+}
+
+func (*TypeDecl) node()     {}
+func (*TypeDecl) stmtNode() {}
+
+func constantString(expr Expr) string {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return fmt.Sprintf("%d", e.Value)
+	case *CharLiteral:
+		return fmt.Sprintf("'%c'", e.Value)
+	case *BooleanLiteral:
+		return fmt.Sprintf("%t", e.Value)
+	case *Identifier:
+		return e.Value
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,258 @@
+package checker
+
+import (
+	"fmt"
+	"pastel/ast"
+)
+
+type builtinFunction struct {
+	minArgs, maxArgs int
+	check            func(c *checker, call *builtinCall) Type
+}
+
+type builtinCall struct {
+	pos   ast.Position
+	name  string
+	exprs []ast.Expr
+	args  []Type
+}
+
+var builtinFunctions = map[string]builtinFunction{
+	"abs":    {1, 1, arithmetic},
+	"sqr":    {1, 1, arithmetic},
+	"sqrt":   {1, 1, transcendental},
+	"sin":    {1, 1, transcendental},
+	"cos":    {1, 1, transcendental},
+	"arctan": {1, 1, transcendental},
+	"exp":    {1, 1, transcendental},
+	"ln":     {1, 1, transcendental},
+	"trunc":  {1, 1, rounding},
+	"round":  {1, 1, rounding},
+	"ord":    {1, 1, ordinalFunction(integerType)},
+	"chr":    {1, 1, builtinChr},
+	"succ":   {1, 1, ordinalFunction(nil)},
+	"pred":   {1, 1, ordinalFunction(nil)},
+	"odd":    {1, 1, builtinOdd},
+	"eof":    {0, 1, builtinEOF},
+	"eoln":   {0, 1, builtinEOF},
+}
+
+var builtinProcedures = map[string]func(c *checker, call *builtinCall){
+	"new":     pointerProcedure,
+	"dispose": pointerProcedure,
+	"reset":   fileProcedure,
+	"rewrite": fileProcedure,
+	"close":   fileProcedure,
+	"assign":  builtinAssign,
+	"read":    builtinRead,
+	"readln":  builtinRead,
+}
+
+func builtinProcedure(name string) func(c *checker, call *builtinCall) {
+	if check, ok := builtinProcedures[name]; ok {
+		return check
+	}
+	return turboProcedures[name]
+}
+
+func (c *checker) callBuiltinFunction(pos ast.Position, name string, f builtinFunction, exprs []ast.Expr) Type {
+	call := c.builtinCall(pos, name, exprs)
+	if len(exprs) < f.minArgs || len(exprs) > f.maxArgs {
+		expects := fmt.Sprintf("%d argument", f.maxArgs)
+		if f.minArgs < f.maxArgs {
+			expects = fmt.Sprintf("at most %d argument", f.maxArgs)
+		}
+		c.errorf(pos,
+			fmt.Sprintf("Wrong number of arguments to '%s'", name),
+			fmt.Sprintf("'%s' expects %s but was given %d.", name, expects, len(exprs)),
+			fmt.Sprintf("Call it as %s(x).", name),
+		)
+		return invalidType
+	}
+	for _, arg := range call.args {
+		if arg == invalidType {
+			return invalidType
+		}
+	}
+	return f.check(c, call)
+}
+
+func (c *checker) builtinCall(pos ast.Position, name string, exprs []ast.Expr) *builtinCall {
+	call := &builtinCall{pos: pos, name: name, exprs: exprs}
+	for _, expr := range exprs {
+		call.args = append(call.args, c.expr(expr))
+	}
+	return call
+}
+
+func arithmetic(c *checker, call *builtinCall) Type {
+	if !isNumeric(call.args[0]) {
+		return c.argumentTypeError(call, "integer or real")
+	}
+	return host(call.args[0])
+}
+
+func transcendental(c *checker, call *builtinCall) Type {
+	if !isNumeric(call.args[0]) {
+		return c.argumentTypeError(call, "integer or real")
+	}
+	return realType
+}
+
+func rounding(c *checker, call *builtinCall) Type {
+	if !isNumeric(call.args[0]) {
+		return c.argumentTypeError(call, "real")
+	}
+	return integerType
+}
+
+func ordinalFunction(result Type) func(c *checker, call *builtinCall) Type {
+	return func(c *checker, call *builtinCall) Type {
+		if !isOrdinal(call.args[0]) {
+			return c.argumentTypeError(call, "ordinal")
+		}
+		if result == nil {
+			return host(call.args[0])
+		}
+		return result
+	}
+}
+
+func builtinChr(c *checker, call *builtinCall) Type {
+	if !isInteger(call.args[0]) {
+		return c.argumentTypeError(call, "integer")
+	}
+	return charType
+}
+
+func builtinOdd(c *checker, call *builtinCall) Type {
+	if !isInteger(call.args[0]) {
+		return c.argumentTypeError(call, "integer")
+	}
+	return booleanType
+}
+
+func builtinEOF(c *checker, call *builtinCall) Type {
+	if len(call.args) == 0 {
+		return booleanType
+	}
+	file, ok := call.args[0].(*fileType)
+	if !ok {
+		return c.argumentTypeError(call, "a file")
+	}
+	if call.name == "eoln" && !file.text {
+		c.errorf(call.pos,
+			"'eoln' requires a text file",
+			fmt.Sprintf("The argument has type %s, which is not divided into lines.", file),
+			"Use eof to test for the end of a file that is not a text file.",
+		)
+	}
+	return booleanType
+}
+
+func (c *checker) argumentTypeError(call *builtinCall, expected string) Type {
+	c.errorf(call.pos,
+		fmt.Sprintf("Argument of '%s' must be %s", call.name, expected),
+		fmt.Sprintf("The argument has type %s.", call.args[0]),
+		fmt.Sprintf("Pass %s(x) a value of the type it expects.", call.name),
+	)
+	return invalidType
+}
+
+func pointerProcedure(c *checker, call *builtinCall) {
+	if len(call.exprs) == 1 && c.isVariable(call.exprs[0]) {
+		if _, ok := call.args[0].(*pointerType); ok || call.args[0] == invalidType {
+			return
+		}
+	}
+	c.errorf(call.pos,
+		fmt.Sprintf("'%s' requires one pointer variable", call.name),
+		fmt.Sprintf("%s(p) takes a single variable of a pointer type.", call.name),
+		"Declare the argument with a pointer type, e.g. `var p: ^integer;`.",
+	)
+}
+
+func fileProcedure(c *checker, call *builtinCall) {
+	if len(call.exprs) == 1 && c.isFileVariable(call.exprs[0], call.args[0]) {
+		return
+	}
+	c.fileArgumentError(call)
+}
+
+func builtinAssign(c *checker, call *builtinCall) {
+	if len(call.exprs) != 2 || !c.isFileVariable(call.exprs[0], call.args[0]) {
+		c.fileArgumentError(call)
+		return
+	}
+	if !isText(call.args[1]) && call.args[1] != invalidType {
+		c.errorf(c.exprPos(call.exprs[1], call.pos),
+			"File name must be a string",
+			fmt.Sprintf("The second argument of 'assign' has type %s.", call.args[1]),
+			"Pass the external file name as a string, e.g. assign(f, 'data.txt').",
+		)
+	}
+}
+
+func (c *checker) isFileVariable(expr ast.Expr, t Type) bool {
+	if t == invalidType {
+		return true
+	}
+	_, ok := t.(*fileType)
+	return ok && c.isVariable(expr)
+}
+
+func (c *checker) fileArgumentError(call *builtinCall) {
+	c.errorf(call.pos,
+		fmt.Sprintf("'%s' requires one file variable", call.name),
+		fmt.Sprintf("The first argument of '%s' must be a variable of a file type.", call.name),
+		"Declare the file with `var f: text;` or `var f: file of integer;`.",
+	)
+}
+
+func builtinRead(c *checker, call *builtinCall) {
+	var file Type = textType
+	args, exprs := call.args, call.exprs
+	if len(args) > 0 {
+		if f, ok := args[0].(*fileType); ok {
+			file, args, exprs = f, args[1:], exprs[1:]
+		}
+	}
+	f, _ := file.(*fileType)
+	if call.name == "readln" && !f.text {
+		c.errorf(call.pos,
+			"'readln' requires a text file",
+			fmt.Sprintf("The file has type %s, which is not divided into lines.", f),
+			"Use read for files that are not text files.",
+		)
+		return
+	}
+	for k, expr := range exprs {
+		if !c.isVariable(expr) {
+			c.errorf(c.exprPos(expr, call.pos),
+				fmt.Sprintf("Arguments of '%s' must be variables", call.name),
+				"The values that are read are stored in the arguments.",
+				fmt.Sprintf("Pass variables to %s, e.g. %s(x, y).", call.name, call.name),
+			)
+			continue
+		}
+		if name, ok := c.controlVariable(expr); ok {
+			c.controlVariableError(c.exprPos(expr, call.pos), fmt.Sprintf("Cannot read into for-loop control variable '%s'", name))
+			continue
+		}
+		t := args[k]
+		if f.text && !isNumeric(t) && !isText(t) && t != invalidType {
+			c.errorf(c.exprPos(expr, call.pos),
+				fmt.Sprintf("Cannot read a value of type %s from a text file", t),
+				"Text files hold characters, which can be read as char, string, integer or real values.",
+				"Read the characters into a variable of one of those types and convert them.",
+			)
+		}
+		if !f.text && !assignable(t, f.element) {
+			c.errorf(c.exprPos(expr, call.pos),
+				"Type mismatch in file component",
+				fmt.Sprintf("A component of type %s cannot be read into a variable of type %s.", f.element, t),
+				"Read file components into variables of the file's component type.",
+			)
+		}
+	}
+}
@@ -0,0 +1,1022 @@
+// Package checker analyses a parsed program before it runs. It builds a symbol
+// table of every block, resolves each identifier, and checks that declarations
+// precede their uses and that assignments, operators and calls are applied to
+// values of compatible types.
+package checker
+
+import (
+	"fmt"
+	"pastel/ast"
+)
+
+type checker struct {
+	scope       *scope
+	pos         ast.Position
+	diagnostics []*Diagnostic
+	dialect     ast.Dialect
+	loops       int
+	controls    map[*symbol]bool
+}
+
+// Check analyses prog and returns the diagnostics it found, in the order the
+// checker met them. A program without diagnostics is free of the errors the
+// checker looks for; range errors and the like are still detected at run time.
+func Check(prog *ast.Program) []*Diagnostic {
+	c := &checker{scope: requiredScope(prog.Dialect), dialect: prog.Dialect, controls: map[*symbol]bool{}}
+	c.scope = newScope(prog.Name, c.scope)
+	c.pos = prog.Start
+
+	for _, param := range prog.Params {
+		if param == "input" || param == "output" {
+			c.scope.define(&symbol{kind: variableSymbol, name: param, typ: textType})
+		}
+	}
+	c.block(prog.Declarations, prog.Main)
+	c.checkProgramParams(prog)
+	return c.diagnostics
+}
+
+func requiredScope(dialect ast.Dialect) *scope {
+	s := newScope("", nil)
+	for _, t := range []*basicType{integerType, realType, booleanType, charType, stringType} {
+		s.define(&symbol{kind: typeSymbol, name: t.name, typ: t})
+	}
+	s.define(&symbol{kind: typeSymbol, name: "text", typ: textType})
+	s.define(&symbol{kind: constantSymbol, name: "maxint", typ: integerType})
+	for name := range builtinFunctions {
+		s.define(&symbol{kind: builtinFunctionSymbol, name: name})
+	}
+	for name := range builtinProcedures {
+		s.define(&symbol{kind: builtinProcedureSymbol, name: name})
+	}
+	if dialect == ast.Turbo {
+		for name := range turboProcedures {
+			s.define(&symbol{kind: builtinProcedureSymbol, name: name})
+		}
+	}
+	return s
+}
+
+func (c *checker) errorf(pos ast.Position, msg, detail, hint string) {
+	if pos.Line == 0 {
+		pos = c.pos
+	}
+	c.diagnostics = append(c.diagnostics, &Diagnostic{
+		Msg:    msg,
+		Detail: detail,
+		Hint:   hint,
+		Line:   pos.Line,
+		Column: pos.Column,
+	})
+}
+
+func (c *checker) checkProgramParams(prog *ast.Program) {
+	for _, param := range prog.Params {
+		if param == "input" || param == "output" {
+			continue
+		}
+		sym := c.scope.symbols[param]
+		if sym != nil && sym.kind == variableSymbol {
+			if _, isFile := sym.typ.(*fileType); isFile {
+				continue
+			}
+		}
+		c.errorf(prog.Start,
+			fmt.Sprintf("Program parameter '%s' must be a file variable", param),
+			fmt.Sprintf("'%s' is listed in the program heading but not declared as a file variable of '%s'.", param, prog.Name),
+			fmt.Sprintf("Declare `var %s: text;` in the program block.", param),
+		)
+	}
+}
+
+func (c *checker) block(decls []ast.Stmt, body *ast.CompoundStmt) {
+	for _, decl := range decls {
+		for _, name := range declaredNames(decl) {
+			c.scope.later[name] = true
+		}
+	}
+
+	var pointers []*pointerType
+	resolved := map[ast.TypeExpr]Type{}
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *ast.ConstDecl:
+			c.pos = d.Start
+			t, ord := c.constant(d.Value)
+			c.declare(d.Start, &symbol{kind: constantSymbol, name: d.Name, typ: t, ordinal: ord})
+
+		case *ast.TypeDecl:
+			c.pos = d.Start
+			t := c.resolveType(d.Type, &pointers)
+			switch named := t.(type) {
+			case *enumType:
+				if _, isNew := d.Type.(*ast.EnumType); isNew {
+					named.name = d.Name
+				}
+			case *recordType:
+				if _, isNew := d.Type.(*ast.RecordType); isNew {
+					named.name = d.Name
+				}
+			}
+			c.declare(d.Start, &symbol{kind: typeSymbol, name: d.Name, typ: t})
+
+		case *ast.VarDecl:
+			c.pos = d.Start
+			t, ok := resolved[d.Type]
+			if !ok {
+				t = c.resolveType(d.Type, &pointers)
+				resolved[d.Type] = t
+			}
+			c.declare(d.Start, &symbol{kind: variableSymbol, name: d.Name, typ: t})
+
+		case *ast.TypedConstDecl:
+			c.pos = d.Start
+			t := c.resolveType(d.Type, &pointers)
+			c.typedConstant(t, d.Value)
+			c.declare(d.Start, &symbol{kind: variableSymbol, name: d.Name, typ: t})
+
+		case *ast.ProcedureDecl:
+			c.routine(d)
+		}
+	}
+
+	for _, ptr := range pointers {
+		if ptr.resolve() == nil {
+			c.unknownTypeError(ptr.targetName)
+		}
+	}
+
+	for _, decl := range decls {
+		if d, ok := decl.(*ast.ProcedureDecl); ok && d.Forward {
+			if sym := c.scope.symbols[d.Name]; sym != nil && sym.forward == d {
+				c.errorf(d.Start,
+					fmt.Sprintf("Forward declaration of '%s' is never completed", d.Name),
+					fmt.Sprintf("'%s' is declared forward in '%s', but no later declaration gives its block.", d.Name, c.scope.name),
+					fmt.Sprintf("Declare `%s %s;` followed by its block later in the same declaration part.", routineKind(d), d.Name),
+				)
+			}
+		}
+	}
+
+	if body != nil {
+		c.stmt(body)
+	}
+}
+
+func declaredNames(decl ast.Stmt) []string {
+	switch d := decl.(type) {
+	case *ast.ConstDecl:
+		return []string{d.Name}
+	case *ast.TypeDecl:
+		names := []string{d.Name}
+		if enum, ok := d.Type.(*ast.EnumType); ok {
+			names = append(names, enum.Values...)
+		}
+		return names
+	case *ast.VarDecl:
+		return []string{d.Name}
+	case *ast.TypedConstDecl:
+		return []string{d.Name}
+	case *ast.ProcedureDecl:
+		return []string{d.Name}
+	}
+	return nil
+}
+
+func (c *checker) declare(pos ast.Position, sym *symbol) {
+	if !c.scope.define(sym) {
+		c.errorf(pos,
+			fmt.Sprintf("Duplicate declaration of '%s'", sym.name),
+			fmt.Sprintf("'%s' is declared more than once in '%s'.", sym.name, c.scope.name),
+			"Rename one of the declarations; an inner block may reuse an outer name, the same block may not.",
+		)
+	}
+}
+
+func (c *checker) routine(d *ast.ProcedureDecl) {
+	c.pos = d.Start
+	sym := c.scope.symbols[d.Name]
+	if sym != nil && sym.forward != nil && !d.Forward {
+		c.completeForward(sym, d)
+	} else {
+		sym = c.routineSymbol(d)
+		if d.IsFunction() && d.ReturnType == "" {
+			c.errorf(d.Start,
+				fmt.Sprintf("Function '%s' has no result type", d.Name),
+				"Only the block of a function declared forward may leave out its result type.",
+				fmt.Sprintf("Add the result type, e.g. `function %s: integer;`.", d.Name),
+			)
+		}
+		c.declare(d.Start, sym)
+		if d.Forward {
+			sym.forward = d
+			return
+		}
+	}
+
+	saved, loops := c.scope, c.loops
+	c.scope = newScope(d.Name, saved)
+	c.scope.routine = sym
+	c.loops = 0
+	defer func() { c.scope, c.loops = saved, loops }()
+
+	for k, p := range sym.params {
+		if p.routine != nil {
+			c.declare(d.Start, p.routine)
+			continue
+		}
+		c.declare(d.Start, &symbol{kind: variableSymbol, name: p.name, typ: p.typ, varParam: p.isVar})
+		if k == 0 || sym.params[k-1].typ != p.typ {
+			c.declareBounds(d.Start, p.typ)
+		}
+	}
+	c.block(d.Declarations, d.Body)
+}
+
+func (c *checker) declareBounds(pos ast.Position, t Type) {
+	for arr, ok := t.(*arrayType); ok && arr.schema != nil; arr, ok = arr.element.(*arrayType) {
+		c.declare(pos, &symbol{kind: boundSymbol, name: arr.schema.Low, typ: arr.index})
+		c.declare(pos, &symbol{kind: boundSymbol, name: arr.schema.High, typ: arr.index})
+	}
+}
+
+func (c *checker) routineSymbol(d *ast.ProcedureDecl) *symbol {
+	sym := &symbol{kind: routineSymbol, name: d.Name, decl: d}
+	schemas := map[*ast.ConformantArray]Type{}
+	for _, p := range d.Params {
+		if p.Routine != nil {
+			sym.params = append(sym.params, &param{name: p.Name, routine: c.routineSymbol(p.Routine)})
+			continue
+		}
+		if p.Schema != nil {
+			if _, ok := schemas[p.Schema]; !ok {
+				schemas[p.Schema] = c.conformantType(p.Schema)
+			}
+			sym.params = append(sym.params, &param{name: p.Name, typ: schemas[p.Schema], isVar: p.IsVar})
+			continue
+		}
+		sym.params = append(sym.params, &param{name: p.Name, typ: c.lookupType(p.Type), isVar: p.IsVar})
+	}
+	if d.IsFunction() {
+		sym.result = invalidType
+		if d.ReturnType != "" {
+			sym.result = c.lookupType(d.ReturnType)
+		}
+	}
+	return sym
+}
+
+func (c *checker) conformantType(schema *ast.ConformantArray) Type {
+	index := c.lookupType(schema.IndexType)
+	if index != invalidType && !isOrdinal(index) {
+		c.errorf(c.pos,
+			fmt.Sprintf("Invalid conformant array index type %s", index),
+			"The index type of a conformant array must be an ordinal type.",
+			"Use integer, char, boolean, an enumerated type or a subrange for the index type.",
+		)
+		index = invalidType
+	}
+	var element Type
+	if nested, ok := schema.Element.(*ast.ConformantArray); ok {
+		element = c.conformantType(nested)
+	} else {
+		element = c.resolveType(schema.Element, nil)
+	}
+	return &arrayType{packed: schema.Packed, index: index, element: element, schema: schema}
+}
+
+func (c *checker) completeForward(sym *symbol, d *ast.ProcedureDecl) {
+	forward := sym.forward
+	sym.forward = nil
+
+	var detail string
+	switch {
+	case d.IsFunction() != forward.IsFunction():
+		detail = fmt.Sprintf("'%s' is declared forward as a %s but completed as a %s.", d.Name, routineKind(forward), routineKind(d))
+	case d.Params == nil && d.ReturnType == "":
+		return
+	case len(d.Params) != len(forward.Params):
+		detail = fmt.Sprintf("The forward declaration has %d parameter(s) but this heading has %d.", len(forward.Params), len(d.Params))
+	default:
+		repeated := c.routineSymbol(d)
+		for k, p := range repeated.params {
+			if detail = paramMismatch(k, p, sym.params[k]); detail != "" {
+				break
+			}
+		}
+		if detail == "" && !sameDeclaredType(repeated.result, sym.result) {
+			detail = fmt.Sprintf("The result type is %s here but %s in the forward declaration.", repeated.result, sym.result)
+		}
+	}
+	if detail != "" {
+		c.errorf(d.Start,
+			fmt.Sprintf("Heading of '%s' does not match its forward declaration", d.Name),
+			detail,
+			fmt.Sprintf("Leave out the parameter list and result type after a forward declaration, e.g. `%s %s;`, or repeat them exactly.", routineKind(forward), d.Name),
+		)
+	}
+}
+
+func paramMismatch(k int, p, want *param) string {
+	switch {
+	case p.name != want.name:
+		return fmt.Sprintf("Parameter %d is '%s' here but '%s' in the forward declaration.", k+1, p.name, want.name)
+	case p.isVar != want.isVar:
+		return fmt.Sprintf("Parameter '%s' is a var parameter in only one of the headings.", p.name)
+	case !sameParam(p, want):
+		return fmt.Sprintf("Parameter '%s' is %s here but %s in the forward declaration.", p.name, p, want)
+	}
+	return ""
+}
+
+func routineKind(d *ast.ProcedureDecl) string {
+	if d.IsFunction() {
+		return "function"
+	}
+	return "procedure"
+}
+
+func (c *checker) constant(expr ast.Expr) (Type, int) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return integerType, e.Value
+	case *ast.RealLiteral:
+		return realType, 0
+	case *ast.BooleanLiteral:
+		if e.Value {
+			return booleanType, 1
+		}
+		return booleanType, 0
+	case *ast.CharLiteral:
+		return charType, int(e.Value)
+	case *ast.StringLiteral:
+		return stringType, 0
+	case *ast.Identifier:
+		if sym := c.scope.lookup(e.Value); sym != nil && sym.kind == constantSymbol {
+			return sym.typ, sym.ordinal
+		}
+	}
+	return c.expr(expr), 0
+}
+
+func (c *checker) lookupType(name string) Type {
+	sym := c.scope.lookup(name)
+	if sym == nil || sym.kind != typeSymbol {
+		c.unknownTypeError(name)
+		return invalidType
+	}
+	return sym.typ
+}
+
+func (c *checker) unknownTypeError(name string) {
+	detail := fmt.Sprintf("'%s' is used as a type but no type with that name is declared.", name)
+	if sym := c.scope.lookup(name); sym != nil {
+		detail = fmt.Sprintf("'%s' is a %s, not a type.", name, sym.describe())
+	}
+	c.errorf(c.pos,
+		fmt.Sprintf("Unknown type '%s'", name),
+		detail,
+		fmt.Sprintf("Declare it in a 'type' section, e.g. `type %s = 1..10;`.", name),
+	)
+}
+
+func (c *checker) resolveType(expr ast.TypeExpr, pointers *[]*pointerType) Type {
+	switch t := expr.(type) {
+	case *ast.NamedType:
+		return c.lookupType(t.Name)
+
+	case *ast.EnumType:
+		enum := &enumType{name: t.String(), values: t.Values}
+		for ord, name := range t.Values {
+			c.declare(c.pos, &symbol{kind: constantSymbol, name: name, typ: enum, ordinal: ord})
+		}
+		return enum
+
+	case *ast.SubrangeType:
+		low, lowOrd := c.constant(t.Low)
+		_, highOrd := c.constant(t.High)
+		return &subrangeType{host: host(low), low: lowOrd, high: highOrd}
+
+	case *ast.ArrayType:
+		index := c.resolveType(t.Index, pointers)
+		element := c.resolveType(t.Element, pointers)
+		if index == invalidType || element == invalidType {
+			return invalidType
+		}
+		low, high, bounded := ordinalBounds(index)
+		if !isOrdinal(index) || !bounded {
+			c.errorf(c.pos,
+				fmt.Sprintf("Invalid array index type %s", index),
+				"An array index type must be an ordinal type with fixed bounds.",
+				"Index arrays by a subrange such as 1..10, or by char, boolean or an enumerated type.",
+			)
+			return invalidType
+		}
+		return &arrayType{packed: t.Packed, index: index, element: element, low: low, high: high}
+
+	case *ast.SetType:
+		element := c.resolveType(t.Element, pointers)
+		if element == invalidType {
+			return invalidType
+		}
+		low, high, bounded := ordinalBounds(element)
+		if !isOrdinal(element) || !bounded || low < 0 || high > maxSetOrdinal {
+			c.errorf(c.pos,
+				fmt.Sprintf("Invalid set base type %s", element),
+				fmt.Sprintf("A set base type must be ordinal with ordinals between 0 and %d.", maxSetOrdinal),
+				"Use a subrange such as 0..63, or char, boolean or an enumerated type.",
+			)
+			return invalidType
+		}
+		return &setType{element: host(element)}
+
+	case *ast.RecordType:
+		record := &recordType{name: t.String()}
+		c.resolveFields(record, t.Fields, t.Variant, pointers)
+		return record
+
+	case *ast.FileType:
+		element := c.resolveType(t.Element, pointers)
+		if element == invalidType {
+			return invalidType
+		}
+		if !isFileComponentType(element) {
+			c.errorf(c.pos,
+				fmt.Sprintf("Invalid file component type %s", element),
+				"The components of a file cannot be or contain files or pointers.",
+				"Store the values the pointers lead to instead of the pointers themselves.",
+			)
+			return invalidType
+		}
+		return &fileType{packed: t.Packed, element: element}
+
+	case *ast.StringType:
+		return &shortStringType{size: t.Size}
+
+	case *ast.PointerType:
+		ptr := &pointerType{targetName: t.Target, scope: c.scope}
+		*pointers = append(*pointers, ptr)
+		return ptr
+	}
+	return invalidType
+}
+
+func (c *checker) resolveFields(record *recordType, fields []*ast.FieldDecl, variant *ast.VariantPart, pointers *[]*pointerType) {
+	resolved := map[ast.TypeExpr]Type{}
+	addField := func(name string, t Type) {
+		if _, exists := record.field(name); exists {
+			c.errorf(c.pos,
+				fmt.Sprintf("Duplicate field '%s'", name),
+				fmt.Sprintf("'%s' is declared more than once in the same record, counting the fields of all variants.", name),
+				"Give every field of a record a distinct name.",
+			)
+			return
+		}
+		record.fields = append(record.fields, &field{name: name, typ: t})
+	}
+
+	for _, decl := range fields {
+		t, ok := resolved[decl.Type]
+		if !ok {
+			t = c.resolveType(decl.Type, pointers)
+			resolved[decl.Type] = t
+		}
+		addField(decl.Name, t)
+	}
+	if variant == nil {
+		return
+	}
+
+	tagType := c.lookupType(variant.TagType)
+	if tagType != invalidType && !isOrdinal(tagType) {
+		c.errorf(c.pos,
+			fmt.Sprintf("Variant tag type %s must be ordinal", tagType),
+			"Variants are selected by the ordinal value of the tag.",
+			"Use an integer, char, boolean or enumerated type for the tag.",
+		)
+	}
+	if variant.Tag != "" {
+		addField(variant.Tag, tagType)
+	}
+	for _, v := range variant.Variants {
+		for _, label := range v.Labels {
+			if t, _ := c.constant(label); !assignable(host(tagType), t) || !isOrdinal(t) {
+				c.errorf(c.pos,
+					"Type mismatch in variant label",
+					fmt.Sprintf("The tag has type %s but a label has type %s.", tagType, t),
+					"Variant labels must have the same type as the tag.",
+				)
+			}
+		}
+		c.resolveFields(record, v.Fields, v.Variant, pointers)
+	}
+}
+
+func (c *checker) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case nil:
+
+	case *ast.CompoundStmt:
+		for _, stmt := range s.Statements {
+			c.stmt(stmt)
+		}
+
+	case *ast.AssignStmt:
+		c.pos = s.Start
+		c.assign(s)
+
+	case *ast.CallStmt:
+		c.pos = s.Start
+		c.callStmt(s)
+
+	case *ast.PrintStmt:
+		c.pos = s.Start
+		c.print(s)
+
+	case *ast.IfStmt:
+		c.pos = s.Start
+		c.condition(s.Condition, "if")
+		c.stmt(s.Then)
+		c.stmt(s.Else)
+
+	case *ast.WhileStmt:
+		c.pos = s.Start
+		c.condition(s.Condition, "while")
+		c.loop(s.Body)
+
+	case *ast.RepeatStmt:
+		c.loop(s.Body...)
+		c.pos = s.Start
+		c.condition(s.Condition, "until")
+
+	case *ast.ForStmt:
+		c.pos = s.Start
+		c.forStmt(s)
+
+	case *ast.CaseStmt:
+		c.pos = s.Start
+		c.caseStmt(s)
+
+	case *ast.WithStmt:
+		c.pos = s.Start
+		c.with(s)
+
+	case *ast.LabeledStmt:
+		c.stmt(s.Stmt)
+	}
+}
+
+func (c *checker) loop(body ...ast.Stmt) {
+	c.loops++
+	for _, stmt := range body {
+		c.stmt(stmt)
+	}
+	c.loops--
+}
+
+func (c *checker) condition(expr ast.Expr, construct string) {
+	if t := c.expr(expr); !isBoolean(t) && t != invalidType {
+		c.errorf(c.exprPos(expr, c.pos),
+			fmt.Sprintf("Condition of '%s' must be boolean", construct),
+			fmt.Sprintf("The condition has type %s.", t),
+			"Use a relational operator such as '=' or '<' to build a boolean condition.",
+		)
+	}
+}
+
+func (c *checker) assign(s *ast.AssignStmt) {
+	target := c.target(s.Target)
+	value := c.expr(s.Value)
+	c.checkAssignable(target, value, s.Start, "assignment")
+}
+
+func (c *checker) target(expr ast.Expr) Type {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		t := c.expr(expr)
+		if t != invalidType && !c.isVariable(expr) {
+			c.errorf(c.exprPos(expr, c.pos),
+				"Left side of assignment must be a variable",
+				"Only variables and their components can be assigned.",
+				"Assign to a declared variable or to a component of one.",
+			)
+			return invalidType
+		}
+		return t
+	}
+
+	sym := c.scope.lookup(ident.Value)
+	if sym == nil {
+		return c.undeclaredError(ident.Start, ident.Value)
+	}
+	switch sym.kind {
+	case variableSymbol:
+		if c.controls[sym] {
+			c.controlVariableError(ident.Start, fmt.Sprintf("Cannot assign to for-loop control variable '%s'", sym.name))
+			return invalidType
+		}
+		return sym.typ
+	case routineSymbol:
+		if sym.result != nil && c.scope.inRoutine(sym) {
+			return sym.result
+		}
+		c.errorf(ident.Start,
+			fmt.Sprintf("Cannot assign to %s '%s'", sym.describe(), sym.name),
+			"Only a function's result can be assigned, and only inside that function's body.",
+			"Assign to a variable instead.",
+		)
+	case constantSymbol:
+		c.errorf(ident.Start,
+			fmt.Sprintf("Cannot assign to constant '%s'", sym.name),
+			"Constants are fixed when they are defined and cannot be changed.",
+			fmt.Sprintf("Declare '%s' in a 'var' section if it needs to change.", sym.name),
+		)
+	case boundSymbol:
+		c.errorf(ident.Start,
+			fmt.Sprintf("Cannot assign to bound identifier '%s'", sym.name),
+			"The bounds of a conformant array parameter are those of the array passed for it and cannot be changed.",
+			fmt.Sprintf("Copy '%s' into a local variable if you need a value that changes.", sym.name),
+		)
+	default:
+		c.errorf(ident.Start,
+			"Left side of assignment must be a variable",
+			fmt.Sprintf("'%s' is a %s.", sym.name, sym.describe()),
+			"Assign to a declared variable or to a component of one.",
+		)
+	}
+	return invalidType
+}
+
+func (c *checker) checkAssignable(target, value Type, pos ast.Position, context string) bool {
+	if _, isFile := target.(*fileType); isFile {
+		c.errorf(pos,
+			"Files cannot be assigned",
+			fmt.Sprintf("A value of type %s cannot be copied into a variable of type %s.", value, target),
+			"Pass files to procedures and functions as var parameters.",
+		)
+		return false
+	}
+	if assignable(target, value) {
+		return true
+	}
+	if isInteger(target) && host(value) == realType {
+		c.errorf(pos,
+			"Cannot assign a real value to an integer",
+			fmt.Sprintf("The value has type real but the target has type %s.", target),
+			"Convert it with trunc or round, or use 'div' for integer division.",
+		)
+		return false
+	}
+	hint := "Assign values of the variable's type; integers may be assigned to reals."
+	switch target.(type) {
+	case *arrayType, *recordType:
+		hint = "Arrays and records can only be assigned whole when both have the same type; declare them with one type name."
+	case *pointerType:
+		hint = "Assign nil or a pointer to the same type."
+	}
+	c.errorf(pos,
+		fmt.Sprintf("Type mismatch in %s", context),
+		fmt.Sprintf("A value of type %s cannot be assigned to a variable of type %s.", value, target),
+		hint,
+	)
+	return false
+}
+
+func (c *checker) callStmt(s *ast.CallStmt) {
+	sym := c.scope.lookup(s.Name)
+	if sym == nil {
+		c.undeclaredRoutineError(s.Start, s.Name)
+		return
+	}
+	switch sym.kind {
+	case builtinProcedureSymbol:
+		builtinProcedure(s.Name)(c, c.builtinCall(s.Start, s.Name, s.Args))
+	case builtinFunctionSymbol:
+		c.functionAsStatementError(s.Start, s.Name)
+	case routineSymbol:
+		if sym.result != nil {
+			c.functionAsStatementError(s.Start, s.Name)
+			return
+		}
+		c.arguments(s.Start, sym, s.Args)
+	default:
+		c.notRoutineError(s.Start, sym)
+	}
+}
+
+func (c *checker) functionAsStatementError(pos ast.Position, name string) {
+	c.errorf(pos,
+		fmt.Sprintf("Function '%s' cannot be called as a statement", name),
+		"The result of a function call must be used in an expression.",
+		fmt.Sprintf("Assign the result, e.g. `x := %s(...)`.", name),
+	)
+}
+
+func (c *checker) arguments(pos ast.Position, routine *symbol, args []ast.Expr) {
+	if len(args) != len(routine.params) {
+		c.errorf(pos,
+			fmt.Sprintf("Wrong number of arguments to '%s'", routine.name),
+			fmt.Sprintf("'%s' expects %d argument(s) but was given %d.", routine.name, len(routine.params), len(args)),
+			"Pass exactly one argument for each declared parameter.",
+		)
+		for _, arg := range args {
+			c.expr(arg)
+		}
+		return
+	}
+
+	groups := map[Type]Type{}
+	for k, arg := range args {
+		p := routine.params[k]
+		argPos := c.exprPos(arg, pos)
+		if p.routine != nil {
+			c.routineArgument(arg, argPos, p, routine)
+			continue
+		}
+		t := c.expr(arg)
+		if arr, ok := p.typ.(*arrayType); ok && arr.schema != nil {
+			c.conformantArgument(arg, argPos, p, routine, t, groups)
+			continue
+		}
+		if !p.isVar {
+			c.checkAssignable(p.typ, t, argPos, fmt.Sprintf("argument '%s' of '%s'", p.name, routine.name))
+			continue
+		}
+		if !c.isVariable(arg) {
+			c.errorf(argPos,
+				fmt.Sprintf("Argument for var parameter '%s' of '%s' must be a variable", p.name, routine.name),
+				"A var parameter refers to the caller's variable, so an expression cannot be passed.",
+				"Pass a declared variable, or remove 'var' to pass the parameter by value.",
+			)
+			continue
+		}
+		if name, ok := c.controlVariable(arg); ok {
+			c.controlVariableError(argPos, fmt.Sprintf("Cannot pass for-loop control variable '%s' to var parameter '%s' of '%s'", name, p.name, routine.name))
+			continue
+		}
+		if !sameType(p.typ, t) {
+			c.errorf(argPos,
+				fmt.Sprintf("Type mismatch in var parameter '%s' of '%s'", p.name, routine.name),
+				fmt.Sprintf("The parameter has type %s but the variable has type %s.", p.typ, t),
+				"A variable passed to a var parameter must have the parameter's type.",
+			)
+		}
+	}
+}
+
+func (c *checker) conformantArgument(arg ast.Expr, pos ast.Position, p *param, routine *symbol, t Type, groups map[Type]Type) {
+	if p.isVar && !c.isVariable(arg) {
+		c.errorf(pos,
+			fmt.Sprintf("Argument for var parameter '%s' of '%s' must be a variable", p.name, routine.name),
+			"A var parameter refers to the caller's variable, so an expression cannot be passed.",
+			"Pass a declared variable, or remove 'var' to pass the parameter by value.",
+		)
+		return
+	}
+	if !conforms(p.typ.(*arrayType), t, p.isVar) {
+		c.errorf(pos,
+			fmt.Sprintf("Type mismatch in argument '%s' of '%s'", p.name, routine.name),
+			fmt.Sprintf("A value of type %s does not conform to %s.", t, p.typ),
+			"Pass an array whose index type lies within the schema's index type and whose elements have the schema's element type.",
+		)
+		return
+	}
+	first, seen := groups[p.typ]
+	if !seen {
+		groups[p.typ] = t
+		return
+	}
+	if first != t && first != invalidType && t != invalidType {
+		c.errorf(pos,
+			fmt.Sprintf("Type mismatch in argument '%s' of '%s'", p.name, routine.name),
+			fmt.Sprintf("The parameters of one conformant array schema must be given arrays of the same type, but this one has type %s and an earlier one %s.", t, first),
+			"Declare the arrays with one type name, or give each parameter its own schema.",
+		)
+	}
+}
+
+func (c *checker) routineArgument(arg ast.Expr, pos ast.Position, p *param, routine *symbol) {
+	kind, expects := "procedural", "procedure"
+	if p.routine.result != nil {
+		kind, expects = "functional", "function"
+	}
+	ident, isIdent := arg.(*ast.Identifier)
+	var sym *symbol
+	if isIdent {
+		sym = c.scope.lookup(ident.Value)
+	}
+
+	switch {
+	case isIdent && sym == nil:
+		c.undeclaredError(pos, ident.Value)
+	case sym != nil && (sym.kind == builtinFunctionSymbol || sym.kind == builtinProcedureSymbol):
+		required := "procedure"
+		if sym.kind == builtinFunctionSymbol {
+			required = "function"
+		}
+		c.errorf(pos,
+			fmt.Sprintf("Required %s '%s' cannot be passed as a parameter", required, sym.name),
+			"Only procedures and functions declared in the program can be passed to procedural and functional parameters.",
+			fmt.Sprintf("Declare a %s of your own that calls %s and pass that instead.", expects, sym.name),
+		)
+	case sym == nil || sym.kind != routineSymbol || (sym.result != nil) != (p.routine.result != nil):
+		detail := "An expression cannot be passed; name the routine without arguments."
+		if sym != nil {
+			detail = fmt.Sprintf("'%s' is a %s.", sym.name, sym.describe())
+		}
+		c.errorf(pos,
+			fmt.Sprintf("Argument for %s parameter '%s' of '%s' must be a %s", kind, p.name, routine.name, expects),
+			detail,
+			fmt.Sprintf("Pass the name of a %s declared like %s.", expects, p.routine.signature()),
+		)
+	case !congruent(sym, p.routine):
+		c.errorf(pos,
+			fmt.Sprintf("Type mismatch in %s parameter '%s' of '%s'", kind, p.name, routine.name),
+			fmt.Sprintf("'%s' is a %s, but the parameter is a %s.", sym.name, sym.signature(), p.routine.signature()),
+			"The parameter lists must match in number, kind and type of parameters, and functions must have the same result type.",
+		)
+	}
+}
+
+func (c *checker) print(s *ast.PrintStmt) {
+	args := s.Args
+	var file *fileType
+	if len(args) > 0 {
+		if _, isFormat := args[0].(*ast.FormatExpr); !isFormat {
+			t := c.expr(args[0])
+			if f, ok := t.(*fileType); ok {
+				file = f
+			}
+			args = args[1:]
+		}
+	}
+
+	for _, arg := range args {
+		value := arg
+		format, isFormat := arg.(*ast.FormatExpr)
+		if isFormat {
+			value = format.Value
+		}
+		t := c.expr(value)
+		if file != nil && !file.text {
+			if isFormat {
+				c.errorf(c.exprPos(value, s.Start),
+					"Field widths can only be used with text files",
+					fmt.Sprintf("Components of %s are written without formatting.", file),
+					"Drop the ':' format specifiers when writing to a typed file.",
+				)
+			}
+			c.checkAssignable(file.element, t, c.exprPos(value, s.Start), "file component")
+			continue
+		}
+		if isFormat {
+			c.fieldWidth(format.Width, "Field width")
+			if format.Decimals != nil {
+				c.fieldWidth(format.Decimals, "Number of decimal places")
+				if !isNumeric(t) && t != invalidType {
+					c.errorf(c.exprPos(value, s.Start),
+						"Decimal places can only be given for real values",
+						fmt.Sprintf("The value written has type %s.", t),
+						"Drop the second format specifier, e.g. write(n:8).",
+					)
+				}
+			}
+		}
+	}
+}
+
+func (c *checker) fieldWidth(expr ast.Expr, what string) {
+	if t := c.expr(expr); !isInteger(t) && t != invalidType {
+		c.errorf(c.exprPos(expr, c.pos),
+			fmt.Sprintf("%s must be an integer", what),
+			fmt.Sprintf("The value given has type %s.", t),
+			"Write the format as value:width or value:width:decimals with integer expressions.",
+		)
+	}
+}
+
+func (c *checker) forStmt(s *ast.ForStmt) {
+	sym, declaredIn := c.scope.resolve(s.Variable)
+	var varType Type = invalidType
+	switch {
+	case sym == nil:
+		c.undeclaredError(s.Start, s.Variable)
+	case sym.kind != variableSymbol:
+		c.errorf(s.Start,
+			fmt.Sprintf("For-loop control variable '%s' must be a variable", s.Variable),
+			fmt.Sprintf("'%s' is a %s.", s.Variable, sym.describe()),
+			fmt.Sprintf("Declare `var %s: integer;` in the block that contains the loop.", s.Variable),
+		)
+	case declaredIn != c.scope.block() || sym.varParam:
+		c.errorf(s.Start,
+			fmt.Sprintf("For-loop control variable '%s' must be a local variable", s.Variable),
+			fmt.Sprintf("'%s' is not a variable declared in '%s'.", s.Variable, c.scope.block().name),
+			fmt.Sprintf("Declare `var %s: integer;` in the block that contains the loop.", s.Variable),
+		)
+	case !isOrdinal(sym.typ) && sym.typ != invalidType:
+		c.errorf(s.Start,
+			fmt.Sprintf("For-loop control variable '%s' must have an ordinal type", s.Variable),
+			fmt.Sprintf("'%s' has type %s.", s.Variable, sym.typ),
+			"Use an integer, char, boolean or enumerated variable to count the loop.",
+		)
+	case c.controls[sym]:
+		c.errorf(s.Start,
+			fmt.Sprintf("For-loop control variable '%s' is already in use", s.Variable),
+			fmt.Sprintf("'%s' is the control variable of an enclosing for loop.", s.Variable),
+			"Count the inner loop with another variable.",
+		)
+	default:
+		varType = sym.typ
+	}
+
+	for _, bound := range []ast.Expr{s.Initial, s.Final} {
+		t := c.expr(bound)
+		if !assignable(host(varType), t) {
+			c.errorf(c.exprPos(bound, s.Start),
+				"Type mismatch in for-loop bound",
+				fmt.Sprintf("The control variable '%s' has type %s but a bound has type %s.", s.Variable, varType, t),
+				"The bounds of a for loop must have the type of its control variable.",
+			)
+		}
+	}
+	if varType != invalidType {
+		c.controls[sym] = true
+		defer delete(c.controls, sym)
+	}
+	c.loop(s.Body)
+}
+
+func (c *checker) controlVariable(expr ast.Expr) (string, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return "", false
+	}
+	sym := c.scope.lookup(ident.Value)
+	return ident.Value, sym != nil && c.controls[sym]
+}
+
+func (c *checker) controlVariableError(pos ast.Position, msg string) {
+	c.errorf(pos, msg,
+		"A for loop counts with its control variable, so the statement it repeats cannot change it.",
+		"Use a while loop if the loop has to change its counter.",
+	)
+}
+
+func (c *checker) caseStmt(s *ast.CaseStmt) {
+	selector := c.expr(s.Selector)
+	if !isOrdinal(selector) && selector != invalidType {
+		c.errorf(c.exprPos(s.Selector, s.Start),
+			"Case selector must be ordinal",
+			fmt.Sprintf("The selector has type %s.", selector),
+			"Use an integer, char, boolean or enumerated expression as the case selector.",
+		)
+		selector = invalidType
+	}
+
+	for _, branch := range s.Branches {
+		for _, label := range branch.Labels {
+			for _, bound := range []ast.Expr{label.Low, label.High} {
+				if bound == nil {
+					continue
+				}
+				if t, _ := c.constant(bound); selector != invalidType && host(t) != host(selector) {
+					c.errorf(s.Start,
+						"Type mismatch in case label",
+						fmt.Sprintf("The selector has type %s but a label has type %s.", selector, t),
+						"Case labels must have the same type as the selector.",
+					)
+				}
+			}
+		}
+		c.stmt(branch.Body)
+	}
+	if s.Else != nil {
+		c.stmt(s.Else)
+	}
+}
+
+func (c *checker) with(s *ast.WithStmt) {
+	t := c.expr(s.Record)
+	record, ok := t.(*recordType)
+	if !ok || !c.isVariable(s.Record) {
+		if t != invalidType {
+			c.errorf(c.exprPos(s.Record, s.Start),
+				fmt.Sprintf("'with' requires a record variable, got '%s'", variableName(s.Record)),
+				"Only the fields of a record variable can be opened as a scope.",
+				"Name a variable of a record type after 'with'.",
+			)
+		}
+		c.stmt(s.Body)
+		return
+	}
+
+	saved := c.scope
+	c.scope = newScope(saved.name, saved)
+	c.scope.with = true
+	defer func() { c.scope = saved }()
+
+	for _, f := range record.fields {
+		c.scope.define(&symbol{kind: variableSymbol, name: f.name, typ: f.typ})
+	}
+	c.stmt(s.Body)
+}
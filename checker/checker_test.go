@@ -0,0 +1,619 @@
+package checker
+
+import (
+	"pastel/ast"
+	"pastel/lexer"
+	"pastel/parser"
+	"strings"
+	"testing"
+)
+
+func checkProgram(t *testing.T, input string) []*Diagnostic {
+	t.Helper()
+	return checkProgramIn(t, ast.ISO, input)
+}
+
+func checkProgramIn(t *testing.T, dialect ast.Dialect, input string) []*Diagnostic {
+	t.Helper()
+	p := parser.New(lexer.New(input), parser.WithDialect(dialect))
+	prog := p.ParseProgram()
+	if p.HasErrors() {
+		t.Fatalf("unexpected parser errors: %v", p.Errors())
+	}
+	return Check(prog)
+}
+
+func TestCheck_ValidPrograms(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"arithmetic", `program test;
+var i: integer; r: real;
+begin
+  i := 7 div 2 + 7 mod 2;
+  r := i / 2 + 1.5;
+  r := i;
+  writeln(i:4, r:6:2)
+end.`},
+		{"routines", `program test;
+var total: integer;
+procedure add(var acc: integer; n: integer);
+begin
+  acc := acc + n
+end;
+function add2(a, b: integer): integer;
+begin
+  add2 := a + b
+end;
+function twice(n: integer): integer;
+begin
+  twice := add2(n, n)
+end;
+begin
+  total := 0;
+  add(total, 5);
+  writeln(total)
+end.`},
+		{"recursion", `program test;
+function fact(n: integer): integer;
+begin
+  if n <= 1 then fact := 1 else fact := n * fact(n - 1)
+end;
+begin
+  writeln(fact(5))
+end.`},
+		{"structured types", `program test;
+type
+  color = (red, green, blue);
+  node = ^cell;
+  cell = record value: integer; next: node end;
+var
+  c: color;
+  s: set of color;
+  a: array[1..3] of color;
+  p: node;
+  name: packed array[1..5] of char;
+begin
+  s := [red, blue];
+  if green in s then c := green else c := succ(red);
+  a[1] := c;
+  new(p);
+  p^.value := ord(c);
+  p^.next := nil;
+  with p^ do value := value + 1;
+  name := 'hello';
+  case c of
+    red: writeln('r');
+    green, blue: writeln(name)
+  end
+end.`},
+		{"labels and goto", `program test;
+label 10, 99;
+var i: integer;
+procedure stop;
+begin
+  goto 99
+end;
+begin
+  i := 0;
+  10: i := i + 1;
+  if i < 3 then goto 10 else stop;
+  99:
+end.`},
+		{"forward declarations", `program test;
+procedure expression(depth: integer); forward;
+function term(depth: integer): integer; forward;
+procedure factor(depth: integer);
+begin
+  if depth > 0 then expression(depth - 1)
+end;
+procedure expression;
+begin
+  writeln(term(depth))
+end;
+function term(depth: integer): integer;
+begin
+  factor(depth);
+  term := depth
+end;
+begin
+  expression(3)
+end.`},
+		{"procedural parameters", `program test;
+function integrate(function f(x: real): real; a, b: real): real;
+begin
+  integrate := (b - a) * f((a + b) / 2)
+end;
+function half(x: real): real;
+begin
+  half := x / 2
+end;
+procedure each(procedure visit(k: integer));
+var k: integer;
+begin
+  for k := 1 to 3 do visit(k)
+end;
+procedure show(k: integer);
+begin
+  writeln(k)
+end;
+procedure relay(procedure visit(k: integer));
+begin
+  each(visit)
+end;
+begin
+  writeln(integrate(half, 0, 2));
+  relay(show)
+end.`},
+		{"conformant arrays", `program test;
+type row = array[1..3] of real; grid = array[1..2, 1..3] of real;
+var r, q: row; g: grid; name: packed array[1..5] of char;
+procedure sort(var a: array[lo..hi: integer] of real);
+var i: integer; t: real;
+begin
+  for i := lo to hi - 1 do
+    if a[i] > a[i + 1] then begin t := a[i]; a[i] := a[i + 1]; a[i + 1] := t end
+end;
+function dot(a, b: array[lo..hi: integer] of real): real;
+begin
+  dot := a[lo] * b[lo] + a[hi] * b[hi]
+end;
+procedure each(var m: array[r1..r2: integer; c1..c2: integer] of real);
+begin
+  sort(m[r1]);
+  writeln(c2 - c1)
+end;
+procedure show(s: packed array[lo..hi: integer] of char);
+begin
+  writeln(s[lo], hi)
+end;
+begin
+  sort(r);
+  writeln(dot(r, q));
+  each(g);
+  show(name);
+  show('abc')
+end.`},
+		{"files and input", `program test(input, output);
+var f: text; x: integer; line: string;
+begin
+  read(x);
+  readln(line);
+  assign(f, 'out.txt');
+  rewrite(f);
+  writeln(f, x, line);
+  close(f)
+end.`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diags := checkProgram(t, tt.input); len(diags) != 0 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+		})
+	}
+}
+
+func TestCheck_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		msg    string
+		line   int
+		column int
+	}{
+		{"undeclared identifier", `program test;
+var x: integer;
+begin
+  x := y
+end.`, "Undeclared identifier 'y'", 4, 8},
+		{"undeclared assignment target", `program test;
+begin
+  z := 1
+end.`, "Undeclared identifier 'z'", 3, 3},
+		{"use before declaration", `program test;
+procedure show;
+begin
+  writeln(count)
+end;
+var count: integer;
+begin
+  show
+end.`, "'count' is used before it is declared", 4, 11},
+		{"assignment mismatch", `program test;
+var x: integer;
+begin
+  x := 'abc'
+end.`, "Type mismatch in assignment", 4, 3},
+		{"real into integer", `program test;
+var x: integer;
+begin
+  x := 10 / 2
+end.`, "Cannot assign a real value to an integer", 4, 3},
+		{"distinct array types", `program test;
+var a: array[1..3] of integer; b: array[1..3] of integer;
+begin
+  a := b
+end.`, "Type mismatch in assignment", 4, 3},
+		{"non-boolean condition", `program test;
+var x: integer;
+begin
+  if x then writeln(x)
+end.`, "Condition of 'if' must be boolean", 4, 6},
+		{"operand types", `program test;
+var x: integer;
+begin
+  x := x + true
+end.`, "Type mismatch in '+'", 4, 10},
+		{"div on reals", `program test;
+var r: real;
+begin
+  r := r div 2
+end.`, "Type mismatch in 'div'", 4, 10},
+		{"duplicate declaration", `program test;
+var x: integer;
+    x: real;
+begin
+end.`, "Duplicate declaration of 'x'", 3, 5},
+		{"unknown type", `program test;
+var x: colour;
+begin
+end.`, "Unknown type 'colour'", 2, 5},
+		{"argument count", `program test;
+procedure p(a, b: integer);
+begin
+end;
+begin
+  p(1)
+end.`, "Wrong number of arguments to 'p'", 6, 3},
+		{"value argument type", `program test;
+procedure p(a: integer);
+begin
+end;
+begin
+  p('x')
+end.`, "Type mismatch in argument 'a' of 'p'", 6, 5},
+		{"var argument not a variable", `program test;
+procedure inc(var a: integer);
+begin
+  a := a + 1
+end;
+begin
+  inc(3)
+end.`, "Argument for var parameter 'a' of 'inc' must be a variable", 7, 7},
+		{"subrange for var integer", `program test;
+var small: 1..5;
+procedure p(var x: integer);
+begin
+  x := 100
+end;
+begin
+  p(small)
+end.`, "Type mismatch in var parameter 'x' of 'p'", 8, 5},
+		{"integer for var subrange", `program test;
+type digit = 0..9;
+var n: integer;
+procedure p(var d: digit);
+begin
+end;
+begin
+  p(n)
+end.`, "Type mismatch in var parameter 'd' of 'p'", 8, 5},
+		{"assignment to control variable", `program test;
+var i: integer;
+begin
+  for i := 1 to 3 do begin writeln(i); i := 5 end
+end.`, "Cannot assign to for-loop control variable 'i'", 4, 40},
+		{"control variable for var parameter", `program test;
+var i: integer;
+procedure bump(var n: integer);
+begin
+  n := n + 1
+end;
+begin
+  for i := 1 to 3 do bump(i)
+end.`, "Cannot pass for-loop control variable 'i' to var parameter 'n' of 'bump'", 8, 27},
+		{"read into control variable", `program test;
+var i: integer;
+begin
+  for i := 1 to 3 do read(i)
+end.`, "Cannot read into for-loop control variable 'i'", 4, 27},
+		{"control variable of enclosing loop", `program test;
+var i: integer;
+begin
+  for i := 1 to 3 do
+    for i := 1 to 2 do writeln(i)
+end.`, "For-loop control variable 'i' is already in use", 5, 5},
+		{"assignment to constant", `program test;
+const max = 10;
+begin
+  max := 11
+end.`, "Cannot assign to constant 'max'", 4, 3},
+		{"assignment to enumeration constant", `program test;
+type color = (red, green);
+begin
+  red := green
+end.`, "Cannot assign to constant 'red'", 4, 3},
+		{"assignment to function outside it", `program test;
+function f: integer;
+begin
+  f := 1
+end;
+begin
+  f := 2
+end.`, "Cannot assign to function 'f'", 7, 3},
+		{"builtin argument type", `program test;
+var c: char;
+begin
+  c := chr('a')
+end.`, "Argument of 'chr' must be integer", 4, 8},
+		{"ordinal builtin argument", `program test;
+begin
+  writeln(ord(1.5))
+end.`, "Argument of 'ord' must be ordinal", 3, 11},
+		{"procedure in expression", `program test;
+var x: integer;
+procedure p;
+begin
+end;
+begin
+  x := p
+end.`, "Procedure 'p' cannot be used in an expression", 7, 8},
+		{"unknown field", `program test;
+type point = record x, y: integer end;
+var p: point;
+begin
+  p.z := 1
+end.`, "Unknown field 'z'", 5, 3},
+		{"forward never completed", `program test;
+procedure p(x: integer); forward;
+begin
+end.`, "Forward declaration of 'p' is never completed", 2, 1},
+		{"forward completed with other kind", `program test;
+procedure p; forward;
+function p: integer;
+begin
+  p := 1
+end;
+begin
+end.`, "Heading of 'p' does not match its forward declaration", 3, 1},
+		{"forward completed with other parameters", `program test;
+procedure p(x: integer); forward;
+procedure p(x: real);
+begin
+end;
+begin
+  p(1)
+end.`, "Heading of 'p' does not match its forward declaration", 3, 1},
+		{"forward declared twice", `program test;
+procedure p; forward;
+procedure p; forward;
+procedure p;
+begin
+end;
+begin
+end.`, "Duplicate declaration of 'p'", 3, 1},
+		{"function without result type", `program test;
+function f;
+begin
+end;
+begin
+end.`, "Function 'f' has no result type", 2, 1},
+		{"function for procedural parameter", `program test;
+procedure each(procedure visit(k: integer));
+begin
+end;
+function f(k: integer): integer;
+begin
+  f := k
+end;
+begin
+  each(f)
+end.`, "Argument for procedural parameter 'visit' of 'each' must be a procedure", 10, 8},
+		{"incongruent functional parameter", `program test;
+function integrate(function f(x: real): real): real;
+begin
+  integrate := f(1)
+end;
+function g(x: integer): real;
+begin
+  g := x
+end;
+begin
+  writeln(integrate(g))
+end.`, "Type mismatch in functional parameter 'f' of 'integrate'", 11, 21},
+		{"required function as parameter", `program test;
+function integrate(function f(x: real): real): real;
+begin
+  integrate := f(1)
+end;
+begin
+  writeln(integrate(sin))
+end.`, "Required function 'sin' cannot be passed as a parameter", 7, 21},
+		{"call through parameter", `program test;
+procedure each(procedure visit(k: integer));
+begin
+  visit('a')
+end;
+begin
+end.`, "Type mismatch in argument 'k' of 'visit'", 4, 9},
+		{"assignment to bound identifier", `program test;
+procedure p(var a: array[lo..hi: integer] of real);
+begin
+  hi := hi - 1
+end;
+begin
+end.`, "Cannot assign to bound identifier 'hi'", 4, 3},
+		{"non-conformable element type", `program test;
+var x: array[1..3] of integer;
+procedure p(var a: array[lo..hi: integer] of real);
+begin
+end;
+begin
+  p(x)
+end.`, "Type mismatch in argument 'a' of 'p'", 7, 5},
+		{"index outside schema index type", `program test;
+type index = 1..10;
+var x: array[0..3] of real;
+procedure p(var a: array[lo..hi: index] of real);
+begin
+end;
+begin
+  p(x)
+end.`, "Type mismatch in argument 'a' of 'p'", 8, 5},
+		{"packedness differs", `program test;
+var x: array[1..3] of char;
+procedure p(a: packed array[lo..hi: integer] of char);
+begin
+end;
+begin
+  p(x)
+end.`, "Type mismatch in argument 'a' of 'p'", 7, 5},
+		{"different types for one schema", `program test;
+var x: array[1..3] of real; y: array[1..4] of real;
+procedure p(a, b: array[lo..hi: integer] of real);
+begin
+end;
+begin
+  p(x, y)
+end.`, "Type mismatch in argument 'b' of 'p'", 7, 8},
+		{"Turbo procedure in ISO", `program test;
+var i: integer;
+begin
+  i := 0;
+  inc(i)
+end.`, "Procedure 'inc' requires the Turbo Pascal dialect", 5, 3},
+		{"exit in ISO", `program test;
+procedure p;
+begin
+  exit
+end;
+begin
+end.`, "Procedure 'exit' requires the Turbo Pascal dialect", 4, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := checkProgram(t, tt.input)
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostic %q, got none", tt.msg)
+			}
+			d := diags[0]
+			if d.Msg != tt.msg {
+				t.Fatalf("wrong diagnostic. expected=%q, got=%q", tt.msg, d.Msg)
+			}
+			if d.Line != tt.line || d.Column != tt.column {
+				t.Fatalf("wrong position for %q. expected=%d:%d, got=%d:%d", d.Msg, tt.line, tt.column, d.Line, d.Column)
+			}
+		})
+	}
+}
+
+func TestCheck_TurboDialect(t *testing.T) {
+	valid := `program test;
+type point = record x, y: integer end;
+const
+  origin: point = (x: 0; y: 0);
+  primes: array[1..3] of integer = (2, 3, 5);
+  name: string[8] = 'pastel';
+var s: string[5]; k: integer; c: char;
+procedure count;
+const calls: integer = 0;
+begin
+  inc(calls);
+  if calls > 10 then exit
+end;
+begin
+  s := name;
+  s[1] := 'P';
+  c := s[0];
+  for k := 1 to 3 do
+    begin
+      if k = 2 then continue;
+      repeat inc(c, 2); break until false
+    end;
+  dec(k);
+  count;
+  halt(primes[1])
+end.`
+	if diags := checkProgramIn(t, ast.Turbo, valid); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		name   string
+		decls  string
+		stmt   string
+		msg    string
+		column int
+	}{
+		{"break outside loop", "", "break", "'break' is not inside a loop", 3},
+		{"inc of real", "var r: real;", "inc(r)", "'inc' requires an ordinal variable", 7},
+		{"inc of constant", "const n = 1;", "inc(n)", "'inc' requires an ordinal variable", 7},
+		{"real step", "var k: integer;", "dec(k, 1.5)", "Step of 'dec' must be integer", 10},
+		{"inc of control variable", "var k: integer;", "for k := 1 to 3 do inc(k)", "Cannot inc for-loop control variable 'k'", 26},
+		{"halt with string", "", "halt('no')", "Argument of 'halt' must be integer", 3},
+		{"exit with argument", "", "exit(1)", "Wrong number of arguments to 'exit'", 3},
+		{"string index type", "var s: string[5];", "s['a'] := 'b'", "Type mismatch in string index", 5},
+		{"array constant size", "const a: array[1..3] of integer = (1, 2);", "", "Wrong number of elements in array constant", 35},
+		{"record constant field", "type p = record x: integer end; const o: p = (y: 1);", "", "Unknown field 'y'", 47},
+		{"typed constant value", "const n: integer = 'a';", "", "Type mismatch in typed constant", 20},
+		{"short string for var string", "procedure p(var x: string); begin end; var s: string[3];", "p(s)", "Type mismatch in var parameter 'x' of 'p'", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := checkProgramIn(t, ast.Turbo, "program test;\n"+tt.decls+"\nbegin\n  "+tt.stmt+"\nend.")
+			if len(diags) == 0 {
+				t.Fatalf("expected diagnostic %q, got none", tt.msg)
+			}
+			line := 4
+			if tt.stmt == "" {
+				line = 2
+			}
+			if d := diags[0]; d.Msg != tt.msg || d.Line != line || d.Column != tt.column {
+				t.Fatalf("expected %q at %d:%d, got %q at %d:%d", tt.msg, line, tt.column, d.Msg, d.Line, d.Column)
+			}
+		})
+	}
+}
+
+func TestCheck_ReportsEveryError(t *testing.T) {
+	input := `program test;
+var x: integer; s: string;
+begin
+  x := s;
+  s := x;
+  writeln(missing)
+end.`
+
+	diags := checkProgram(t, input)
+	if len(diags) != 3 {
+		t.Fatalf("expected 3 diagnostics, got %d: %v", len(diags), diags)
+	}
+	for i, line := range []int{4, 5, 6} {
+		if diags[i].Line != line {
+			t.Errorf("diagnostic %d at line %d, expected line %d", i, diags[i].Line, line)
+		}
+	}
+}
+
+func TestCheck_DiagnosticFormat(t *testing.T) {
+	input := `program test;
+begin
+  writeln(y)
+end.`
+
+	diags := checkProgram(t, input)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+	}
+	msg := diags[0].Error()
+	for _, want := range []string{"[Semantic Error] at line 3, column 11", "Undeclared identifier 'y'", "Hint:"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("diagnostic %q does not contain %q", msg, want)
+		}
+	}
+}
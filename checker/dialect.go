@@ -0,0 +1,159 @@
+package checker
+
+import (
+	"fmt"
+	"pastel/ast"
+)
+
+var turboProcedures = map[string]func(c *checker, call *builtinCall){
+	"inc":      builtinIncDec,
+	"dec":      builtinIncDec,
+	"halt":     builtinHalt,
+	"exit":     builtinExit,
+	"break":    loopControl,
+	"continue": loopControl,
+}
+
+var turboAlternatives = map[string]string{
+	"inc":      "write x := succ(x) instead",
+	"dec":      "write x := pred(x) instead",
+	"halt":     "goto a label on the last statement of the program instead",
+	"exit":     "goto a label on the last statement of the routine instead",
+	"break":    "goto a label on the statement after the loop instead",
+	"continue": "goto a label at the end of the loop body instead",
+}
+
+func (c *checker) turboProcedureError(pos ast.Position, name string) {
+	c.errorf(pos,
+		fmt.Sprintf("Procedure '%s' requires the %s dialect", name, ast.Turbo),
+		fmt.Sprintf("'%s' is a %s extension that %s does not have.", name, ast.Turbo, ast.ISO),
+		fmt.Sprintf("Select the dialect with -dialect=turbo, or %s.", turboAlternatives[name]),
+	)
+}
+
+func (c *checker) argumentCountError(call *builtinCall, expects, usage string) {
+	c.errorf(call.pos,
+		fmt.Sprintf("Wrong number of arguments to '%s'", call.name),
+		fmt.Sprintf("'%s' expects %s but was given %d.", call.name, expects, len(call.args)),
+		fmt.Sprintf("Call it as %s.", usage),
+	)
+}
+
+func builtinIncDec(c *checker, call *builtinCall) {
+	if len(call.args) < 1 || len(call.args) > 2 {
+		c.argumentCountError(call, "1 or 2 arguments", call.name+"(x) or "+call.name+"(x, n)")
+		return
+	}
+	if t := call.args[0]; t != invalidType && (!isOrdinal(t) || !c.isVariable(call.exprs[0])) {
+		c.errorf(c.exprPos(call.exprs[0], call.pos),
+			fmt.Sprintf("'%s' requires an ordinal variable", call.name),
+			fmt.Sprintf("The first argument has type %s and must be a variable of an ordinal type.", t),
+			fmt.Sprintf("Pass %s an integer, char, boolean or enumerated variable.", call.name),
+		)
+	} else if name, ok := c.controlVariable(call.exprs[0]); ok {
+		c.controlVariableError(c.exprPos(call.exprs[0], call.pos), fmt.Sprintf("Cannot %s for-loop control variable '%s'", call.name, name))
+	}
+	if len(call.args) == 2 && call.args[1] != invalidType && !isInteger(call.args[1]) {
+		c.errorf(c.exprPos(call.exprs[1], call.pos),
+			fmt.Sprintf("Step of '%s' must be integer", call.name),
+			fmt.Sprintf("The second argument has type %s.", call.args[1]),
+			fmt.Sprintf("Pass the number of steps as an integer, e.g. %s(x, 2).", call.name),
+		)
+	}
+}
+
+func builtinHalt(c *checker, call *builtinCall) {
+	if len(call.args) > 1 {
+		c.argumentCountError(call, "at most 1 argument", "halt or halt(code)")
+		return
+	}
+	if len(call.args) == 1 && call.args[0] != invalidType && !isInteger(call.args[0]) {
+		c.argumentTypeError(call, "integer")
+	}
+}
+
+func builtinExit(c *checker, call *builtinCall) {
+	if len(call.args) > 0 {
+		c.argumentCountError(call, "no arguments", "exit")
+	}
+}
+
+func loopControl(c *checker, call *builtinCall) {
+	if len(call.args) > 0 {
+		c.argumentCountError(call, "no arguments", call.name)
+		return
+	}
+	if c.loops == 0 {
+		c.errorf(call.pos,
+			fmt.Sprintf("'%s' is not inside a loop", call.name),
+			fmt.Sprintf("'%s' applies to the innermost while, repeat or for loop of its block.", call.name),
+			"Use exit to leave a procedure or function.",
+		)
+	}
+}
+
+func (c *checker) stringIndex(e *ast.IndexExpr, index Type) Type {
+	if index != invalidType && !isInteger(index) {
+		c.errorf(c.exprPos(e.Index, c.pos),
+			"Type mismatch in string index",
+			fmt.Sprintf("'%s' is a string, indexed by integer, but the index has type %s.", variableName(e.Array), index),
+			"Index a string with the position of a character, starting at 1.",
+		)
+	}
+	return charType
+}
+
+func (c *checker) typedConstant(t Type, value ast.Expr) {
+	if t == invalidType {
+		return
+	}
+	switch v := value.(type) {
+	case *ast.ArrayConstant:
+		arr, ok := t.(*arrayType)
+		if !ok {
+			c.typedConstantError(v.Start, t, "an array")
+			return
+		}
+		if n := arr.high - arr.low + 1; len(v.Elements) != n {
+			c.errorf(v.Start,
+				"Wrong number of elements in array constant",
+				fmt.Sprintf("The type %s has %d elements but the constant gives %d.", t, n, len(v.Elements)),
+				"Give one value for every element of the array.",
+			)
+			return
+		}
+		for _, el := range v.Elements {
+			c.typedConstant(arr.element, el)
+		}
+
+	case *ast.RecordConstant:
+		record, ok := t.(*recordType)
+		if !ok {
+			c.typedConstantError(v.Start, t, "a record")
+			return
+		}
+		for _, fc := range v.Fields {
+			f, ok := record.field(fc.Name)
+			if !ok {
+				c.errorf(fc.Start,
+					fmt.Sprintf("Unknown field '%s'", fc.Name),
+					fmt.Sprintf("Type %s has no field named '%s'.", t, fc.Name),
+					"Check the spelling of the field name against the record declaration.",
+				)
+				continue
+			}
+			c.typedConstant(f.typ, fc.Value)
+		}
+
+	default:
+		c.checkAssignable(t, c.expr(value), c.exprPos(value, c.pos), "typed constant")
+	}
+}
+
+func (c *checker) typedConstantError(pos ast.Position, t Type, kind string) {
+	c.errorf(pos,
+		"Type mismatch in typed constant",
+		fmt.Sprintf("The value is %s constant but the type of the constant is %s.", kind, t),
+		"Give arrays as (1, 2, 3), records as (x: 1; y: 2) and other values as constant expressions.",
+	)
+}
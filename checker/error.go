@@ -0,0 +1,28 @@
+package checker
+
+import "fmt"
+
+// Diagnostic describes a semantic error found before the program runs.
+type Diagnostic struct {
+	Msg    string
+	Detail string
+	Hint   string
+	Line   int
+	Column int
+}
+
+func (d *Diagnostic) Error() string {
+	var msg string
+	if d.Line > 0 {
+		msg = fmt.Sprintf("\n[Semantic Error] at line %d, column %d: %s", d.Line, d.Column, d.Msg)
+	} else {
+		msg = fmt.Sprintf("\n[Semantic Error] %s", d.Msg)
+	}
+	if d.Detail != "" {
+		msg += fmt.Sprintf("\n  → %s", d.Detail)
+	}
+	if d.Hint != "" {
+		msg += fmt.Sprintf("\n  Hint: %s", d.Hint)
+	}
+	return msg
+}
@@ -0,0 +1,430 @@
+package checker
+
+import (
+	"fmt"
+	"pastel/ast"
+	"pastel/token"
+)
+
+func (c *checker) expr(expr ast.Expr) Type {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return integerType
+	case *ast.RealLiteral:
+		return realType
+	case *ast.BooleanLiteral:
+		return booleanType
+	case *ast.CharLiteral:
+		return charType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.NilLiteral:
+		return nilType
+
+	case *ast.Identifier:
+		return c.identifier(e)
+
+	case *ast.CallExpr:
+		return c.call(e)
+
+	case *ast.IndexExpr:
+		return c.index(e)
+
+	case *ast.FieldExpr:
+		base := c.expr(e.Record)
+		if base == invalidType {
+			return invalidType
+		}
+		record, ok := base.(*recordType)
+		if !ok {
+			c.errorf(c.exprPos(e, c.pos),
+				fmt.Sprintf("Cannot select field '%s' of '%s'", e.Field, variableName(e.Record)),
+				fmt.Sprintf("'%s' has type %s, which is not a record.", variableName(e.Record), base),
+				"Only record variables have fields.",
+			)
+			return invalidType
+		}
+		f, ok := record.field(e.Field)
+		if !ok {
+			c.errorf(c.exprPos(e, c.pos),
+				fmt.Sprintf("Unknown field '%s'", e.Field),
+				fmt.Sprintf("'%s' has type %s, which has no field named '%s'.", variableName(e.Record), record, e.Field),
+				"Check the spelling against the fields in the record type declaration.",
+			)
+			return invalidType
+		}
+		return f.typ
+
+	case *ast.DerefExpr:
+		base := c.expr(e.Pointer)
+		if base == invalidType {
+			return invalidType
+		}
+		ptr, ok := base.(*pointerType)
+		if !ok {
+			name := variableName(e.Pointer)
+			c.errorf(c.exprPos(e, c.pos),
+				fmt.Sprintf("Cannot dereference '%s'", name),
+				fmt.Sprintf("'%s' has type %s, which is not a pointer.", name, base),
+				"Only pointer variables can be followed by ^.",
+			)
+			return invalidType
+		}
+		if target := ptr.resolve(); target != nil {
+			return target
+		}
+		return invalidType
+
+	case *ast.SetExpr:
+		return c.setConstructor(e)
+
+	case *ast.UnaryExpr:
+		return c.unary(e)
+
+	case *ast.BinaryExpr:
+		return c.binary(e)
+
+	case *ast.FormatExpr:
+		c.errorf(c.exprPos(e, c.pos),
+			"Field widths can only be used in write and writeln",
+			"The ':' format specifiers describe how a value is written.",
+			"Remove the format specifiers from the expression.",
+		)
+		return invalidType
+	}
+	return invalidType
+}
+
+func (c *checker) identifier(e *ast.Identifier) Type {
+	sym := c.scope.lookup(e.Value)
+	if sym == nil {
+		return c.undeclaredError(e.Start, e.Value)
+	}
+	switch sym.kind {
+	case variableSymbol, constantSymbol, boundSymbol:
+		return sym.typ
+	case routineSymbol:
+		if sym.result == nil {
+			c.procedureInExpressionError(e.Start, sym.name)
+			return invalidType
+		}
+		c.arguments(e.Start, sym, nil)
+		return sym.result
+	case builtinFunctionSymbol:
+		return c.callBuiltinFunction(e.Start, e.Value, builtinFunctions[e.Value], nil)
+	case builtinProcedureSymbol:
+		c.procedureInExpressionError(e.Start, sym.name)
+		return invalidType
+	default:
+		c.errorf(e.Start,
+			fmt.Sprintf("'%s' is a type, not a value", e.Value),
+			"Type names describe values but cannot be used as values themselves.",
+			fmt.Sprintf("Declare a variable of type %s and use that instead.", e.Value),
+		)
+		return invalidType
+	}
+}
+
+func (c *checker) call(e *ast.CallExpr) Type {
+	sym := c.scope.lookup(e.Name)
+	if sym == nil {
+		c.undeclaredRoutineError(e.Start, e.Name)
+		for _, arg := range e.Args {
+			c.expr(arg)
+		}
+		return invalidType
+	}
+	switch sym.kind {
+	case builtinFunctionSymbol:
+		return c.callBuiltinFunction(e.Start, e.Name, builtinFunctions[e.Name], e.Args)
+	case routineSymbol:
+		if sym.result == nil {
+			c.procedureInExpressionError(e.Start, sym.name)
+			return invalidType
+		}
+		c.arguments(e.Start, sym, e.Args)
+		return sym.result
+	case builtinProcedureSymbol:
+		c.procedureInExpressionError(e.Start, sym.name)
+		return invalidType
+	default:
+		c.notRoutineError(e.Start, sym)
+		return invalidType
+	}
+}
+
+func (c *checker) index(e *ast.IndexExpr) Type {
+	base := c.expr(e.Array)
+	index := c.expr(e.Index)
+	if base == invalidType {
+		return invalidType
+	}
+	if c.dialect == ast.Turbo && host(base) == stringType {
+		return c.stringIndex(e, index)
+	}
+	arr, ok := base.(*arrayType)
+	if !ok {
+		c.errorf(c.exprPos(e, c.pos),
+			fmt.Sprintf("Cannot index '%s'", variableName(e.Array)),
+			fmt.Sprintf("'%s' has type %s, which is not an array.", variableName(e.Array), base),
+			"Only array variables can be indexed with [...].",
+		)
+		return invalidType
+	}
+	if index != invalidType && host(index) != host(arr.index) {
+		c.errorf(c.exprPos(e.Index, c.pos),
+			"Type mismatch in array index",
+			fmt.Sprintf("'%s' is indexed by %s but the index has type %s.", variableName(e.Array), arr.index, index),
+			"Index an array with values of its declared index type.",
+		)
+	}
+	return arr.element
+}
+
+func (c *checker) setConstructor(e *ast.SetExpr) Type {
+	var element Type
+	for _, el := range e.Elements {
+		for _, bound := range []ast.Expr{el.Low, el.High} {
+			if bound == nil {
+				continue
+			}
+			t := c.expr(bound)
+			if t == invalidType {
+				continue
+			}
+			switch {
+			case !isOrdinal(t):
+				c.errorf(c.exprPos(bound, c.pos),
+					"Set elements must be ordinal",
+					fmt.Sprintf("An element of the set constructor has type %s.", t),
+					"Build sets from integer, char, boolean or enumeration values.",
+				)
+			case element == nil:
+				element = host(t)
+			case host(t) != element:
+				c.errorf(c.exprPos(bound, c.pos),
+					"Type mismatch in set constructor",
+					fmt.Sprintf("The set has elements of type %s and of type %s.", element, t),
+					"All elements of a set must have the same type.",
+				)
+			}
+		}
+	}
+	return &setType{element: element}
+}
+
+func (c *checker) unary(e *ast.UnaryExpr) Type {
+	operand := c.expr(e.Operand)
+	if operand == invalidType {
+		return invalidType
+	}
+	if e.Operator.Type == token.NOT {
+		if isBoolean(operand) {
+			return booleanType
+		}
+		c.errorf(tokenPos(e.Operator),
+			"Type mismatch in 'not'",
+			fmt.Sprintf("Operator 'not' requires a boolean operand, got %s.", operand),
+			"Apply 'not' only to boolean expressions.",
+		)
+		return invalidType
+	}
+	if isNumeric(operand) {
+		return host(operand)
+	}
+	c.errorf(tokenPos(e.Operator),
+		fmt.Sprintf("Type mismatch in unary '%s'", e.Operator.Literal),
+		fmt.Sprintf("A sign requires an integer or real operand, got %s.", operand),
+		"Apply '+' and '-' only to numbers; use 'not' to negate booleans.",
+	)
+	return invalidType
+}
+
+func (c *checker) binary(e *ast.BinaryExpr) Type {
+	left := c.expr(e.Left)
+	right := c.expr(e.Right)
+	if left == invalidType || right == invalidType {
+		return invalidType
+	}
+	if t := binaryResult(e.Operator.Type, left, right); t != nil {
+		return t
+	}
+
+	hint := "Ensure both operands are numeric types."
+	switch e.Operator.Type {
+	case token.AND, token.OR:
+		hint = "Parenthesize comparisons, e.g. (a < b) and (c < d)."
+	case token.DIV, token.MOD:
+		hint = "Use '/' to divide real numbers, or convert them with trunc or round first."
+	case token.IN:
+		hint = "The right operand of 'in' must be a set of the left operand's type."
+	case token.EQUAL, token.NEQ, token.LT, token.LE, token.GT, token.GE:
+		hint = "Compare values of the same type; integers and reals may be mixed."
+	}
+	if _, isSet := left.(*setType); isSet {
+		hint = "Sets support +, -, * and the relations =, <>, <= and >= with sets of the same base type."
+	}
+	if _, isPointer := left.(*pointerType); isPointer {
+		hint = "Compare a pointer with nil or with a pointer to the same type, using '=' or '<>'."
+	}
+	c.errorf(tokenPos(e.Operator),
+		fmt.Sprintf("Type mismatch in '%s'", e.Operator.Literal),
+		fmt.Sprintf("Operator '%s' cannot be applied to %s and %s.", e.Operator.Literal, left, right),
+		hint,
+	)
+	return invalidType
+}
+
+func binaryResult(op token.TokenType, left, right Type) Type {
+	ls, lIsSet := left.(*setType)
+	rs, rIsSet := right.(*setType)
+	if lIsSet && rIsSet {
+		if ls.element != nil && rs.element != nil && ls.element != rs.element {
+			return nil
+		}
+		switch op {
+		case token.PLUS, token.MINUS, token.STAR:
+			if ls.element == nil {
+				return rs
+			}
+			return ls
+		case token.EQUAL, token.NEQ, token.LE, token.GE:
+			return booleanType
+		}
+		return nil
+	}
+
+	lh, rh := host(left), host(right)
+	switch op {
+	case token.PLUS, token.MINUS, token.STAR:
+		switch {
+		case lh == integerType && rh == integerType:
+			return integerType
+		case isNumeric(lh) && isNumeric(rh):
+			return realType
+		case op == token.PLUS && (lh == stringType || lh == charType) && (rh == stringType || rh == charType):
+			return stringType
+		}
+	case token.SLASH:
+		if isNumeric(lh) && isNumeric(rh) {
+			return realType
+		}
+	case token.DIV, token.MOD:
+		if lh == integerType && rh == integerType {
+			return integerType
+		}
+	case token.AND, token.OR:
+		if lh == booleanType && rh == booleanType {
+			return booleanType
+		}
+	case token.IN:
+		if rIsSet && isOrdinal(lh) && (rs.element == nil || rs.element == lh) {
+			return booleanType
+		}
+	case token.EQUAL, token.NEQ:
+		if _, isPointer := lh.(*pointerType); isPointer || lh == nilType {
+			if assignable(left, right) || assignable(right, left) {
+				return booleanType
+			}
+			return nil
+		}
+		if comparable(left, right) {
+			return booleanType
+		}
+	case token.LT, token.LE, token.GT, token.GE:
+		if comparable(left, right) {
+			return booleanType
+		}
+	}
+	return nil
+}
+
+func (c *checker) isVariable(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		sym := c.scope.lookup(e.Value)
+		return sym == nil || sym.kind == variableSymbol
+	case *ast.IndexExpr:
+		return c.isVariable(e.Array)
+	case *ast.FieldExpr:
+		return c.isVariable(e.Record)
+	case *ast.DerefExpr:
+		return true
+	}
+	return false
+}
+
+func (c *checker) exprPos(expr ast.Expr, fallback ast.Position) ast.Position {
+	if expr == nil || expr.SourceSpan().Start.Line == 0 {
+		return fallback
+	}
+	return expr.SourceSpan().Start
+}
+
+func tokenPos(tok token.Token) ast.Position {
+	return ast.Position{Line: tok.Line, Column: tok.Column, Offset: tok.Offset}
+}
+
+func variableName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.IndexExpr:
+		return variableName(e.Array)
+	case *ast.FieldExpr:
+		return variableName(e.Record) + "." + e.Field
+	case *ast.DerefExpr:
+		return variableName(e.Pointer) + "^"
+	default:
+		return "expression"
+	}
+}
+
+func (c *checker) undeclaredError(pos ast.Position, name string) Type {
+	if c.scope.declaredLater(name) {
+		c.errorf(pos,
+			fmt.Sprintf("'%s' is used before it is declared", name),
+			fmt.Sprintf("'%s' is declared further down in the block, but Pascal requires declarations to come first.", name),
+			fmt.Sprintf("Move the declaration of '%s' above the code that uses it.", name),
+		)
+		return invalidType
+	}
+	if _, ok := turboProcedures[name]; ok {
+		c.turboProcedureError(pos, name)
+		return invalidType
+	}
+	c.errorf(pos,
+		fmt.Sprintf("Undeclared identifier '%s'", name),
+		fmt.Sprintf("'%s' is used here but never declared.", name),
+		fmt.Sprintf("Declare it before use, e.g. `var %s: integer;`.", name),
+	)
+	return invalidType
+}
+
+func (c *checker) undeclaredRoutineError(pos ast.Position, name string) {
+	if _, ok := turboProcedures[name]; ok || c.scope.declaredLater(name) {
+		c.undeclaredError(pos, name)
+		return
+	}
+	c.errorf(pos,
+		fmt.Sprintf("Undefined procedure or function '%s'", name),
+		"This name is being called but was never declared.",
+		fmt.Sprintf("Declare it with `procedure %s;` or `function %s: integer;` before the code that calls it.", name, name),
+	)
+}
+
+func (c *checker) notRoutineError(pos ast.Position, sym *symbol) {
+	c.errorf(pos,
+		fmt.Sprintf("'%s' is not a procedure or function", sym.name),
+		fmt.Sprintf("'%s' is a %s of type %s.", sym.name, sym.describe(), sym.typ),
+		"Only procedures and functions can be called.",
+	)
+}
+
+func (c *checker) procedureInExpressionError(pos ast.Position, name string) {
+	c.errorf(pos,
+		fmt.Sprintf("Procedure '%s' cannot be used in an expression", name),
+		"Procedures do not return a value.",
+		"Call the procedure as a statement, or declare it as a function.",
+	)
+}
@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"pastel/ast"
+	"strings"
+)
+
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	constantSymbol
+	boundSymbol
+	typeSymbol
+	routineSymbol
+	builtinFunctionSymbol
+	builtinProcedureSymbol
+)
+
+func (k symbolKind) String() string {
+	switch k {
+	case variableSymbol:
+		return "variable"
+	case constantSymbol:
+		return "constant"
+	case boundSymbol:
+		return "bound identifier"
+	case typeSymbol:
+		return "type"
+	case routineSymbol:
+		return "routine"
+	default:
+		return "required procedure or function"
+	}
+}
+
+type symbol struct {
+	kind symbolKind
+	name string
+	typ  Type
+
+	varParam bool
+	ordinal  int
+
+	decl    *ast.ProcedureDecl
+	params  []*param
+	result  Type
+	forward *ast.ProcedureDecl
+}
+
+type param struct {
+	name    string
+	typ     Type
+	isVar   bool
+	routine *symbol
+}
+
+func (p *param) String() string {
+	switch {
+	case p.routine != nil:
+		return p.routine.signature()
+	case p.isVar:
+		return "var " + p.typ.String()
+	}
+	return p.typ.String()
+}
+
+func (s *symbol) describe() string {
+	if s.kind == routineSymbol {
+		if s.result != nil {
+			return "function"
+		}
+		return "procedure"
+	}
+	return s.kind.String()
+}
+
+func (s *symbol) signature() string {
+	sig := s.describe()
+	if len(s.params) > 0 {
+		params := make([]string, len(s.params))
+		for k, p := range s.params {
+			params[k] = p.String()
+		}
+		sig += "(" + strings.Join(params, "; ") + ")"
+	}
+	if s.result != nil {
+		sig += ": " + s.result.String()
+	}
+	return sig
+}
+
+func congruent(a, b *symbol) bool {
+	if len(a.params) != len(b.params) || (a.result == nil) != (b.result == nil) || !sameDeclaredType(a.result, b.result) {
+		return false
+	}
+	for k, p := range a.params {
+		if !sameParam(p, b.params[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameParam(p, q *param) bool {
+	if p.isVar != q.isVar || (p.routine == nil) != (q.routine == nil) {
+		return false
+	}
+	if p.routine != nil {
+		return congruent(p.routine, q.routine)
+	}
+	return sameDeclaredType(p.typ, q.typ)
+}
+
+func sameDeclaredType(a, b Type) bool {
+	if x, ok := a.(*arrayType); ok && x.schema != nil {
+		y, ok := b.(*arrayType)
+		return ok && y.schema != nil && x.packed == y.packed && x.index == y.index && sameDeclaredType(x.element, y.element)
+	}
+	return a == b || a == invalidType || b == invalidType
+}
+
+type scope struct {
+	name    string
+	outer   *scope
+	symbols map[string]*symbol
+	later   map[string]bool
+	routine *symbol
+	with    bool
+}
+
+func newScope(name string, outer *scope) *scope {
+	return &scope{name: name, outer: outer, symbols: map[string]*symbol{}, later: map[string]bool{}}
+}
+
+func (s *scope) define(sym *symbol) bool {
+	if _, exists := s.symbols[sym.name]; exists {
+		return false
+	}
+	s.symbols[sym.name] = sym
+	delete(s.later, sym.name)
+	return true
+}
+
+func (s *scope) lookup(name string) *symbol {
+	sym, _ := s.resolve(name)
+	return sym
+}
+
+func (s *scope) resolve(name string) (*symbol, *scope) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if sym, ok := scope.symbols[name]; ok {
+			return sym, scope
+		}
+	}
+	return nil, nil
+}
+
+func (s *scope) declaredLater(name string) bool {
+	for scope := s; scope != nil; scope = scope.outer {
+		if _, ok := scope.symbols[name]; ok {
+			return false
+		}
+		if scope.later[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scope) block() *scope {
+	for s.with {
+		s = s.outer
+	}
+	return s
+}
+
+func (s *scope) inRoutine(routine *symbol) bool {
+	for scope := s; scope != nil; scope = scope.outer {
+		if scope.routine == routine {
+			return true
+		}
+	}
+	return false
+}
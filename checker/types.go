@@ -0,0 +1,332 @@
+package checker
+
+import (
+	"fmt"
+	"pastel/ast"
+)
+
+// Type describes the static type of a variable or expression.
+type Type interface {
+	String() string
+}
+
+type basicType struct{ name string }
+
+func (t *basicType) String() string { return t.name }
+
+type enumType struct {
+	name   string
+	values []string
+}
+
+func (t *enumType) String() string { return t.name }
+
+type subrangeType struct {
+	host      Type
+	low, high int
+}
+
+func (t *subrangeType) String() string {
+	return fmt.Sprintf("%s..%s", ordinalLiteral(t.host, t.low), ordinalLiteral(t.host, t.high))
+}
+
+type arrayType struct {
+	packed    bool
+	index     Type
+	element   Type
+	low, high int
+	schema    *ast.ConformantArray
+}
+
+func (t *arrayType) String() string {
+	if t.schema != nil {
+		return t.schema.String()
+	}
+	prefix := ""
+	if t.packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sarray[%s] of %s", prefix, t.index, t.element)
+}
+
+type recordType struct {
+	name   string
+	fields []*field
+}
+
+func (t *recordType) String() string { return t.name }
+
+func (t *recordType) field(name string) (*field, bool) {
+	for _, f := range t.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+type field struct {
+	name string
+	typ  Type
+}
+
+type setType struct {
+	element Type
+}
+
+func (t *setType) String() string {
+	if t.element == nil {
+		return "set of []"
+	}
+	return fmt.Sprintf("set of %s", t.element)
+}
+
+type pointerType struct {
+	targetName string
+	scope      *scope
+	target     Type
+}
+
+func (t *pointerType) String() string { return "^" + t.targetName }
+
+func (t *pointerType) resolve() Type {
+	if t.target == nil {
+		if sym := t.scope.lookup(t.targetName); sym != nil && sym.kind == typeSymbol {
+			t.target = sym.typ
+		}
+	}
+	return t.target
+}
+
+type shortStringType struct {
+	size int
+}
+
+func (t *shortStringType) String() string { return fmt.Sprintf("string[%d]", t.size) }
+
+type fileType struct {
+	packed  bool
+	text    bool
+	element Type
+}
+
+func (t *fileType) String() string {
+	if t.text {
+		return "text"
+	}
+	prefix := ""
+	if t.packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sfile of %s", prefix, t.element)
+}
+
+var (
+	integerType = &basicType{"integer"}
+	realType    = &basicType{"real"}
+	booleanType = &basicType{"boolean"}
+	charType    = &basicType{"char"}
+	stringType  = &basicType{"string"}
+	textType    = &fileType{text: true, element: charType}
+	nilType     = &basicType{"nil"}
+	invalidType = &basicType{"invalid"}
+)
+
+const maxSetOrdinal = 255
+
+func host(t Type) Type {
+	switch s := t.(type) {
+	case *subrangeType:
+		return s.host
+	case *shortStringType:
+		return stringType
+	}
+	return t
+}
+
+func isOrdinal(t Type) bool {
+	switch h := host(t).(type) {
+	case *enumType:
+		return true
+	case *basicType:
+		return h == integerType || h == booleanType || h == charType
+	}
+	return false
+}
+
+func isNumeric(t Type) bool {
+	h := host(t)
+	return h == integerType || h == realType
+}
+
+func isInteger(t Type) bool {
+	return host(t) == integerType
+}
+
+func isBoolean(t Type) bool {
+	return host(t) == booleanType
+}
+
+func isStringType(t Type) bool {
+	arr, ok := t.(*arrayType)
+	return ok && arr.schema == nil && arr.packed && host(arr.element) == charType && host(arr.index) == integerType && arr.low == 1
+}
+
+func isText(t Type) bool {
+	h := host(t)
+	return h == stringType || h == charType || isStringType(t)
+}
+
+func ordinalBounds(t Type) (int, int, bool) {
+	switch t := t.(type) {
+	case *subrangeType:
+		return t.low, t.high, true
+	case *enumType:
+		return 0, len(t.values) - 1, true
+	}
+	switch t {
+	case booleanType:
+		return 0, 1, true
+	case charType:
+		return 0, 255, true
+	}
+	return 0, 0, false
+}
+
+func ordinalLiteral(t Type, ord int) string {
+	switch h := host(t).(type) {
+	case *enumType:
+		if ord >= 0 && ord < len(h.values) {
+			return h.values[ord]
+		}
+	case *basicType:
+		switch h {
+		case charType:
+			return fmt.Sprintf("'%c'", rune(ord))
+		case booleanType:
+			return fmt.Sprintf("%t", ord != 0)
+		}
+	}
+	return fmt.Sprintf("%d", ord)
+}
+
+func assignable(target, value Type) bool {
+	if target == invalidType || value == invalidType || target == value {
+		return true
+	}
+	switch t := target.(type) {
+	case *arrayType:
+		if isStringType(t) {
+			h := host(value)
+			return h == stringType || h == charType
+		}
+		return false
+	case *recordType:
+		return false
+	case *setType:
+		v, ok := value.(*setType)
+		return ok && (v.element == nil || t.element == nil || host(v.element) == host(t.element))
+	case *pointerType:
+		if value == nilType {
+			return true
+		}
+		v, ok := value.(*pointerType)
+		if !ok {
+			return false
+		}
+		tt, vt := t.resolve(), v.resolve()
+		return tt == nil || vt == nil || tt == vt
+	case *fileType:
+		return false
+	}
+
+	th, vh := host(target), host(value)
+	switch {
+	case th == vh:
+		return true
+	case th == realType:
+		return vh == integerType
+	case th == stringType:
+		return isText(value)
+	}
+	return false
+}
+
+func sameType(formal, actual Type) bool {
+	if formal == invalidType || actual == invalidType {
+		return true
+	}
+	switch formal.(type) {
+	case *setType, *pointerType:
+		return assignable(formal, actual) && actual != nilType
+	}
+	return formal == actual
+}
+
+func conforms(formal *arrayType, actual Type, isVar bool) bool {
+	if actual == invalidType || formal.index == invalidType {
+		return true
+	}
+	if host(actual) == stringType && !isVar {
+		return formal.packed && host(formal.index) == integerType && host(formal.element) == charType
+	}
+	arr, ok := actual.(*arrayType)
+	if !ok || arr.packed != formal.packed || host(arr.index) != host(formal.index) {
+		return false
+	}
+	if arr.schema == nil {
+		if low, high, bounded := ordinalBounds(formal.index); bounded && (arr.low < low || arr.high > high) {
+			return false
+		}
+	} else if !subsumes(formal.index, arr.index) {
+		return false
+	}
+	if element, ok := formal.element.(*arrayType); ok && element.schema != nil {
+		return conforms(element, arr.element, isVar)
+	}
+	return formal.element == arr.element || formal.element == invalidType || arr.element == invalidType
+}
+
+func subsumes(outer, t Type) bool {
+	low, high, bounded := ordinalBounds(outer)
+	if !bounded {
+		return true
+	}
+	tLow, tHigh, ok := ordinalBounds(t)
+	return ok && tLow >= low && tHigh <= high
+}
+
+func comparable(l, r Type) bool {
+	if l == invalidType || r == invalidType {
+		return true
+	}
+	lh, rh := host(l), host(r)
+	switch {
+	case isNumeric(lh) && isNumeric(rh):
+		return true
+	case isText(lh) && isText(rh):
+		return true
+	case lh == booleanType || rh == booleanType:
+		return lh == rh
+	}
+	if le, ok := lh.(*enumType); ok {
+		return le == rh
+	}
+	return false
+}
+
+func isFileComponentType(t Type) bool {
+	switch t := t.(type) {
+	case *fileType, *pointerType:
+		return false
+	case *arrayType:
+		return isFileComponentType(t.element)
+	case *recordType:
+		for _, f := range t.fields {
+			if !isFileComponentType(f.typ) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,575 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"pastel/ast"
+	"strings"
+)
+
+type builtin struct {
+	minArgs int
+	maxArgs int
+	fn      builtinFunc
+}
+
+type builtinFunc func(i *Interpreter, call *builtinCall) (Value, error)
+
+type builtinCall struct {
+	name  string
+	exprs []ast.Expr
+	args  []Value
+}
+
+var builtins = map[string]builtin{
+	"abs":    {1, 1, arithmetic(func(n int) (Value, error) { return &IntegerValue{Val: max(n, -n)}, nil }, math.Abs)},
+	"sqr":    {1, 1, arithmetic(func(n int) (Value, error) { return multiplyIntegers(n, n) }, func(x float64) float64 { return x * x })},
+	"sqrt":   {1, 1, transcendental(math.Sqrt, nonNegative)},
+	"sin":    {1, 1, transcendental(math.Sin, nil)},
+	"cos":    {1, 1, transcendental(math.Cos, nil)},
+	"arctan": {1, 1, transcendental(math.Atan, nil)},
+	"exp":    {1, 1, transcendental(math.Exp, nil)},
+	"ln":     {1, 1, transcendental(math.Log, positive)},
+	"trunc":  {1, 1, rounding(math.Trunc)},
+	"round":  {1, 1, rounding(math.Round)},
+	"ord":    {1, 1, builtinOrd},
+	"chr":    {1, 1, builtinChr},
+	"succ":   {1, 1, builtinStep(1, "successor")},
+	"pred":   {1, 1, builtinStep(-1, "predecessor")},
+	"odd":    {1, 1, builtinOdd},
+	"eof":    {0, 1, builtinEOF},
+	"eoln":   {0, 1, builtinEOF},
+}
+
+func (i *Interpreter) callBuiltin(name string, b builtin, exprs []ast.Expr) (Value, error) {
+	if len(exprs) < b.minArgs || len(exprs) > b.maxArgs {
+		expects := fmt.Sprintf("%d argument", b.maxArgs)
+		if b.minArgs < b.maxArgs {
+			expects = fmt.Sprintf("at most %d argument", b.maxArgs)
+		}
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Wrong number of arguments to '%s'", name),
+			Detail: fmt.Sprintf("'%s' expects %s but was given %d.", name, expects, len(exprs)),
+			Hint:   fmt.Sprintf("Call it as %s(x).", name),
+		}
+	}
+	call := &builtinCall{name: name, exprs: exprs}
+	for _, expr := range exprs {
+		arg, err := i.evalExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+	}
+	return b.fn(i, call)
+}
+
+func arithmetic(integerFn func(int) (Value, error), realFn func(float64) float64) builtinFunc {
+	return func(_ *Interpreter, call *builtinCall) (Value, error) {
+		switch arg := call.args[0].(type) {
+		case *IntegerValue:
+			return integerFn(arg.Val)
+		case *RealValue:
+			return &RealValue{Val: realFn(arg.Val)}, nil
+		}
+		return nil, argumentTypeError(call, "integer or real")
+	}
+}
+
+func transcendental(fn func(float64) float64, domain func(call *builtinCall, x float64) error) builtinFunc {
+	return func(_ *Interpreter, call *builtinCall) (Value, error) {
+		x, ok := realOf(call.args[0])
+		if !ok {
+			return nil, argumentTypeError(call, "integer or real")
+		}
+		if domain != nil {
+			if err := domain(call, x); err != nil {
+				return nil, err
+			}
+		}
+		result := fn(x)
+		if math.IsInf(result, 0) {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("%s(%s) is too large", call.name, call.args[0]),
+				Detail: "The result cannot be represented as a real.",
+				Hint:   "Scale the argument down before calling the function.",
+			}
+		}
+		return &RealValue{Val: result}, nil
+	}
+}
+
+func nonNegative(call *builtinCall, x float64) error {
+	if x >= 0 {
+		return nil
+	}
+	return &PascalError{
+		Msg:    fmt.Sprintf("Square root of negative number %s", call.args[0]),
+		Detail: "sqrt is only defined for arguments that are zero or positive.",
+		Hint:   "Check the sign first, or take the square root of abs(x).",
+	}
+}
+
+func positive(call *builtinCall, x float64) error {
+	if x > 0 {
+		return nil
+	}
+	return &PascalError{
+		Msg:    fmt.Sprintf("Logarithm of non-positive number %s", call.args[0]),
+		Detail: "ln is only defined for arguments greater than zero.",
+		Hint:   "Check that the argument is positive before calling ln.",
+	}
+}
+
+func rounding(fn func(float64) float64) builtinFunc {
+	return func(_ *Interpreter, call *builtinCall) (Value, error) {
+		x, ok := realOf(call.args[0])
+		if !ok {
+			return nil, argumentTypeError(call, "real")
+		}
+		result := fn(x)
+		if math.IsNaN(result) || result < math.MinInt64 || result >= math.MaxInt64 {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("%s(%s) is out of the integer range", call.name, call.args[0]),
+				Detail: "The result is too large to be represented as an integer.",
+				Hint:   "Keep the value within the integer range before converting it.",
+			}
+		}
+		return &IntegerValue{Val: int(result)}, nil
+	}
+}
+
+func realOf(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case *IntegerValue:
+		return float64(v.Val), true
+	case *RealValue:
+		return v.Val, true
+	default:
+		return 0, false
+	}
+}
+
+func builtinOrd(_ *Interpreter, call *builtinCall) (Value, error) {
+	n, ok := ordinalOf(call.args[0])
+	if !ok {
+		return nil, notOrdinalError(call.name, call.args[0])
+	}
+	return &IntegerValue{Val: n}, nil
+}
+
+func builtinChr(_ *Interpreter, call *builtinCall) (Value, error) {
+	n, ok := call.args[0].(*IntegerValue)
+	if !ok {
+		return nil, argumentTypeError(call, "integer")
+	}
+	if low, high, _ := ordinalBounds(charType); n.Val < low || n.Val > high {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("chr(%d) is out of range", n.Val),
+			Detail: fmt.Sprintf("There is no character with ordinal %d; character ordinals run from %d to %d.", n.Val, low, high),
+			Hint:   "Pass a value between 0 and 255, e.g. chr(ord('a') + 1).",
+		}
+	}
+	return &CharValue{Val: rune(n.Val)}, nil
+}
+
+func builtinOdd(_ *Interpreter, call *builtinCall) (Value, error) {
+	n, ok := call.args[0].(*IntegerValue)
+	if !ok {
+		return nil, argumentTypeError(call, "integer")
+	}
+	return &BooleanValue{Val: n.Val%2 != 0}, nil
+}
+
+func builtinStep(step int, relation string) builtinFunc {
+	return func(_ *Interpreter, call *builtinCall) (Value, error) {
+		arg := call.args[0]
+		t, ok := ordinalTypeOf(arg)
+		if !ok {
+			return nil, notOrdinalError(call.name, arg)
+		}
+		n, _ := ordinalOf(arg)
+		n += step
+		if low, high, bounded := ordinalBounds(t); (bounded && (n < low || n > high)) || n < -maxint {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("%s(%s) is out of range", call.name, arg),
+				Detail: fmt.Sprintf("%s has no %s in type %s.", arg, relation, t),
+				Hint:   "Check the value against the first or last value of its type before stepping.",
+			}
+		}
+		return valueFromOrdinal(t, n), nil
+	}
+}
+
+func builtinEOF(i *Interpreter, call *builtinCall) (Value, error) {
+	f, name := i.input, "input"
+	if len(call.args) > 0 {
+		file, ok := call.args[0].(*FileValue)
+		if !ok {
+			return nil, fileArgumentError(call.name)
+		}
+		f, name = file, variableName(call.exprs[0])
+	}
+	if call.name == "eof" {
+		return &BooleanValue{Val: f.eof()}, nil
+	}
+	if !f.File.Text {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("'eoln' requires a text file, got '%s'", name),
+			Detail: fmt.Sprintf("'%s' has type %s, which is not divided into lines.", name, f.File),
+			Hint:   "Use eof to test for the end of a file that is not a text file.",
+		}
+	}
+	if err := f.checkMode(fileReading, name); err != nil {
+		return nil, err
+	}
+	if f.eof() {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("eoln(%s) at end of file", name),
+			Detail: fmt.Sprintf("'%s' has no more lines, so there is no line whose end eoln could test.", name),
+			Hint:   fmt.Sprintf("Test eof(%s) before eoln(%s).", name, name),
+		}
+	}
+	return &BooleanValue{Val: f.eoln()}, nil
+}
+
+func argumentTypeError(call *builtinCall, expected string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Argument of '%s' must be %s", call.name, expected),
+		Detail: fmt.Sprintf("The argument has type %s.", call.args[0].Type()),
+		Hint:   fmt.Sprintf("Pass a value of type %s.", expected),
+	}
+}
+
+func (i *Interpreter) builtinProcedure(name string) (func(args []ast.Expr) error, bool) {
+	switch name {
+	case "new":
+		return i.builtinNew, true
+	case "dispose":
+		return i.builtinDispose, true
+	case "reset", "rewrite", "close":
+		return func(args []ast.Expr) error { return i.builtinOpen(name, args) }, true
+	case "assign":
+		return i.builtinAssign, true
+	case "read":
+		return func(args []ast.Expr) error { return i.read(args, false) }, true
+	case "readln":
+		return func(args []ast.Expr) error { return i.read(args, true) }, true
+	}
+	if i.dialect == ast.Turbo {
+		return i.turboProcedure(name)
+	}
+	return nil, false
+}
+
+func (i *Interpreter) builtinNew(args []ast.Expr) error {
+	if len(args) != 1 {
+		return pointerArgumentError("new")
+	}
+	loc, t, err := i.locate(args[0])
+	if err != nil && !errors.Is(err, errNotVariable) {
+		return err
+	}
+	ptr, ok := t.(*PointerType)
+	if err != nil || !ok {
+		return pointerArgumentError("new")
+	}
+	addr := i.heap.allocate(zeroValue(ptr.Target))
+	loc.store(&PointerValue{Pointer: ptr, Addr: addr})
+	return nil
+}
+
+func (i *Interpreter) builtinDispose(args []ast.Expr) error {
+	if len(args) != 1 {
+		return pointerArgumentError("dispose")
+	}
+	val, err := i.evalExpr(args[0])
+	if err != nil {
+		return err
+	}
+	ptr, ok := val.(*PointerValue)
+	if !ok {
+		return pointerArgumentError("dispose")
+	}
+	name := variableName(args[0])
+	if ptr.Addr == 0 {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Dispose of nil pointer '%s'", name),
+			Detail: fmt.Sprintf("'%s' is nil, so there is no variable to release.", name),
+			Hint:   "Only dispose pointers that were allocated with new.",
+		}
+	}
+	if !i.heap.live(ptr.Addr) {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Pointer '%s' was already disposed", name),
+			Detail: fmt.Sprintf("The variable '%s' points to has already been released.", name),
+			Hint:   "Dispose each variable exactly once, and set pointers to nil afterwards.",
+		}
+	}
+	i.heap.free(ptr.Addr)
+	return nil
+}
+
+func (i *Interpreter) builtinOpen(name string, args []ast.Expr) error {
+	if len(args) != 1 {
+		return fileArgumentError(name)
+	}
+	f, rest, err := i.fileArgument(args)
+	if err != nil {
+		return err
+	}
+	if f == nil || len(rest) != 0 {
+		return fileArgumentError(name)
+	}
+	switch name {
+	case "reset":
+		return i.reset(f, variableName(args[0]))
+	case "rewrite":
+		return i.rewrite(f, variableName(args[0]))
+	default:
+		if f == i.input || f == i.output {
+			return nil
+		}
+		return f.close(variableName(args[0]))
+	}
+}
+
+func (i *Interpreter) builtinAssign(args []ast.Expr) error {
+	f, rest, err := i.fileArgument(args)
+	if err != nil {
+		return err
+	}
+	if f == nil || len(rest) != 1 {
+		return &PascalError{
+			Msg:    "'assign' requires a file variable and a file name",
+			Detail: fmt.Sprintf("'assign' was given %d argument(s).", len(args)),
+			Hint:   "Call it as assign(f, 'data.txt').",
+		}
+	}
+	val, err := i.evalExpr(rest[0])
+	if err != nil {
+		return err
+	}
+	text, ok := textOf(val)
+	if !ok {
+		return &PascalError{
+			Msg:    "File name must be a string",
+			Detail: fmt.Sprintf("The file name has type %s.", val.Type()),
+			Hint:   "Pass the name of the external file as a string, e.g. assign(f, 'data.txt').",
+		}
+	}
+	if err := f.close(variableName(args[0])); err != nil {
+		return err
+	}
+	f.Name = strings.TrimRight(text, " ")
+	return nil
+}
+
+func (i *Interpreter) fileArgument(args []ast.Expr) (*FileValue, []ast.Expr, error) {
+	if len(args) == 0 {
+		return nil, args, nil
+	}
+	loc, t, err := i.locate(args[0])
+	if errors.Is(err, errNotVariable) {
+		return nil, args, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, isFile := t.(*FileType); !isFile {
+		return nil, args, nil
+	}
+	return loc.load().(*FileValue), args[1:], nil
+}
+
+func (i *Interpreter) read(args []ast.Expr, line bool) error {
+	procedure := "read"
+	if line {
+		procedure = "readln"
+	}
+	f, targets, err := i.fileArgument(args)
+	if err != nil {
+		return err
+	}
+	name := "input"
+	if f == nil {
+		f = i.input
+	} else {
+		name = variableName(args[0])
+	}
+	if err := f.checkMode(fileReading, name); err != nil {
+		return err
+	}
+	if line && !f.File.Text {
+		return textOnlyError(procedure, name, f)
+	}
+
+	for _, target := range targets {
+		loc, t, err := i.locate(target)
+		if errors.Is(err, errNotVariable) {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Argument of '%s' must be a variable", procedure),
+				Detail: fmt.Sprintf("'%s' stores the value it reads, so it cannot read into an expression.", procedure),
+				Hint:   "Pass the variables to read into.",
+			}
+		}
+		if err != nil {
+			return err
+		}
+		var val Value
+		if f.File.Text {
+			val, err = f.readText(t, name)
+		} else {
+			val, err = f.readComponent(name)
+		}
+		if err != nil {
+			return err
+		}
+		if val, err = conform(t, val); err != nil {
+			return err
+		}
+		loc.store(val)
+	}
+
+	if line {
+		f.skipLine()
+	}
+	return nil
+}
+
+func (i *Interpreter) write(args []ast.Expr, line bool) error {
+	procedure := "write"
+	if line {
+		procedure = "writeln"
+	}
+	f, values, err := i.fileArgument(args)
+	if err != nil {
+		return err
+	}
+	name := "output"
+	if f == nil {
+		f = i.output
+	} else {
+		name = variableName(args[0])
+	}
+	if err := f.checkMode(fileWriting, name); err != nil {
+		return err
+	}
+	if line && !f.File.Text {
+		return textOnlyError(procedure, name, f)
+	}
+
+	for _, arg := range values {
+		format, isFormatted := arg.(*ast.FormatExpr)
+		if isFormatted {
+			arg = format.Value
+		}
+		val, err := i.evalExpr(arg)
+		if err != nil {
+			return err
+		}
+		if !f.File.Text {
+			if isFormatted {
+				return at(format, &PascalError{
+					Msg:    fmt.Sprintf("Field widths can only be used with text files, not '%s'", name),
+					Detail: fmt.Sprintf("'%s' has type %s; its components are written without formatting.", name, f.File),
+					Hint:   "Remove the ':width' from the argument.",
+				})
+			}
+			if val, err = conformComponent(f.File.Element, val); err == nil {
+				err = encodeComponent(f.writer, f.File.Element, val)
+			}
+			if err != nil {
+				return writeError(name, err)
+			}
+			continue
+		}
+
+		width, decimals := 0, 0
+		if isFormatted {
+			if width, err = i.evalFieldWidth(format.Width, "Field width"); err != nil {
+				return err
+			}
+			if format.Decimals != nil {
+				if decimals, err = i.evalFieldWidth(format.Decimals, "Number of decimal places"); err != nil {
+					return err
+				}
+			}
+		}
+		text, err := formatValue(val, width, decimals, isFormatted && format.Decimals != nil)
+		if err != nil {
+			return at(arg, err)
+		}
+		if _, err := io.WriteString(f.writer, text); err != nil {
+			return writeError(name, err)
+		}
+	}
+
+	if line {
+		if _, err := io.WriteString(f.writer, "\n"); err != nil {
+			return writeError(name, err)
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) evalFieldWidth(expr ast.Expr, what string) (int, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := val.(*IntegerValue)
+	if !ok || n.Val < 0 {
+		return 0, at(expr, &PascalError{
+			Msg:    fmt.Sprintf("%s must be a non-negative integer", what),
+			Detail: fmt.Sprintf("Got %s of type %s.", val, val.Type()),
+			Hint:   "Write formatted values as x:width or x:width:decimals, e.g. writeln(x:8:2).",
+		})
+	}
+	return n.Val, nil
+}
+
+func writeError(name string, err error) error {
+	if _, isPascal := err.(*PascalError); isPascal {
+		return err
+	}
+	return &PascalError{
+		Msg:    fmt.Sprintf("Cannot write to file '%s'", name),
+		Detail: err.Error(),
+		Hint:   "Check that the device holding the file is writable and not full.",
+	}
+}
+
+func textOnlyError(procedure, name string, f *FileValue) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("'%s' requires a text file, got '%s'", procedure, name),
+		Detail: fmt.Sprintf("'%s' has type %s, which is not divided into lines.", name, f.File),
+		Hint:   fmt.Sprintf("Use %s without 'ln' for files that are not text files.", procedure[:len(procedure)-2]),
+	}
+}
+
+func fileArgumentError(name string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("'%s' requires one file variable", name),
+		Detail: fmt.Sprintf("'%s' takes exactly one argument of a file type.", name),
+		Hint:   fmt.Sprintf("Declare `var f: text;` and call %s(f).", name),
+	}
+}
+
+func pointerArgumentError(name string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("'%s' requires one pointer variable", name),
+		Detail: fmt.Sprintf("'%s' takes exactly one argument of a pointer type.", name),
+		Hint:   fmt.Sprintf("Declare `var p: ^T;` and call %s(p).", name),
+	}
+}
+
+func notOrdinalError(name string, arg Value) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Argument of '%s' must be ordinal", name),
+		Detail: fmt.Sprintf("The argument has type %s.", arg.Type()),
+		Hint:   "Pass an integer, char, boolean or enumerated value.",
+	}
+}
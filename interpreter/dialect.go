@@ -0,0 +1,344 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"pastel/ast"
+)
+
+// ExitCode returns the exit code the program passed to halt, or 0 if it ran to
+// its end.
+func (i *Interpreter) ExitCode() int {
+	return i.exitCode
+}
+
+type loopSignal struct {
+	name string
+}
+
+func (s *loopSignal) Error() string {
+	return fmt.Sprintf("%s outside a loop", s.name)
+}
+
+type exitSignal struct{}
+
+func (s *exitSignal) Error() string { return "exit outside a routine" }
+
+type haltSignal struct {
+	code int
+}
+
+func (s *haltSignal) Error() string {
+	return fmt.Sprintf("program halted with exit code %d", s.code)
+}
+
+func loopControl(err error) (bool, error) {
+	var signal *loopSignal
+	if errors.As(err, &signal) {
+		return signal.name == "break", nil
+	}
+	return err != nil, err
+}
+
+func (i *Interpreter) exited(err error) bool {
+	var halt *haltSignal
+	if errors.As(err, &halt) {
+		i.exitCode = halt.code
+		return true
+	}
+	var exit *exitSignal
+	return errors.As(err, &exit)
+}
+
+func (i *Interpreter) turboProcedure(name string) (func(args []ast.Expr) error, bool) {
+	switch name {
+	case "inc":
+		return func(args []ast.Expr) error { return i.builtinIncDec(name, 1, args) }, true
+	case "dec":
+		return func(args []ast.Expr) error { return i.builtinIncDec(name, -1, args) }, true
+	case "halt":
+		return i.builtinHalt, true
+	case "exit":
+		return func(args []ast.Expr) error {
+			if len(args) > 0 {
+				return argumentCountError(name, "no arguments", len(args))
+			}
+			return &exitSignal{}
+		}, true
+	case "break", "continue":
+		return func(args []ast.Expr) error {
+			if len(args) > 0 {
+				return argumentCountError(name, "no arguments", len(args))
+			}
+			return &loopSignal{name: name}
+		}, true
+	}
+	return nil, false
+}
+
+func argumentCountError(name, expects string, given int) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Wrong number of arguments to '%s'", name),
+		Detail: fmt.Sprintf("'%s' expects %s but was given %d.", name, expects, given),
+		Hint:   "Check the call against the parameters of the procedure.",
+	}
+}
+
+func (i *Interpreter) builtinIncDec(name string, sign int, args []ast.Expr) error {
+	if len(args) < 1 || len(args) > 2 {
+		return argumentCountError(name, "1 or 2 arguments", len(args))
+	}
+	loc, t, err := i.locate(args[0])
+	if errors.Is(err, errNotVariable) || (err == nil && !isOrdinalType(t)) {
+		return &PascalError{
+			Msg:    fmt.Sprintf("'%s' requires an ordinal variable", name),
+			Detail: fmt.Sprintf("The first argument of '%s' must be a variable of an ordinal type.", name),
+			Hint:   fmt.Sprintf("Pass %s an integer, char, boolean or enumerated variable.", name),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	step := 1
+	if len(args) == 2 {
+		val, err := i.evalExpr(args[1])
+		if err != nil {
+			return err
+		}
+		n, ok := val.(*IntegerValue)
+		if !ok {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Step of '%s' must be integer", name),
+				Detail: fmt.Sprintf("The second argument has type %s.", val.Type()),
+				Hint:   fmt.Sprintf("Pass the number of steps as an integer, e.g. %s(x, 2).", name),
+			}
+		}
+		step = n.Val
+	}
+
+	current := loc.load()
+	if _, undefined := current.(*UndefinedValue); undefined {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Variable '%s' is undefined", variableName(args[0])),
+			Detail: "The variable has no defined value at this point.",
+			Hint:   fmt.Sprintf("Assign the variable a value before passing it to %s.", name),
+		}
+	}
+	old, _ := ordinalOf(current)
+	n, overflow := old+sign*step, false
+	if sign*step > 0 {
+		overflow = n < old
+	} else {
+		overflow = n > old || n < -maxint
+	}
+	if low, high, bounded := ordinalBounds(t); overflow || (bounded && (n < low || n > high)) {
+		return &PascalError{
+			Msg:    fmt.Sprintf("%s(%s) is out of range", name, variableName(args[0])),
+			Detail: fmt.Sprintf("Stepping %s by %d leaves the type %s.", current, step, t),
+			Hint:   "Check the value against the first or last value of its type before stepping.",
+		}
+	}
+	loc.store(valueFromOrdinal(t, n))
+	return nil
+}
+
+func (i *Interpreter) builtinHalt(args []ast.Expr) error {
+	if len(args) > 1 {
+		return argumentCountError("halt", "at most 1 argument", len(args))
+	}
+	code := 0
+	if len(args) == 1 {
+		val, err := i.evalExpr(args[0])
+		if err != nil {
+			return err
+		}
+		n, ok := val.(*IntegerValue)
+		if !ok {
+			return &PascalError{
+				Msg:    "Argument of 'halt' must be integer",
+				Detail: fmt.Sprintf("The argument has type %s.", val.Type()),
+				Hint:   "Pass the exit code as an integer, e.g. halt(1).",
+			}
+		}
+		code = n.Val
+	}
+	return &haltSignal{code: code}
+}
+
+func (i *Interpreter) stringIndex(s *StringValue, e *ast.IndexExpr) (int, error) {
+	val, err := i.evalExpr(e.Index)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := val.(*IntegerValue)
+	if !ok {
+		return 0, &PascalError{
+			Msg:    "Type mismatch in string index",
+			Detail: fmt.Sprintf("'%s' is a string, indexed by integer, but the index has type %s.", variableName(e.Array), val.Type()),
+			Hint:   "Index a string with the position of a character, starting at 1.",
+		}
+	}
+	if n.Val < 0 || n.Val > len(s.Val) {
+		return 0, &PascalError{
+			Msg:    "String index out of range",
+			Detail: fmt.Sprintf("Index %d is outside the length %d of '%s'.", n.Val, len(s.Val), variableName(e.Array)),
+			Hint:   fmt.Sprintf("Check the index against length byte %s[0] before using it.", variableName(e.Array)),
+		}
+	}
+	return n.Val, nil
+}
+
+func stringElement(s *StringValue, index int) Value {
+	if index == 0 {
+		return &CharValue{Val: rune(len(s.Val))}
+	}
+	return &CharValue{Val: rune(s.Val[index-1])}
+}
+
+type stringLocation struct {
+	str   location
+	index int
+}
+
+func (l stringLocation) load() Value {
+	return stringElement(l.str.load().(*StringValue), l.index)
+}
+
+func (l stringLocation) store(value Value) {
+	text := []byte(l.str.load().(*StringValue).Val)
+	ch := byte(value.(*CharValue).Val)
+	if l.index == 0 {
+		n := int(ch)
+		for len(text) < n {
+			text = append(text, 0)
+		}
+		text = text[:n]
+	} else {
+		text[l.index-1] = ch
+	}
+	l.str.store(&StringValue{Val: string(text)})
+}
+
+type lengthByte struct {
+	max int
+}
+
+func (t *lengthByte) String() string { return "char" }
+
+func lengthByteOf(t Type) *lengthByte {
+	if s, ok := t.(*ShortStringType); ok {
+		return &lengthByte{max: s.Max}
+	}
+	return &lengthByte{max: 255}
+}
+
+func conformLength(t *lengthByte, val Value) (Value, error) {
+	ch, ok := val.(*CharValue)
+	if !ok {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in assignment",
+			Detail: fmt.Sprintf("The length byte of a string is a char, but the value has type %s.", val.Type()),
+			Hint:   "Set the length with a char, e.g. s[0] := chr(3).",
+		}
+	}
+	if int(ch.Val) > t.max {
+		return nil, &PascalError{
+			Msg:    "String length out of range",
+			Detail: fmt.Sprintf("Length %d is more than the maximum length %d of the string.", ch.Val, t.max),
+			Hint:   fmt.Sprintf("Set the length byte to at most chr(%d).", t.max),
+		}
+	}
+	return ch, nil
+}
+
+type staticVariable struct {
+	loc location
+	typ Type
+}
+
+func (i *Interpreter) declareTypedConstant(d *ast.TypedConstDecl) error {
+	static, ok := i.statics[d]
+	if !ok {
+		t, err := i.resolveType(d.Type)
+		if err != nil {
+			return err
+		}
+		val, err := i.typedConstantValue(t, d.Value)
+		if err != nil {
+			return err
+		}
+		env := NewEnvironment()
+		env.name = i.env.Name()
+		env.DefineVariable(d.Name, t, val)
+		static = staticVariable{loc: variableLocation{env: env, name: d.Name}, typ: t}
+		i.statics[d] = static
+	}
+	if i.env.has(d.Name) {
+		return duplicateDeclarationError(d.Name, i.env)
+	}
+	i.env.bindAlias(d.Name, static.typ, static.loc)
+	return nil
+}
+
+func (i *Interpreter) typedConstantValue(t Type, expr ast.Expr) (_ Value, err error) {
+	defer func() { err = at(expr, err) }()
+
+	switch v := expr.(type) {
+	case *ast.ArrayConstant:
+		arr, ok := t.(*ArrayType)
+		if !ok {
+			return nil, typedConstantError(t, "an array")
+		}
+		val := zeroValue(arr).(*ArrayValue)
+		if len(v.Elements) != len(val.Elems) {
+			return nil, &PascalError{
+				Msg:    "Wrong number of elements in array constant",
+				Detail: fmt.Sprintf("The type %s has %d elements but the constant gives %d.", t, len(val.Elems), len(v.Elements)),
+				Hint:   "Give one value for every element of the array.",
+			}
+		}
+		for k, el := range v.Elements {
+			if val.Elems[k], err = i.typedConstantValue(arr.Element, el); err != nil {
+				return nil, err
+			}
+		}
+		return val, nil
+
+	case *ast.RecordConstant:
+		record, ok := t.(*RecordType)
+		if !ok {
+			return nil, typedConstantError(t, "a record")
+		}
+		val := zeroValue(record).(*RecordValue)
+		for _, fc := range v.Fields {
+			field, ok := record.Field(fc.Name)
+			if !ok {
+				return nil, at(fc, &PascalError{
+					Msg:    fmt.Sprintf("Unknown field '%s'", fc.Name),
+					Detail: fmt.Sprintf("Type %s has no field named '%s'.", t, fc.Name),
+					Hint:   "Check the spelling of the field name against the record declaration.",
+				})
+			}
+			fieldVal, err := i.typedConstantValue(field.Type, fc.Value)
+			if err != nil {
+				return nil, err
+			}
+			val.set(field, fieldVal)
+		}
+		return val, nil
+	}
+
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return conform(t, val)
+}
+
+func typedConstantError(t Type, kind string) *PascalError {
+	return &PascalError{
+		Msg:    "Type mismatch in typed constant",
+		Detail: fmt.Sprintf("The value is %s constant but the type of the constant is %s.", kind, t),
+		Hint:   "Give arrays as (1, 2, 3), records as (x: 1; y: 2) and other values as constant expressions.",
+	}
+}
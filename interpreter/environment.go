@@ -1,28 +1,296 @@
 package interpreter
 
-// Environment stores variable bindings for the interpreter.
+// Environment stores the bindings of one lexical scope of the interpreter.
+// Each scope holds a static link to the scope its block was declared in, so a
+// procedure activation sees the variables of its textually enclosing blocks
+// rather than those of its caller. Inner declarations shadow outer ones.
 type Environment struct {
-	store map[string]Value
+	name      string
+	store     map[string]Value
+	aliases   map[string]location
+	constants map[string]bool
+	declared  map[string]Type
+	types     map[string]Type
+	labels    map[int]bool
+	outer     *Environment
+	routine   *RoutineValue
+	result    Value
+	with      bool
 }
 
 // NewEnvironment creates a new empty environment.
 func NewEnvironment() *Environment {
-	return &Environment{store: make(map[string]Value)}
+	return &Environment{
+		store:     make(map[string]Value),
+		aliases:   make(map[string]location),
+		constants: make(map[string]bool),
+		declared:  make(map[string]Type),
+		types:     make(map[string]Type),
+		labels:    make(map[int]bool),
+	}
 }
 
-// Set binds a value to a variable name.
+// NewEnclosedEnvironment creates a new empty scope whose static link is outer.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Name returns the name of the program or routine that owns the scope.
+func (e *Environment) Name() string {
+	return e.name
+}
+
+// Outer returns the static link: the scope of the block this one is nested in.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Depth returns the static nesting level of the scope; the outermost scope is 0.
+func (e *Environment) Depth() int {
+	depth := 0
+	for scope := e.outer; scope != nil; scope = scope.outer {
+		depth++
+	}
+	return depth
+}
+
+// Define declares name in this scope, shadowing any outer declaration.
+// It reports false if the scope already declares the name.
+func (e *Environment) Define(name string, value Value) bool {
+	if e.has(name) {
+		return false
+	}
+	e.store[name] = value
+	return true
+}
+
+// DefineVariable declares name as a variable of type t in this scope.
+// It reports false if the scope already declares the name.
+func (e *Environment) DefineVariable(name string, t Type, value Value) bool {
+	if !e.Define(name, value) {
+		return false
+	}
+	e.declared[name] = t
+	return true
+}
+
+// DefineType declares name as a type identifier denoting t in this scope.
+// It reports false if the scope already declares the name.
+func (e *Environment) DefineType(name string, t Type) bool {
+	if e.has(name) {
+		return false
+	}
+	e.types[name] = t
+	return true
+}
+
+// LookupType returns the type denoted by name, if the innermost declaration of name is a type.
+func (e *Environment) LookupType(name string) (Type, bool) {
+	scope := e.lookup(name)
+	if scope == nil {
+		return nil, false
+	}
+	t, ok := scope.types[name]
+	return t, ok
+}
+
+// TypeOf returns the declared type of the variable visible under name.
+func (e *Environment) TypeOf(name string) (Type, bool) {
+	scope := e.lookup(name)
+	if scope == nil {
+		return nil, false
+	}
+	t, ok := scope.declared[name]
+	return t, ok
+}
+
+// DefineConstant declares name as a constant in this scope.
+// It reports false if the scope already declares the name.
+func (e *Environment) DefineConstant(name string, value Value) bool {
+	if !e.Define(name, value) {
+		return false
+	}
+	e.constants[name] = true
+	return true
+}
+
+// IsConstant reports whether name resolves to a constant.
+func (e *Environment) IsConstant(name string) bool {
+	scope := e.lookup(name)
+	return scope != nil && scope.constants[name]
+}
+
+// Resolve returns the innermost scope that declares name.
+func (e *Environment) Resolve(name string) (*Environment, bool) {
+	scope := e.lookup(name)
+	return scope, scope != nil
+}
+
+// DefineLabel declares label in this scope.
+func (e *Environment) DefineLabel(label int) {
+	e.labels[label] = true
+}
+
+// Set binds a value to a variable name in this scope.
 func (e *Environment) Set(name string, value Value) {
+	delete(e.aliases, name)
 	e.store[name] = value
 }
 
-// Get retrieves the value bound to a variable name.
+// Get retrieves the value bound to a variable name, searching enclosing scopes.
 func (e *Environment) Get(name string) (Value, bool) {
-	val, ok := e.store[name]
-	return val, ok
+	scope := e.lookup(name)
+	if scope == nil {
+		return nil, false
+	}
+	if _, isType := scope.types[name]; isType {
+		return nil, false
+	}
+	return scope.load(name), true
 }
 
-// Exists checks if a variable is bound in the environment.
+// Exists checks if a variable is bound in the environment or an enclosing scope.
 func (e *Environment) Exists(name string) bool {
-	_, ok := e.store[name]
+	return e.lookup(name) != nil
+}
+
+// Assign updates the variable visible under name, writing through var-parameter
+// aliases. It reports false if no enclosing scope declares the name.
+func (e *Environment) Assign(name string, value Value) bool {
+	scope := e.lookup(name)
+	if scope == nil {
+		return false
+	}
+	if loc, ok := scope.aliases[name]; ok {
+		loc.store(value)
+		return true
+	}
+	scope.store[name] = value
+	return true
+}
+
+func (e *Environment) bindAlias(name string, t Type, loc location) {
+	delete(e.store, name)
+	e.aliases[name] = loc
+	e.declared[name] = t
+}
+
+func (e *Environment) block() *Environment {
+	scope := e
+	for scope.with {
+		scope = scope.outer
+	}
+	return scope
+}
+
+func (e *Environment) labelScope(label int) *Environment {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.labels[label] {
+			return scope
+		}
+	}
+	return nil
+}
+
+func (e *Environment) locationOf(name string) (location, bool) {
+	scope := e.lookup(name)
+	if scope == nil {
+		return nil, false
+	}
+	loc, ok := scope.aliases[name]
+	return loc, ok
+}
+
+func (e *Environment) lookup(name string) *Environment {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.has(name) {
+			return scope
+		}
+	}
+	return nil
+}
+
+func (e *Environment) has(name string) bool {
+	if _, ok := e.store[name]; ok {
+		return true
+	}
+	if _, ok := e.types[name]; ok {
+		return true
+	}
+	_, ok := e.aliases[name]
 	return ok
 }
+
+func (e *Environment) load(name string) Value {
+	if loc, ok := e.aliases[name]; ok {
+		return loc.load()
+	}
+	return e.store[name]
+}
+
+func (e *Environment) activationOf(routine *RoutineValue) *Environment {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.routine == routine {
+			return scope
+		}
+	}
+	return nil
+}
+
+type location interface {
+	load() Value
+	store(Value)
+}
+
+type variableLocation struct {
+	env  *Environment
+	name string
+}
+
+func (l variableLocation) load() Value {
+	return l.env.load(l.name)
+}
+
+func (l variableLocation) store(value Value) {
+	l.env.Assign(l.name, value)
+}
+
+type elementLocation struct {
+	array location
+	index int
+}
+
+func (l elementLocation) load() Value {
+	return l.array.load().(*ArrayValue).Elems[l.index]
+}
+
+func (l elementLocation) store(value Value) {
+	l.array.load().(*ArrayValue).Elems[l.index] = value
+}
+
+type fieldLocation struct {
+	record location
+	field  *Field
+}
+
+func (l fieldLocation) load() Value {
+	return l.record.load().(*RecordValue).Fields[l.field.Name]
+}
+
+func (l fieldLocation) store(value Value) {
+	l.record.load().(*RecordValue).set(l.field, value)
+}
+
+func checkActive(loc location) error {
+	switch l := loc.(type) {
+	case fieldLocation:
+		return l.record.load().(*RecordValue).checkActive(l.field)
+	case variableLocation:
+		if alias, ok := l.env.aliases[l.name]; ok {
+			return checkActive(alias)
+		}
+	}
+	return nil
+}
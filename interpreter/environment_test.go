@@ -0,0 +1,108 @@
+package interpreter
+
+import "testing"
+
+func TestEnvironment_InnerScopeShadowsOuter(t *testing.T) {
+	global := NewEnvironment()
+	global.Define("x", &IntegerValue{Val: 1})
+
+	local := NewEnclosedEnvironment(global)
+	local.Define("x", &IntegerValue{Val: 2})
+
+	val, _ := local.Get("x")
+	if val.(*IntegerValue).Val != 2 {
+		t.Fatalf("expected inner x to shadow outer x, got %s", val)
+	}
+
+	local.Assign("x", &IntegerValue{Val: 3})
+	outer, _ := global.Get("x")
+	if outer.(*IntegerValue).Val != 1 {
+		t.Fatalf("assigning inner x must not change outer x, got %s", outer)
+	}
+}
+
+func TestEnvironment_AssignUpdatesDeclaringScope(t *testing.T) {
+	global := NewEnvironment()
+	global.Define("count", &IntegerValue{Val: 0})
+	local := NewEnclosedEnvironment(global)
+
+	if !local.Assign("count", &IntegerValue{Val: 5}) {
+		t.Fatalf("expected assignment through the static link to succeed")
+	}
+	if local.has("count") {
+		t.Fatalf("assignment must not create a local binding")
+	}
+
+	val, _ := global.Get("count")
+	if val.(*IntegerValue).Val != 5 {
+		t.Fatalf("expected global count to be 5, got %s", val)
+	}
+
+	if local.Assign("missing", &IntegerValue{Val: 1}) {
+		t.Fatalf("expected assignment to an undeclared name to fail")
+	}
+}
+
+func TestEnvironment_ResolveReportsDeclaringScope(t *testing.T) {
+	global := NewEnvironment()
+	global.name = "main"
+	global.Define("g", &IntegerValue{})
+
+	outer := NewEnclosedEnvironment(global)
+	outer.name = "outer"
+	outer.Define("o", &IntegerValue{})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.name = "inner"
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"g", "main"},
+		{"o", "outer"},
+	}
+	for _, tt := range tests {
+		scope, ok := inner.Resolve(tt.name)
+		if !ok {
+			t.Fatalf("expected %q to resolve", tt.name)
+		}
+		if scope.Name() != tt.expected {
+			t.Fatalf("expected %q to resolve in %q, got %q", tt.name, tt.expected, scope.Name())
+		}
+	}
+
+	if _, ok := inner.Resolve("nope"); ok {
+		t.Fatalf("expected undeclared name not to resolve")
+	}
+	if inner.Depth() != 2 || inner.Outer() != outer {
+		t.Fatalf("expected inner scope at depth 2 linked to outer, got depth %d", inner.Depth())
+	}
+}
+
+func TestEnvironment_DefineRejectsRedeclaration(t *testing.T) {
+	env := NewEnvironment()
+	if !env.Define("x", &IntegerValue{}) {
+		t.Fatalf("expected first declaration to succeed")
+	}
+	if env.Define("x", &RealValue{}) {
+		t.Fatalf("expected redeclaration in the same scope to fail")
+	}
+	if !NewEnclosedEnvironment(env).Define("x", &RealValue{}) {
+		t.Fatalf("expected redeclaration in an inner scope to succeed")
+	}
+}
+
+func TestEnvironment_AliasWritesThroughToTarget(t *testing.T) {
+	global := NewEnvironment()
+	global.Define("x", &IntegerValue{Val: 1})
+
+	frame := NewEnclosedEnvironment(global)
+	frame.bindAlias("a", integerType, variableLocation{env: global, name: "x"})
+	frame.Assign("a", &IntegerValue{Val: 42})
+
+	val, _ := global.Get("x")
+	if val.(*IntegerValue).Val != 42 {
+		t.Fatalf("expected write through alias to update x, got %s", val)
+	}
+}
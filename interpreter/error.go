@@ -1,15 +1,28 @@
 package interpreter
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"pastel/ast"
+)
 
+// PascalError is an error detected while running a program. Span locates the
+// source of the node that caused it; it is zero for errors that belong to no
+// single node, such as failing to close a file when the program ends.
 type PascalError struct {
 	Msg    string
 	Detail string
 	Hint   string
+	Span   ast.Span
 }
 
 func (e *PascalError) Error() string {
-	msg := fmt.Sprintf("\n[Pascal Error] %s", e.Msg)
+	var msg string
+	if e.Span.Start.Line > 0 {
+		msg = fmt.Sprintf("\n[Pascal Error] at line %d, column %d: %s", e.Span.Start.Line, e.Span.Start.Column, e.Msg)
+	} else {
+		msg = fmt.Sprintf("\n[Pascal Error] %s", e.Msg)
+	}
 	if e.Detail != "" {
 		msg += fmt.Sprintf("\n  → %s", e.Detail)
 	}
@@ -18,3 +31,11 @@ func (e *PascalError) Error() string {
 	}
 	return msg
 }
+
+func at(node interface{ SourceSpan() ast.Span }, err error) error {
+	var pe *PascalError
+	if err != nil && node != nil && errors.As(err, &pe) && pe.Span.Start.Line == 0 {
+		pe.Span = node.SourceSpan()
+	}
+	return err
+}
@@ -0,0 +1,443 @@
+package interpreter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type fileMode int
+
+const (
+	fileClosed fileMode = iota
+	fileReading
+	fileWriting
+)
+
+func (i *Interpreter) reset(f *FileValue, name string) error {
+	if f == i.input {
+		return nil
+	}
+	if err := f.close(name); err != nil {
+		return err
+	}
+	if f.Name == "" {
+		if f.buffer == nil {
+			return &PascalError{
+				Msg:    fmt.Sprintf("File '%s' is undefined", name),
+				Detail: fmt.Sprintf("reset(%s) requires a file with contents, but '%s' was never written.", name, name),
+				Hint:   fmt.Sprintf("Call rewrite(%s) and write the file before resetting it, or assign it an external file.", name),
+			}
+		}
+		f.reader, f.line, f.mode = bufio.NewReader(bytes.NewReader(f.buffer.Bytes())), 1, fileReading
+		return nil
+	}
+	r, err := i.files.Open(f.Name)
+	if err != nil {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot open file '%s' for reading", f.Name),
+			Detail: fmt.Sprintf("reset(%s) failed: %v.", name, err),
+			Hint:   "Check that the file exists and is readable.",
+		}
+	}
+	f.reader, f.closer, f.line, f.mode = bufio.NewReader(r), r, 1, fileReading
+	i.open = append(i.open, f)
+	return nil
+}
+
+func (i *Interpreter) rewrite(f *FileValue, name string) error {
+	if f == i.output {
+		return nil
+	}
+	if err := f.close(name); err != nil {
+		return err
+	}
+	if f.Name == "" {
+		f.buffer = new(bytes.Buffer)
+		f.writer, f.mode = f.buffer, fileWriting
+		return nil
+	}
+	w, err := i.files.Create(f.Name)
+	if err != nil {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot open file '%s' for writing", f.Name),
+			Detail: fmt.Sprintf("rewrite(%s) failed: %v.", name, err),
+			Hint:   "Check that the file can be created in its directory.",
+		}
+	}
+	f.writer, f.closer, f.mode = w, w, fileWriting
+	i.open = append(i.open, f)
+	return nil
+}
+
+func (i *Interpreter) closeFiles() error {
+	var first error
+	for _, f := range i.open {
+		if err := f.close(f.Name); err != nil && first == nil {
+			first = err
+		}
+	}
+	i.open = nil
+	return first
+}
+
+func (f *FileValue) close(name string) error {
+	closer := f.closer
+	f.mode, f.reader, f.writer, f.closer, f.lineStarted = fileClosed, nil, nil, nil, false
+	if closer == nil {
+		return nil
+	}
+	if err := closer.Close(); err != nil {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot close file '%s'", f.Name),
+			Detail: fmt.Sprintf("Closing %s failed: %v.", name, err),
+			Hint:   "Check that the device holding the file is writable and not full.",
+		}
+	}
+	return nil
+}
+
+func (f *FileValue) checkMode(mode fileMode, name string) error {
+	if f.mode == mode {
+		return nil
+	}
+	if mode == fileReading {
+		return &PascalError{
+			Msg:    fmt.Sprintf("File '%s' is not open for reading", name),
+			Detail: "Reading requires the file to be in inspection mode.",
+			Hint:   fmt.Sprintf("Call reset(%s) before reading from it.", name),
+		}
+	}
+	return &PascalError{
+		Msg:    fmt.Sprintf("File '%s' is not open for writing", name),
+		Detail: "Writing requires the file to be in generation mode.",
+		Hint:   fmt.Sprintf("Call rewrite(%s) before writing to it.", name),
+	}
+}
+
+func (f *FileValue) eof() bool {
+	if f.mode != fileReading {
+		return true
+	}
+	return f.streamEnded() && !f.lineStarted
+}
+
+func (f *FileValue) streamEnded() bool {
+	_, err := f.reader.Peek(1)
+	return err != nil
+}
+
+func (f *FileValue) eoln() bool {
+	_, ok := f.lineEnd()
+	return ok
+}
+
+func (f *FileValue) lineEnd() (int, bool) {
+	if b, _ := f.reader.Peek(1); len(b) == 1 && b[0] == '\n' {
+		return 1, true
+	}
+	if b, _ := f.reader.Peek(2); len(b) == 2 && b[0] == '\r' && b[1] == '\n' {
+		return 2, true
+	}
+	return 0, f.lineStarted && f.streamEnded()
+}
+
+func (f *FileValue) endLine(n int) {
+	_, _ = f.reader.Discard(n)
+	f.line++
+	f.lineStarted = false
+}
+
+func (f *FileValue) readByte() byte {
+	b, _ := f.reader.ReadByte()
+	f.lineStarted = true
+	return b
+}
+
+func (f *FileValue) skipLine() {
+	for !f.eof() {
+		if n, ok := f.lineEnd(); ok {
+			f.endLine(n)
+			return
+		}
+		f.readByte()
+	}
+}
+
+func (f *FileValue) skipSpace() {
+	for !f.eof() {
+		if n, ok := f.lineEnd(); ok {
+			f.endLine(n)
+			continue
+		}
+		b, _ := f.reader.Peek(1)
+		if b[0] != ' ' && b[0] != '\t' {
+			return
+		}
+		f.readByte()
+	}
+}
+
+func (f *FileValue) scan(accept func(b byte) bool) string {
+	var text strings.Builder
+	for !f.eoln() {
+		b, err := f.reader.Peek(1)
+		if err != nil || !accept(b[0]) {
+			break
+		}
+		text.WriteByte(f.readByte())
+	}
+	return text.String()
+}
+
+func isDigit(b byte) bool { return '0' <= b && b <= '9' }
+func isSign(b byte) bool  { return b == '+' || b == '-' }
+
+func (f *FileValue) scanDigits() string { return f.scan(isDigit) }
+
+func (f *FileValue) scanSign() string {
+	if b, err := f.reader.Peek(1); err == nil && isSign(b[0]) {
+		return string(f.readByte())
+	}
+	return ""
+}
+
+func (f *FileValue) readText(t Type, name string) (Value, error) {
+	if arr, ok := t.(*ArrayType); ok && arr.IsString() {
+		line := f.scan(func(byte) bool { return true })
+		if len(line) > arr.High {
+			line = line[:arr.High]
+		}
+		return &StringValue{Val: line + strings.Repeat(" ", arr.High-len(line))}, nil
+	}
+
+	switch valueTypeOf(t) {
+	case CharType:
+		if f.eof() {
+			return nil, readPastEndError(name)
+		}
+		if n, ok := f.lineEnd(); ok {
+			f.endLine(n)
+			return &CharValue{Val: ' '}, nil
+		}
+		return &CharValue{Val: rune(f.readByte())}, nil
+
+	case StringType:
+		return &StringValue{Val: f.scan(func(byte) bool { return true })}, nil
+
+	case IntegerType:
+		f.skipSpace()
+		if f.eof() {
+			return nil, readPastEndError(name)
+		}
+		text := f.scanSign() + f.scanDigits()
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, f.conversionError(text, t, name)
+		}
+		return &IntegerValue{Val: n}, nil
+
+	case RealType:
+		f.skipSpace()
+		if f.eof() {
+			return nil, readPastEndError(name)
+		}
+		text := f.scanSign() + f.scanDigits()
+		if b, err := f.reader.Peek(2); err == nil && b[0] == '.' && isDigit(b[1]) {
+			text += string(f.readByte()) + f.scanDigits()
+		}
+		if b, err := f.reader.Peek(1); err == nil && (b[0] == 'e' || b[0] == 'E') {
+			f.readByte()
+			text += "e" + f.scanSign() + f.scanDigits()
+		}
+		x, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, f.conversionError(text, t, name)
+		}
+		return &RealValue{Val: x}, nil
+
+	default:
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Cannot read a value of type %s from a text file", t),
+			Detail: "Only integers, reals, chars and strings can be read from text.",
+			Hint:   "Read the value as an integer or string and convert it yourself.",
+		}
+	}
+}
+
+func (f *FileValue) conversionError(text string, t Type, name string) *PascalError {
+	line := f.line
+	text += f.scan(func(b byte) bool { return b != ' ' && b != '\t' })
+	if text == "" {
+		text = "end of line"
+	}
+	return &PascalError{
+		Msg:    fmt.Sprintf("Invalid %s input '%s'", valueTypeOf(t), text),
+		Detail: fmt.Sprintf("'%s' on line %d of '%s' cannot be converted to %s.", text, line, name, t),
+		Hint:   fmt.Sprintf("Enter %s.", inputExample(t)),
+	}
+}
+
+func inputExample(t Type) string {
+	if valueTypeOf(t) == RealType {
+		return "a real number such as 42, -1.5 or 2.5e3"
+	}
+	return "a whole number such as 42 or -7"
+}
+
+func readPastEndError(name string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Read past end of file '%s'", name),
+		Detail: "There are no more values to read.",
+		Hint:   fmt.Sprintf("Test eof(%s) before reading.", name),
+	}
+}
+
+func (f *FileValue) readComponent(name string) (Value, error) {
+	val, err := decodeComponent(f.reader, f.File.Element)
+	if errors.Is(err, io.EOF) {
+		return nil, readPastEndError(name)
+	}
+	if err != nil {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("File '%s' is truncated", name),
+			Detail: fmt.Sprintf("The file ended in the middle of a component of type %s.", f.File.Element),
+			Hint:   "Read the file with the same component type that it was written with.",
+		}
+	}
+	return val, nil
+}
+
+func conformComponent(t Type, val Value) (Value, error) {
+	val, err := conform(t, val)
+	if err != nil {
+		return nil, err
+	}
+	if kind := valueTypeOf(t); kind != "" && val.Type() != kind {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in file component",
+			Detail: fmt.Sprintf("Cannot write a value of type %s to a file of %s.", val.Type(), t),
+			Hint:   "Write values of the file's component type.",
+		}
+	}
+	return val, nil
+}
+
+func encodeComponent(w io.Writer, t Type, v Value) error {
+	le := binary.LittleEndian
+	switch t := t.(type) {
+	case *ArrayType:
+		for _, elem := range v.(*ArrayValue).Elems {
+			if err := encodeComponent(w, t.Element, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *RecordType:
+		rec := v.(*RecordValue)
+		for _, field := range t.Fields {
+			if err := encodeComponent(w, field.Type, rec.Fields[field.Name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *SetType:
+		return binary.Write(w, le, v.(*SetValue).Bits)
+	}
+	switch v := v.(type) {
+	case *RealValue:
+		return binary.Write(w, le, math.Float64bits(v.Val))
+	case *StringValue:
+		if err := binary.Write(w, le, uint32(len(v.Val))); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, v.Val)
+		return err
+	case *CharValue, *BooleanValue:
+		n, _ := ordinalOf(v)
+		return binary.Write(w, le, uint8(n))
+	default:
+		n, _ := ordinalOf(v)
+		return binary.Write(w, le, int64(n))
+	}
+}
+
+func decodeComponent(r io.Reader, t Type) (Value, error) {
+	le := binary.LittleEndian
+	switch t := t.(type) {
+	case *ArrayType:
+		arr := zeroValue(t).(*ArrayValue)
+		for k := range arr.Elems {
+			elem, err := decodeComponent(r, t.Element)
+			if err != nil {
+				return nil, orUnexpected(err, k)
+			}
+			arr.Elems[k] = elem
+		}
+		return arr, nil
+	case *RecordType:
+		rec := zeroValue(t).(*RecordValue)
+		for k, field := range t.Fields {
+			val, err := decodeComponent(r, field.Type)
+			if err != nil {
+				return nil, orUnexpected(err, k)
+			}
+			rec.Fields[field.Name] = val
+		}
+		return rec, nil
+	case *SetType:
+		set := &SetValue{Base: hostType(t.Element)}
+		return set, binary.Read(r, le, &set.Bits)
+	}
+	switch valueTypeOf(t) {
+	case RealType:
+		var bits uint64
+		err := binary.Read(r, le, &bits)
+		return &RealValue{Val: math.Float64frombits(bits)}, err
+	case StringType:
+		var n uint32
+		if err := binary.Read(r, le, &n); err != nil {
+			return nil, err
+		}
+		text := make([]byte, n)
+		_, err := io.ReadFull(r, text)
+		return &StringValue{Val: string(text)}, orUnexpected(err, 1)
+	case CharType, BooleanType:
+		var b uint8
+		err := binary.Read(r, le, &b)
+		return valueFromOrdinal(t, int(b)), err
+	default:
+		var n int64
+		err := binary.Read(r, le, &n)
+		return valueFromOrdinal(t, int(n)), err
+	}
+}
+
+func orUnexpected(err error, part int) error {
+	if part > 0 && errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func isFileComponentType(t Type) bool {
+	switch t := t.(type) {
+	case *FileType, *PointerType:
+		return false
+	case *ArrayType:
+		return isFileComponentType(t.Element)
+	case *RecordType:
+		for _, field := range t.Fields {
+			if !isFileComponentType(field.Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package interpreter
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// FileSystem opens the external files that program parameters and assign bind
+// file variables to. Embedders supply their own to control what a program can access.
+type FileSystem interface {
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+}
+
+// OSFileSystem is a FileSystem backed by the files of the operating system.
+type OSFileSystem struct{}
+
+func (OSFileSystem) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (OSFileSystem) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+// MemoryFileSystem is a FileSystem held in memory that maps file names to their contents.
+// A file written by the program replaces its entry when the file is closed.
+type MemoryFileSystem map[string]string
+
+func (fs MemoryFileSystem) Open(name string) (io.ReadCloser, error) {
+	contents, ok := fs[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return io.NopCloser(strings.NewReader(contents)), nil
+}
+
+func (fs MemoryFileSystem) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{fs: fs, name: name}, nil
+}
+
+type memoryFile struct {
+	strings.Builder
+	fs   MemoryFileSystem
+	name string
+}
+
+func (f *memoryFile) Close() error {
+	f.fs[f.name] = f.String()
+	return nil
+}
@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultRealWidth = 17
+	expDigits        = 2
+)
+
+func formatValue(val Value, width, decimals int, hasDecimals bool) (string, error) {
+	if hasDecimals {
+		switch v := val.(type) {
+		case *RealValue:
+			return pad(strconv.FormatFloat(v.Val, 'f', decimals, 64), width), nil
+		case *IntegerValue:
+			return pad(strconv.FormatFloat(float64(v.Val), 'f', decimals, 64), width), nil
+		}
+		return "", &PascalError{
+			Msg:    "Decimal places can only be given for real values",
+			Detail: fmt.Sprintf("The value written with :%d:%d has type %s.", width, decimals, val.Type()),
+			Hint:   "Drop the second format specifier, e.g. write(n:8).",
+		}
+	}
+
+	switch v := val.(type) {
+	case *RealValue:
+		return pad(formatReal(v.Val, width), width), nil
+	case *IntegerValue, *CharValue:
+		return pad(v.String(), width), nil
+	}
+	text := unpackString(val).String()
+	if width > 0 && width < len(text) {
+		text = text[:width]
+	}
+	return pad(text, width), nil
+}
+
+func formatReal(x float64, width int) string {
+	if width == 0 {
+		width = defaultRealWidth
+	}
+	decimals := max(width-expDigits-5, 1)
+	text := strconv.FormatFloat(x, 'E', decimals, 64)
+	if !strings.HasPrefix(text, "-") {
+		text = " " + text
+	}
+	return text
+}
+
+func pad(text string, width int) string {
+	if len(text) >= width {
+		return text
+	}
+	return strings.Repeat(" ", width-len(text)) + text
+}
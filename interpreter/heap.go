@@ -0,0 +1,39 @@
+package interpreter
+
+type heap struct {
+	cells map[int]Value
+	next  int
+}
+
+func newHeap() *heap {
+	return &heap{cells: make(map[int]Value), next: 1}
+}
+
+func (h *heap) allocate(value Value) int {
+	addr := h.next
+	h.next++
+	h.cells[addr] = value
+	return addr
+}
+
+func (h *heap) live(addr int) bool {
+	_, ok := h.cells[addr]
+	return ok
+}
+
+func (h *heap) free(addr int) {
+	delete(h.cells, addr)
+}
+
+type heapLocation struct {
+	heap *heap
+	addr int
+}
+
+func (l heapLocation) load() Value {
+	return l.heap.cells[l.addr]
+}
+
+func (l heapLocation) store(value Value) {
+	l.heap.cells[l.addr] = value
+}
@@ -1,162 +1,1669 @@
 package interpreter
 
 import (
+	"bufio"
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"pastel/ast"
 	"pastel/token"
+	"slices"
+	"strings"
+)
+
+const (
+	maxCallDepth = 10000
+	maxint       = math.MaxInt64
 )
 
 // Interpreter holds the state for program execution.
 type Interpreter struct {
-	env *Environment
+	env      *Environment
+	depth    int
+	heap     *heap
+	pointers []pendingPointer
+	files    FileSystem
+	input    *FileValue
+	output   *FileValue
+	open     []*FileValue
+	dialect  ast.Dialect
+	statics  map[*ast.TypedConstDecl]staticVariable
+	exitCode int
+}
+
+// New creates a new Interpreter instance with a fresh environment.
+// Standard output is the process's standard output; standard input is empty until SetInput is called.
+func New() *Interpreter {
+	i := &Interpreter{
+		env:     NewEnvironment(),
+		heap:    newHeap(),
+		files:   OSFileSystem{},
+		statics: map[*ast.TypedConstDecl]staticVariable{},
+		input:   &FileValue{File: textType},
+		output:  &FileValue{File: textType, mode: fileWriting, writer: os.Stdout},
+	}
+	i.SetInput(strings.NewReader(""))
+	return i
+}
+
+// SetInput sets the reader that read, readln, eof and eoln consume as the standard file input.
+func (i *Interpreter) SetInput(r io.Reader) {
+	i.input.reader, i.input.line, i.input.mode = bufio.NewReader(r), 1, fileReading
+}
+
+// SetFileSystem sets the file system that external files are opened in.
+func (i *Interpreter) SetFileSystem(fs FileSystem) {
+	i.files = fs
+}
+
+// Run executes a Pascal program.
+// Exit and halt end it normally.
+func (i *Interpreter) Run(prog *ast.Program) error {
+	i.env.name = prog.Name
+	i.dialect = prog.Dialect
+	for _, param := range prog.Params {
+		switch param {
+		case "input":
+			i.env.DefineVariable(param, textType, i.input)
+		case "output":
+			i.env.DefineVariable(param, textType, i.output)
+		}
+	}
+	if err := i.declare(prog.Declarations); err != nil {
+		return err
+	}
+	if err := i.bindProgramParams(prog.Params); err != nil {
+		return at(prog, err)
+	}
+
+	if err := i.evalStmt(prog.Main); err != nil && !i.exited(err) {
+		if closeErr := i.closeFiles(); closeErr != nil {
+			return errors.Join(err, closeErr)
+		}
+		return err
+	}
+
+	return i.closeFiles()
+}
+
+func (i *Interpreter) bindProgramParams(params []string) error {
+	for _, param := range params {
+		if param == "input" || param == "output" {
+			continue
+		}
+		if _, isFile := i.env.declared[param].(*FileType); !isFile {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Program parameter '%s' must be a file variable", param),
+				Detail: fmt.Sprintf("'%s' is listed in the program heading but not declared as a file variable of '%s'.", param, i.env.Name()),
+				Hint:   fmt.Sprintf("Declare `var %s: text;` in the program block.", param),
+			}
+		}
+		i.env.store[param].(*FileValue).Name = param
+	}
+	return nil
+}
+
+func (i *Interpreter) declare(decls []ast.Stmt) error {
+	resolved := map[ast.TypeExpr]Type{}
+	i.pointers = nil
+	for _, decl := range decls {
+		var name string
+		var val Value
+		switch d := decl.(type) {
+		case *ast.LabelDecl:
+			i.env.DefineLabel(d.Label)
+			continue
+		case *ast.ConstDecl:
+			constant, err := i.evalExpr(d.Value)
+			if err != nil {
+				return err
+			}
+			if !i.env.DefineConstant(d.Name, constant) {
+				return at(d, duplicateDeclarationError(d.Name, i.env))
+			}
+			continue
+		case *ast.TypedConstDecl:
+			if err := i.declareTypedConstant(d); err != nil {
+				return at(d, err)
+			}
+			continue
+		case *ast.TypeDecl:
+			t, err := i.resolveType(d.Type)
+			if err != nil {
+				return at(d, err)
+			}
+			switch named := t.(type) {
+			case *EnumType:
+				if _, isNew := d.Type.(*ast.EnumType); isNew {
+					named.Name = d.Name
+				}
+			case *RecordType:
+				if _, isNew := d.Type.(*ast.RecordType); isNew {
+					named.Name = d.Name
+				}
+			}
+			if !i.env.DefineType(d.Name, t) {
+				return at(d, duplicateDeclarationError(d.Name, i.env))
+			}
+			continue
+		case *ast.VarDecl:
+			t, ok := resolved[d.Type]
+			if !ok {
+				var err error
+				if t, err = i.resolveType(d.Type); err != nil {
+					return at(d, err)
+				}
+				resolved[d.Type] = t
+			}
+			if !i.env.DefineVariable(d.Name, t, zeroValue(t)) {
+				return at(d, duplicateDeclarationError(d.Name, i.env))
+			}
+			continue
+		case *ast.ProcedureDecl:
+			if routine, ok := i.env.store[d.Name].(*RoutineValue); ok && routine.Decl.Forward && !d.Forward {
+				routine.Decl = completeForward(routine.Decl, d)
+				continue
+			}
+			name, val = d.Name, &RoutineValue{Decl: d, Env: i.env}
+		default:
+			continue
+		}
+		if !i.env.Define(name, val) {
+			return at(decl, duplicateDeclarationError(name, i.env))
+		}
+	}
+	for _, pending := range i.pointers {
+		t, err := lookupType(i.env, pending.ptr.TargetName)
+		if err != nil {
+			return at(pending.node, err)
+		}
+		pending.ptr.Target = t
+	}
+	for _, decl := range decls {
+		if d, ok := decl.(*ast.ProcedureDecl); ok && d.Forward {
+			if routine, _ := i.env.store[d.Name].(*RoutineValue); routine != nil && routine.Decl == d {
+				return at(d, &PascalError{
+					Msg:    fmt.Sprintf("Forward declaration of '%s' is never completed", d.Name),
+					Detail: fmt.Sprintf("'%s' is declared forward in '%s', but no later declaration gives its block.", d.Name, i.env.Name()),
+					Hint:   fmt.Sprintf("Declare `%s %s;` followed by its block later in the same declaration part.", routine.Type(), d.Name),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func completeForward(forward, body *ast.ProcedureDecl) *ast.ProcedureDecl {
+	decl := *body
+	decl.Params, decl.ReturnType, decl.Function = forward.Params, forward.ReturnType, forward.Function
+	return &decl
+}
+
+type pendingPointer struct {
+	ptr  *PointerType
+	node *ast.PointerType
+}
+
+func duplicateDeclarationError(name string, scope *Environment) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Duplicate declaration of '%s'", name),
+		Detail: fmt.Sprintf("'%s' is declared more than once in '%s'.", name, scope.Name()),
+		Hint:   "Rename one of the declarations; an inner block may reuse an outer name, the same block may not.",
+	}
+}
+
+func (i *Interpreter) resolveType(expr ast.TypeExpr) (_ Type, err error) {
+	defer func() { err = at(expr, err) }()
+
+	switch t := expr.(type) {
+	case *ast.NamedType:
+		return lookupType(i.env, t.Name)
+
+	case *ast.EnumType:
+		enum := &EnumType{Name: t.String(), Values: t.Values}
+		for ord, name := range t.Values {
+			if !i.env.DefineConstant(name, &EnumValue{Enum: enum, Ord: ord}) {
+				return nil, duplicateDeclarationError(name, i.env)
+			}
+		}
+		return enum, nil
+
+	case *ast.ArrayType:
+		index, err := i.resolveType(t.Index)
+		if err != nil {
+			return nil, err
+		}
+		low, high, bounded := ordinalBounds(index)
+		if !isOrdinalType(index) || !bounded {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Invalid array index type %s", index),
+				Detail: "An array index type must be an ordinal type with fixed bounds.",
+				Hint:   "Index arrays by a subrange such as 1..10, or by char, boolean or an enumerated type.",
+			}
+		}
+		element, err := i.resolveType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayType{Packed: t.Packed, Index: index, Element: element, Low: low, High: high}, nil
+
+	case *ast.SetType:
+		element, err := i.resolveType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		low, high, bounded := ordinalBounds(element)
+		if !isOrdinalType(element) || !bounded || low < 0 || high > maxSetOrdinal {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Invalid set base type %s", element),
+				Detail: fmt.Sprintf("A set base type must be ordinal with ordinals between 0 and %d.", maxSetOrdinal),
+				Hint:   "Use a subrange such as 0..63, or char, boolean or an enumerated type.",
+			}
+		}
+		return &SetType{Packed: t.Packed, Element: element}, nil
+
+	case *ast.RecordType:
+		record := &RecordType{Name: t.String(), Packed: t.Packed}
+		if err := i.resolveFields(record, nil, t.Fields, t.Variant); err != nil {
+			return nil, err
+		}
+		return record, nil
+
+	case *ast.FileType:
+		element, err := i.resolveType(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		if !isFileComponentType(element) {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Invalid file component type %s", element),
+				Detail: "The components of a file cannot be or contain files or pointers.",
+				Hint:   "Store the values the pointers lead to instead of the pointers themselves.",
+			}
+		}
+		return &FileType{Packed: t.Packed, Element: element}, nil
+
+	case *ast.StringType:
+		return &ShortStringType{Max: t.Size}, nil
+
+	case *ast.PointerType:
+		ptr := &PointerType{TargetName: t.Target}
+		i.pointers = append(i.pointers, pendingPointer{ptr: ptr, node: t})
+		return ptr, nil
+
+	case *ast.SubrangeType:
+		low, err := i.evalExpr(t.Low)
+		if err != nil {
+			return nil, err
+		}
+		high, err := i.evalExpr(t.High)
+		if err != nil {
+			return nil, err
+		}
+		host, ok := ordinalTypeOf(low)
+		lowOrd, _ := ordinalOf(low)
+		highOrd, _ := ordinalOf(high)
+		if !ok || low.Type() != high.Type() || lowOrd > highOrd {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Invalid subrange type %s", t),
+				Detail: "Both bounds of a subrange must have the same ordinal type, and the lower bound must not exceed the upper bound.",
+				Hint:   "Write subranges as low..high, e.g. 1..10 or 'a'..'z'.",
+			}
+		}
+		return &SubrangeType{Host: host, Low: lowOrd, High: highOrd}, nil
+
+	default:
+		return nil, &PascalError{
+			Msg:    "Unknown type expression",
+			Detail: fmt.Sprintf("Encountered an unsupported type: %T", expr),
+			Hint:   "Ensure all types are valid Pascal constructs.",
+		}
+	}
+}
+
+func (i *Interpreter) resolveFields(record *RecordType, owner *Variant, fields []*ast.FieldDecl, variant *ast.VariantPart) error {
+	resolved := map[ast.TypeExpr]Type{}
+	addField := func(name string, t Type) error {
+		if _, exists := record.Field(name); exists {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Duplicate field '%s'", name),
+				Detail: fmt.Sprintf("'%s' is declared more than once in the same record, counting the fields of all variants.", name),
+				Hint:   "Give every field of a record a distinct name.",
+			}
+		}
+		record.Fields = append(record.Fields, &Field{Name: name, Type: t, Variant: owner})
+		return nil
+	}
+
+	for _, decl := range fields {
+		t, ok := resolved[decl.Type]
+		if !ok {
+			var err error
+			if t, err = i.resolveType(decl.Type); err != nil {
+				return err
+			}
+			resolved[decl.Type] = t
+		}
+		if err := addField(decl.Name, t); err != nil {
+			return at(decl, err)
+		}
+	}
+	if variant == nil {
+		return nil
+	}
+
+	tagType, err := lookupType(i.env, variant.TagType)
+	if err != nil {
+		return at(variant, err)
+	}
+	if !isOrdinalType(tagType) {
+		return at(variant, &PascalError{
+			Msg:    fmt.Sprintf("Variant tag type %s must be ordinal", tagType),
+			Detail: "Variants are selected by the ordinal value of the tag.",
+			Hint:   "Use an integer, char, boolean or enumerated type for the tag.",
+		})
+	}
+	part := &VariantPart{Tag: variant.Tag, TagType: tagType, Parent: owner}
+	if variant.Tag != "" {
+		if err := addField(variant.Tag, tagType); err != nil {
+			return at(variant, err)
+		}
+	}
+
+	for _, decl := range variant.Variants {
+		v := &Variant{Part: part}
+		for _, label := range decl.Labels {
+			val, err := i.evalExpr(label)
+			if err != nil {
+				return err
+			}
+			ord, ok := ordinalOf(val)
+			if !ok || val.Type() != valueTypeOf(tagType) {
+				return at(label, &PascalError{
+					Msg:    "Type mismatch in variant label",
+					Detail: fmt.Sprintf("The tag has type %s but a label has type %s.", tagType, val.Type()),
+					Hint:   "Variant labels must have the same type as the tag.",
+				})
+			}
+			v.Labels = append(v.Labels, ord)
+		}
+		part.Variants = append(part.Variants, v)
+		if err := i.resolveFields(record, v, decl.Fields, decl.Variant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupType(env *Environment, name string) (Type, error) {
+	if t, ok := requiredTypes[name]; ok {
+		return t, nil
+	}
+	if t, ok := env.LookupType(name); ok {
+		return t, nil
+	}
+	return nil, &PascalError{
+		Msg:    fmt.Sprintf("Unknown type '%s'", name),
+		Detail: fmt.Sprintf("'%s' is used as a type but no type with that name is declared.", name),
+		Hint:   fmt.Sprintf("Declare it in a 'type' section, e.g. `type %s = 1..10;`.", name),
+	}
+}
+
+func (i *Interpreter) evalStmt(stmt ast.Stmt) (err error) {
+	defer func() { err = at(stmt, err) }()
+
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		return i.evalAssign(s)
+
+	case *ast.CompoundStmt:
+		return i.evalSequence(s.Statements)
+
+	case *ast.PrintStmt:
+		return i.write(s.Args, s.Newline)
+
+	case *ast.IfStmt:
+		cond, err := i.evalCondition(s.Condition, "if")
+		if err != nil {
+			return err
+		}
+		if cond {
+			return i.evalOptionalStmt(s.Then)
+		}
+		return i.evalOptionalStmt(s.Else)
+
+	case *ast.WhileStmt:
+		return i.evalWhile(s)
+
+	case *ast.RepeatStmt:
+		return i.evalRepeat(s)
+
+	case *ast.ForStmt:
+		return i.evalFor(s)
+
+	case *ast.CaseStmt:
+		return i.evalCase(s)
+
+	case *ast.WithStmt:
+		return i.evalWith(s)
+
+	case *ast.LabeledStmt:
+		for {
+			err := i.evalOptionalStmt(s.Stmt)
+			if jump, ok := i.jump(err); !ok || jump.label != s.Label {
+				return err
+			}
+		}
+
+	case *ast.GotoStmt:
+		target := i.env.labelScope(s.Label)
+		if target == nil {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Undeclared label %d", s.Label),
+				Detail: "A goto may only jump to a label declared in its block or an enclosing one.",
+				Hint:   fmt.Sprintf("Add `label %d;` to the block that contains the target statement.", s.Label),
+			}
+		}
+		return &gotoSignal{label: s.Label, target: target}
+
+	case *ast.CallStmt:
+		if proc, ok := i.builtinProcedure(s.Name); ok && !i.env.Exists(s.Name) {
+			return proc(s.Args)
+		}
+		routine, err := i.lookupRoutine(s.Name)
+		if err != nil {
+			return err
+		}
+		if routine.Decl.IsFunction() {
+			return &PascalError{
+				Msg:    fmt.Sprintf("Function '%s' cannot be called as a statement", s.Name),
+				Detail: "The result of a function call must be used in an expression.",
+				Hint:   fmt.Sprintf("Assign the result, e.g. `x := %s(...)`.", s.Name),
+			}
+		}
+		_, err = i.invoke(routine, s.Args)
+		return err
+
+	default:
+		return &PascalError{
+			Msg:    "Unknown statement type",
+			Detail: fmt.Sprintf("Encountered an unsupported statement: %T", stmt),
+			Hint:   "Ensure all statements are valid Pascal constructs.",
+		}
+	}
+}
+
+func (i *Interpreter) evalExpr(expr ast.Expr) (_ Value, err error) {
+	defer func() { err = at(expr, err) }()
+
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return &IntegerValue{Val: e.Value}, nil
+
+	case *ast.RealLiteral:
+		return &RealValue{Val: e.Value}, nil
+
+	case *ast.BooleanLiteral:
+		return &BooleanValue{Val: e.Value}, nil
+
+	case *ast.CharLiteral:
+		return &CharValue{Val: e.Value}, nil
+
+	case *ast.StringLiteral:
+		return &StringValue{Val: e.Value}, nil
+
+	case *ast.NilLiteral:
+		return &PointerValue{}, nil
+
+	case *ast.BinaryExpr:
+		if e.Operator.Type == token.AND || e.Operator.Type == token.OR {
+			return i.evalLogical(e)
+		}
+
+		left, err := i.evalExpr(e.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := i.evalExpr(e.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.evalBinaryOp(e.Operator, left, right)
+
+	case *ast.Identifier:
+		val, ok := i.env.Get(e.Value)
+		if c, isConstant := requiredConstants[e.Value]; !ok && isConstant {
+			return c, nil
+		}
+		if b, isBuiltin := builtins[e.Value]; !ok && isBuiltin {
+			return i.callBuiltin(e.Value, b, nil)
+		}
+		if !ok {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Undefined variable '%s'", e.Value),
+				Detail: "This variable is being used but was never declared or assigned a value.",
+				Hint:   fmt.Sprintf("Declare the variable using `var %s: integer;` and assign it a value before use.", e.Value),
+			}
+		}
+		if routine, ok := val.(*RoutineValue); ok {
+			return i.evalFunctionCall(routine, nil)
+		}
+		if loc, ok := i.env.locationOf(e.Value); ok {
+			if err := checkActive(loc); err != nil {
+				return nil, err
+			}
+		}
+		if _, undefined := val.(*UndefinedValue); undefined {
+			return nil, &PascalError{
+				Msg:    fmt.Sprintf("Variable '%s' is undefined", e.Value),
+				Detail: "The variable has no defined value at this point.",
+				Hint:   "A for-loop control variable is undefined after the loop; assign it a value before reading it.",
+			}
+		}
+		return val, nil
+
+	case *ast.CallExpr:
+		if b, ok := builtins[e.Name]; ok && !i.env.Exists(e.Name) {
+			return i.callBuiltin(e.Name, b, e.Args)
+		}
+		routine, err := i.lookupRoutine(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		return i.evalFunctionCall(routine, e.Args)
+
+	case *ast.IndexExpr:
+		base, err := i.evalExpr(e.Array)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := base.(*StringValue); ok && i.dialect == ast.Turbo {
+			index, err := i.stringIndex(s, e)
+			if err != nil {
+				return nil, err
+			}
+			return stringElement(s, index), nil
+		}
+		arr, index, err := i.evalIndex(base, e)
+		if err != nil {
+			return nil, err
+		}
+		return arr.Elems[index], nil
+
+	case *ast.FieldExpr:
+		base, err := i.evalExpr(e.Record)
+		if err != nil {
+			return nil, err
+		}
+		rec, field, err := selectField(base, e)
+		if err != nil {
+			return nil, err
+		}
+		if err := rec.checkActive(field); err != nil {
+			return nil, err
+		}
+		return rec.Fields[field.Name], nil
+
+	case *ast.DerefExpr:
+		loc, _, err := i.dereference(e)
+		if err != nil {
+			return nil, err
+		}
+		return loc.load(), nil
+
+	case *ast.SetExpr:
+		return i.evalSetConstructor(e)
+
+	case *ast.UnaryExpr:
+		operand, err := i.evalExpr(e.Operand)
+		if err != nil {
+			return nil, err
+		}
+		return i.evalUnaryOp(e.Operator, operand)
+
+	default:
+		return nil, &PascalError{
+			Msg:    "Unknown expression type",
+			Detail: fmt.Sprintf("Encountered an unsupported expression: %T", expr),
+			Hint:   "Ensure all expressions are valid Pascal constructs.",
+		}
+	}
+}
+
+func (i *Interpreter) evalBinaryOp(op token.Token, left, right Value) (Value, error) {
+	if op.Type == token.IN {
+		return evalIn(left, right)
+	}
+	if l, ok := left.(*SetValue); ok {
+		if r, ok := right.(*SetValue); ok {
+			return evalSetOp(op, l, r)
+		}
+	}
+
+	switch op.Type {
+	case token.PLUS:
+		return i.evalPlus(left, right)
+	case token.MINUS:
+		return i.evalMinus(left, right)
+	case token.STAR:
+		return i.evalStar(left, right)
+	case token.SLASH:
+		return i.evalSlash(left, right)
+	case token.DIV, token.MOD:
+		return i.evalIntegerDivision(op, left, right)
+	case token.EQUAL, token.NEQ, token.LT, token.LE, token.GT, token.GE:
+		return i.evalComparison(op, left, right)
+	default:
+		return nil, &PascalError{
+			Msg:    "Unknown operator",
+			Detail: fmt.Sprintf("Operator '%s' is not supported.", op.Literal),
+			Hint:   "Use valid operators such as +, -, *, /, div, mod, relational operators, and, or, or not.",
+		}
+	}
+}
+
+func (i *Interpreter) evalAssign(s *ast.AssignStmt) error {
+	if ident, ok := s.Target.(*ast.Identifier); ok {
+		return i.assignVariable(ident.Value, s.Value)
+	}
+
+	loc, t, err := i.locate(s.Target)
+	if errors.Is(err, errNotVariable) {
+		return &PascalError{
+			Msg:    "Left side of assignment must be a variable",
+			Detail: "Only variables and their components can be assigned.",
+			Hint:   "Assign to a declared variable or to an element of an array variable.",
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	val, err := i.evalExpr(s.Value)
+	if err != nil {
+		return err
+	}
+	if val, err = conform(t, val); err != nil {
+		return err
+	}
+	loc.store(val)
+	return nil
+}
+
+func (i *Interpreter) assignVariable(name string, expr ast.Expr) error {
+	current, ok := i.env.Get(name)
+	if !ok {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Undeclared variable '%s'", name),
+			Detail: "This variable is being used but was never declared with a type.",
+			Hint:   fmt.Sprintf("Try adding `var %s: integer;` at the top of your program.", name),
+		}
+	}
+
+	if i.env.IsConstant(name) {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot assign to constant '%s'", name),
+			Detail: "Constants are fixed when they are defined and cannot be changed.",
+			Hint:   fmt.Sprintf("Declare '%s' in a 'var' section if it needs to change.", name),
+		}
+	}
+
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return err
+	}
+
+	if routine, ok := current.(*RoutineValue); ok {
+		return i.assignResult(routine, val)
+	}
+	t, _ := i.env.TypeOf(name)
+	if val, err = conform(t, val); err != nil {
+		return err
+	}
+	i.env.Assign(name, val)
+	return nil
+}
+
+func (i *Interpreter) assignResult(routine *RoutineValue, val Value) error {
+	frame := i.env.activationOf(routine)
+	if !routine.Decl.IsFunction() || frame == nil {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot assign to %s '%s'", routine.Type(), routine.Decl.Name),
+			Detail: "Only a function's result can be assigned, and only inside that function's body.",
+			Hint:   "Assign to a variable instead.",
+		}
+	}
+	t, err := lookupType(routine.Env, routine.Decl.ReturnType)
+	if err != nil {
+		return err
+	}
+	if val, err = conform(t, val); err != nil {
+		return err
+	}
+	frame.result = val
+	return nil
+}
+
+func (i *Interpreter) lookupRoutine(name string) (*RoutineValue, error) {
+	val, ok := i.env.Get(name)
+	if !ok {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Undefined procedure or function '%s'", name),
+			Detail: "This name is being called but was never declared.",
+			Hint:   fmt.Sprintf("Declare it with `procedure %s;` or `function %s: integer;` before the code that calls it.", name, name),
+		}
+	}
+	routine, ok := val.(*RoutineValue)
+	if !ok {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("'%s' is not a procedure or function", name),
+			Detail: fmt.Sprintf("'%s' is a variable of type %s.", name, val.Type()),
+			Hint:   "Only procedures and functions can be called.",
+		}
+	}
+	return routine, nil
+}
+
+func (i *Interpreter) evalFunctionCall(routine *RoutineValue, args []ast.Expr) (Value, error) {
+	if !routine.Decl.IsFunction() {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Procedure '%s' cannot be used in an expression", routine.Decl.Name),
+			Detail: "Procedures do not return a value.",
+			Hint:   "Call the procedure as a statement, or declare it as a function.",
+		}
+	}
+	return i.invoke(routine, args)
+}
+
+func (i *Interpreter) invoke(routine *RoutineValue, args []ast.Expr) (Value, error) {
+	decl := routine.Decl
+	if len(args) != len(decl.Params) {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Wrong number of arguments to '%s'", decl.Name),
+			Detail: fmt.Sprintf("'%s' expects %d argument(s) but was given %d.", decl.Name, len(decl.Params), len(args)),
+			Hint:   "Pass exactly one argument for each declared parameter.",
+		}
+	}
+	if i.depth >= maxCallDepth {
+		return nil, &PascalError{
+			Msg:    "Stack overflow",
+			Detail: fmt.Sprintf("Calls nested more than %d deep while calling '%s'.", maxCallDepth, decl.Name),
+			Hint:   "Check that recursive routines have a terminating case.",
+		}
+	}
+
+	frame := NewEnclosedEnvironment(routine.Env)
+	frame.name = decl.Name
+	frame.routine = routine
+	for idx, param := range decl.Params {
+		if err := i.bindParam(frame, param, args[idx], decl.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	saved := i.env
+	i.env = frame
+	i.depth++
+	defer func() {
+		i.env = saved
+		i.depth--
+	}()
+
+	if err := i.declare(decl.Declarations); err != nil {
+		return nil, err
+	}
+	var exit *exitSignal
+	if err := i.evalStmt(decl.Body); err != nil && !errors.As(err, &exit) {
+		return nil, err
+	}
+
+	if !decl.IsFunction() {
+		return nil, nil
+	}
+	if frame.result == nil {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Function '%s' did not assign a result", decl.Name),
+			Detail: "The function finished without assigning a value to its name.",
+			Hint:   fmt.Sprintf("Add an assignment such as `%s := ...;` to the function body.", decl.Name),
+		}
+	}
+	return frame.result, nil
+}
+
+func (i *Interpreter) bindParam(frame *Environment, param *ast.Param, arg ast.Expr, routineName string) error {
+	if param.Routine != nil {
+		return i.bindRoutineParam(frame, param, arg, routineName)
+	}
+	if param.Schema != nil {
+		return i.bindConformantParam(frame, param, arg, routineName)
+	}
+
+	t, err := lookupType(frame.Outer(), param.Type)
+	if err != nil {
+		return at(param, err)
+	}
+
+	if !param.IsVar {
+		val, err := i.evalExpr(arg)
+		if err != nil {
+			return err
+		}
+		if val, err = conform(t, val); err != nil {
+			return at(arg, err)
+		}
+		if !frame.DefineVariable(param.Name, t, val) {
+			return at(param, duplicateDeclarationError(param.Name, frame))
+		}
+		return nil
+	}
+
+	loc, _, err := i.locate(arg)
+	if err != nil && !errors.Is(err, errNotVariable) {
+		return err
+	}
+	if err != nil {
+		return at(arg, notVariableArgumentError(param, routineName))
+	}
+	if frame.has(param.Name) {
+		return at(param, duplicateDeclarationError(param.Name, frame))
+	}
+	frame.bindAlias(param.Name, t, loc)
+	return nil
+}
+
+func notVariableArgumentError(param *ast.Param, routineName string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Argument for var parameter '%s' of '%s' must be a variable", param.Name, routineName),
+		Detail: "A var parameter refers to the caller's variable, so an expression cannot be passed.",
+		Hint:   "Pass a declared variable, or remove 'var' to pass the parameter by value.",
+	}
+}
+
+func (i *Interpreter) bindConformantParam(frame *Environment, param *ast.Param, arg ast.Expr, routineName string) error {
+	var loc location
+	var val Value
+	if param.IsVar {
+		l, _, err := i.locate(arg)
+		if err != nil && !errors.Is(err, errNotVariable) {
+			return err
+		}
+		if err != nil {
+			return at(arg, notVariableArgumentError(param, routineName))
+		}
+		loc, val = l, l.load()
+	} else {
+		v, err := i.evalExpr(arg)
+		if err != nil {
+			return err
+		}
+		val = v
+	}
+
+	arr, ok, err := conformantArray(param.Schema, frame.Outer(), val)
+	if err != nil {
+		return at(param, err)
+	}
+	if !ok {
+		return at(arg, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in argument '%s' of '%s'", param.Name, routineName),
+			Detail: fmt.Sprintf("A value of type %s does not conform to %s.", val.Type(), param.Schema),
+			Hint:   "Pass an array whose index type lies within the schema's index type and whose elements have the schema's element type.",
+		})
+	}
+
+	first := param
+	for _, p := range frame.routine.Decl.Params {
+		if p.Schema == param.Schema {
+			first = p
+			break
+		}
+	}
+	if first != param {
+		t, _ := frame.TypeOf(first.Name)
+		if !sameArrayType(t, arr.Array) {
+			return at(arg, &PascalError{
+				Msg:    fmt.Sprintf("Type mismatch in argument '%s' of '%s'", param.Name, routineName),
+				Detail: fmt.Sprintf("The parameters of one conformant array schema must be given arrays of the same type, but this one has type %s and '%s' has type %s.", arr.Array, first.Name, t),
+				Hint:   "Declare the arrays with one type name, or give each parameter its own schema.",
+			})
+		}
+	} else {
+		t := arr.Array
+		for schema := param.Schema; schema != nil; schema, _ = schema.Element.(*ast.ConformantArray) {
+			for _, bound := range []struct {
+				name string
+				ord  int
+			}{{schema.Low, t.Low}, {schema.High, t.High}} {
+				if !frame.DefineConstant(bound.name, valueFromOrdinal(t.Index, bound.ord)) {
+					return at(param, duplicateDeclarationError(bound.name, frame))
+				}
+			}
+			t, _ = t.Element.(*ArrayType)
+		}
+	}
+
+	if frame.has(param.Name) {
+		return at(param, duplicateDeclarationError(param.Name, frame))
+	}
+	if param.IsVar {
+		frame.bindAlias(param.Name, arr.Array, loc)
+	} else {
+		frame.DefineVariable(param.Name, arr.Array, copyValue(arr))
+	}
+	return nil
+}
+
+func (i *Interpreter) bindRoutineParam(frame *Environment, param *ast.Param, arg ast.Expr, routineName string) error {
+	heading := param.Routine
+	kind, expects := "procedural", ProcedureType
+	if heading.IsFunction() {
+		kind, expects = "functional", FunctionType
+	}
+
+	var routine *RoutineValue
+	ident, isIdent := arg.(*ast.Identifier)
+	if isIdent {
+		val, _ := i.env.Get(ident.Value)
+		routine, _ = val.(*RoutineValue)
+	}
+	if routine == nil && isIdent && !i.env.Exists(ident.Value) && i.isBuiltin(ident.Value) {
+		return at(arg, &PascalError{
+			Msg:    fmt.Sprintf("Required procedure or function '%s' cannot be passed as a parameter", ident.Value),
+			Detail: "Only procedures and functions declared in the program can be passed to procedural and functional parameters.",
+			Hint:   fmt.Sprintf("Declare a %s of your own that calls %s and pass that instead.", expects, ident.Value),
+		})
+	}
+	if routine == nil || routine.Decl.IsFunction() != heading.IsFunction() {
+		return at(arg, &PascalError{
+			Msg:    fmt.Sprintf("Argument for %s parameter '%s' of '%s' must be a %s", kind, param.Name, routineName, expects),
+			Detail: fmt.Sprintf("The argument is %s.", describeArgument(arg, routine)),
+			Hint:   fmt.Sprintf("Pass the name of a %s without arguments.", expects),
+		})
+	}
+	if !congruent(routine.Decl, routine.Env, heading, frame.Outer()) {
+		return at(arg, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in %s parameter '%s' of '%s'", kind, param.Name, routineName),
+			Detail: fmt.Sprintf("The parameters or result type of '%s' differ from those of '%s'.", routine.Decl.Name, param.Name),
+			Hint:   "The parameter lists must match in number, kind and type of parameters, and functions must have the same result type.",
+		})
+	}
+	if !frame.Define(param.Name, routine) {
+		return at(param, duplicateDeclarationError(param.Name, frame))
+	}
+	return nil
+}
+
+func (i *Interpreter) isBuiltin(name string) bool {
+	_, isFunction := builtins[name]
+	_, isProcedure := i.builtinProcedure(name)
+	return isFunction || isProcedure
+}
+
+func describeArgument(arg ast.Expr, routine *RoutineValue) string {
+	if routine != nil {
+		return fmt.Sprintf("the %s '%s'", routine.Type(), routine.Decl.Name)
+	}
+	if ident, ok := arg.(*ast.Identifier); ok {
+		return fmt.Sprintf("'%s', which is not a procedure or function", ident.Value)
+	}
+	return "an expression"
+}
+
+func congruent(a *ast.ProcedureDecl, aEnv *Environment, b *ast.ProcedureDecl, bEnv *Environment) bool {
+	if a.IsFunction() != b.IsFunction() || len(a.Params) != len(b.Params) {
+		return false
+	}
+	if a.IsFunction() && !sameTypeName(a.ReturnType, aEnv, b.ReturnType, bEnv) {
+		return false
+	}
+	for k, p := range a.Params {
+		q := b.Params[k]
+		if p.IsVar != q.IsVar || (p.Routine == nil) != (q.Routine == nil) {
+			return false
+		}
+		switch {
+		case p.Routine != nil:
+			if !congruent(p.Routine, aEnv, q.Routine, bEnv) {
+				return false
+			}
+		case p.Schema != nil || q.Schema != nil:
+			if !equivalentSchemas(p.Schema, aEnv, q.Schema, bEnv) {
+				return false
+			}
+		case !sameTypeName(p.Type, aEnv, q.Type, bEnv):
+			return false
+		}
+	}
+	return true
+}
+
+func sameTypeName(a string, aEnv *Environment, b string, bEnv *Environment) bool {
+	aType, err := lookupType(aEnv, a)
+	if err != nil {
+		return false
+	}
+	bType, err := lookupType(bEnv, b)
+	return err == nil && aType == bType
+}
+
+func equivalentSchemas(a *ast.ConformantArray, aEnv *Environment, b *ast.ConformantArray, bEnv *Environment) bool {
+	if a == nil || b == nil || a.Packed != b.Packed || !sameTypeName(a.IndexType, aEnv, b.IndexType, bEnv) {
+		return false
+	}
+	switch aElem := a.Element.(type) {
+	case *ast.ConformantArray:
+		bElem, ok := b.Element.(*ast.ConformantArray)
+		return ok && equivalentSchemas(aElem, aEnv, bElem, bEnv)
+	case *ast.NamedType:
+		bElem, ok := b.Element.(*ast.NamedType)
+		return ok && sameTypeName(aElem.Name, aEnv, bElem.Name, bEnv)
+	}
+	return false
+}
+
+var errNotVariable = errors.New("expression is not a variable")
+
+func (i *Interpreter) locate(expr ast.Expr) (location, Type, error) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		scope := i.env.lookup(e.Value)
+		if scope == nil {
+			return nil, nil, errNotVariable
+		}
+		t, isVariable := scope.declared[e.Value]
+		if !isVariable {
+			return nil, nil, errNotVariable
+		}
+		return variableLocation{env: scope, name: e.Value}, t, nil
+
+	case *ast.IndexExpr:
+		loc, t, err := i.locate(e.Array)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s, ok := loc.load().(*StringValue); ok && i.dialect == ast.Turbo {
+			index, err := i.stringIndex(s, e)
+			if err != nil {
+				return nil, nil, at(e, err)
+			}
+			if index == 0 {
+				return stringLocation{str: loc, index: index}, lengthByteOf(t), nil
+			}
+			return stringLocation{str: loc, index: index}, charType, nil
+		}
+		arr, index, err := i.evalIndex(loc.load(), e)
+		if err != nil {
+			return nil, nil, at(e, err)
+		}
+		return elementLocation{array: loc, index: index}, arr.Array.Element, nil
+
+	case *ast.FieldExpr:
+		loc, _, err := i.locate(e.Record)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, field, err := selectField(loc.load(), e)
+		if err != nil {
+			return nil, nil, at(e, err)
+		}
+		return fieldLocation{record: loc, field: field}, field.Type, nil
+
+	case *ast.DerefExpr:
+		loc, t, err := i.dereference(e)
+		return loc, t, at(e, err)
+
+	default:
+		return nil, nil, errNotVariable
+	}
 }
 
-// New creates a new Interpreter instance with a fresh environment.
-func New() *Interpreter {
-	return &Interpreter{env: NewEnvironment()}
+func (i *Interpreter) dereference(e *ast.DerefExpr) (location, Type, error) {
+	val, err := i.evalExpr(e.Pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := variableName(e.Pointer)
+	ptr, ok := val.(*PointerValue)
+	if !ok {
+		return nil, nil, &PascalError{
+			Msg:    fmt.Sprintf("Cannot dereference '%s'", name),
+			Detail: fmt.Sprintf("'%s' has type %s, which is not a pointer.", name, val.Type()),
+			Hint:   "Only pointer variables can be followed by ^.",
+		}
+	}
+	if ptr.Addr == 0 {
+		return nil, nil, &PascalError{
+			Msg:    fmt.Sprintf("Dereference of nil pointer '%s'", name),
+			Detail: fmt.Sprintf("'%s' is nil, so %s^ does not denote a variable.", name, name),
+			Hint:   fmt.Sprintf("Allocate a variable with new(%s), or test `%s <> nil` before dereferencing.", name, name),
+		}
+	}
+	if !i.heap.live(ptr.Addr) {
+		return nil, nil, &PascalError{
+			Msg:    fmt.Sprintf("Dereference of disposed pointer '%s'", name),
+			Detail: fmt.Sprintf("The variable '%s' points to was released by dispose.", name),
+			Hint:   "Set pointers to nil after dispose, and stop using other pointers to the same variable.",
+		}
+	}
+	return heapLocation{heap: i.heap, addr: ptr.Addr}, ptr.Pointer.Target, nil
 }
 
-// Run executes a Pascal program.
-func (i *Interpreter) Run(prog *ast.Program) error {
-	for _, decl := range prog.Declarations {
-		if v, ok := decl.(*ast.VarDecl); ok {
-			i.env.Set(v.Name, defaultValue(v.Type))
+func (i *Interpreter) evalIndex(base Value, e *ast.IndexExpr) (*ArrayValue, int, error) {
+	arr, ok := base.(*ArrayValue)
+	if !ok {
+		return nil, 0, &PascalError{
+			Msg:    fmt.Sprintf("Cannot index '%s'", variableName(e.Array)),
+			Detail: fmt.Sprintf("'%s' has type %s, which is not an array.", variableName(e.Array), base.Type()),
+			Hint:   "Only array variables can be indexed with [...].",
 		}
 	}
 
-	if err := i.evalStmt(prog.Main); err != nil {
-		return err
+	val, err := i.evalExpr(e.Index)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, ok := ordinalOf(val)
+	if !ok || val.Type() != valueTypeOf(arr.Array.Index) {
+		return nil, 0, &PascalError{
+			Msg:    "Type mismatch in array index",
+			Detail: fmt.Sprintf("'%s' is indexed by %s but the index has type %s.", variableName(e.Array), arr.Array.Index, val.Type()),
+			Hint:   "Index an array with values of its declared index type.",
+		}
 	}
+	if n < arr.Array.Low || n > arr.Array.High {
+		return nil, 0, &PascalError{
+			Msg: "Array index out of range",
+			Detail: fmt.Sprintf("Index %s is outside the bounds %s..%s of '%s'.",
+				ordinalLiteral(arr.Array.Index, n), ordinalLiteral(arr.Array.Index, arr.Array.Low), ordinalLiteral(arr.Array.Index, arr.Array.High), variableName(e.Array)),
+			Hint: "Check the index against the array bounds before using it.",
+		}
+	}
+	return arr, n - arr.Array.Low, nil
+}
 
-	return nil
+func selectField(base Value, e *ast.FieldExpr) (*RecordValue, *Field, error) {
+	rec, ok := base.(*RecordValue)
+	if !ok {
+		return nil, nil, &PascalError{
+			Msg:    fmt.Sprintf("Cannot select field '%s' of '%s'", e.Field, variableName(e.Record)),
+			Detail: fmt.Sprintf("'%s' has type %s, which is not a record.", variableName(e.Record), base.Type()),
+			Hint:   "Only record variables have fields.",
+		}
+	}
+	field, ok := rec.Record.Field(e.Field)
+	if !ok {
+		return nil, nil, &PascalError{
+			Msg:    fmt.Sprintf("Unknown field '%s'", e.Field),
+			Detail: fmt.Sprintf("'%s' has type %s, which has no field named '%s'.", variableName(e.Record), rec.Record, e.Field),
+			Hint:   "Check the spelling against the fields in the record type declaration.",
+		}
+	}
+	return rec, field, nil
 }
 
-func defaultValue(typeName string) Value {
-	switch typeName {
-	case "integer":
-		return &IntegerValue{Val: 0}
-	case "real":
-		return &RealValue{Val: 0.0}
-	case "boolean":
-		return &BooleanValue{Val: false}
-	case "char":
-		return &CharValue{Val: ' '}
-	case "string":
-		return &StringValue{Val: ""}
+func variableName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.IndexExpr:
+		return variableName(e.Array)
+	case *ast.FieldExpr:
+		return variableName(e.Record) + "." + e.Field
+	case *ast.DerefExpr:
+		return variableName(e.Pointer) + "^"
 	default:
-		return &IntegerValue{Val: 0}
+		return "expression"
 	}
 }
 
-func (i *Interpreter) evalStmt(stmt ast.Stmt) error {
-	switch s := stmt.(type) {
-	case *ast.AssignStmt:
-		if !i.env.Exists(s.Name) {
-			return &PascalError{
-				Msg:    fmt.Sprintf("Undeclared variable '%s'", s.Name),
-				Detail: "This variable is being used but was never declared with a type.",
-				Hint:   fmt.Sprintf("Try adding `var %s: integer;` at the top of your program.", s.Name),
-			}
+func (i *Interpreter) evalWhile(s *ast.WhileStmt) error {
+	for {
+		cond, err := i.evalCondition(s.Condition, "while")
+		if err != nil || !cond {
+			return err
 		}
-
-		val, err := i.evalExpr(s.Value)
-		if err != nil {
+		if done, err := loopControl(i.evalOptionalStmt(s.Body)); done {
 			return err
 		}
-		i.env.Set(s.Name, val)
+	}
+}
 
-	case *ast.CompoundStmt:
-		for _, stmt := range s.Statements {
-			if err := i.evalStmt(stmt); err != nil {
-				return err
-			}
+func (i *Interpreter) evalRepeat(s *ast.RepeatStmt) error {
+	for {
+		if done, err := loopControl(i.evalSequence(s.Body)); done {
+			return err
 		}
-
-	case *ast.PrintStmt:
-		val, err := i.evalExpr(s.Argument)
-		if err != nil {
+		done, err := i.evalCondition(s.Condition, "until")
+		if err != nil || done {
 			return err
 		}
-		fmt.Println(val.String())
+	}
+}
 
-	default:
+func (i *Interpreter) evalFor(s *ast.ForStmt) error {
+	scope, ok := i.env.Resolve(s.Variable)
+	if !ok {
 		return &PascalError{
-			Msg:    "Unknown statement type",
-			Detail: fmt.Sprintf("Encountered an unsupported statement: %T", stmt),
-			Hint:   "Ensure all statements are valid Pascal constructs.",
+			Msg:    fmt.Sprintf("Undeclared variable '%s'", s.Variable),
+			Detail: "The for-loop control variable must be declared in the enclosing block.",
+			Hint:   fmt.Sprintf("Try adding `var %s: integer;` at the top of your program.", s.Variable),
+		}
+	}
+	if scope.constants[s.Variable] {
+		return &PascalError{
+			Msg:    fmt.Sprintf("Cannot use constant '%s' as a for-loop control variable", s.Variable),
+			Detail: "The control variable is assigned on every iteration, but constants cannot change.",
+			Hint:   fmt.Sprintf("Declare `var %s: integer;` in the block that contains the loop.", s.Variable),
+		}
+	}
+	if scope != i.env.block() {
+		return nonLocalControlVariableError(s.Variable, fmt.Sprintf("'%s' resolves to a variable of '%s', but the loop is in '%s'.", s.Variable, scope.Name(), i.env.Name()))
+	}
+	if _, isAlias := scope.aliases[s.Variable]; isAlias {
+		return nonLocalControlVariableError(s.Variable, fmt.Sprintf("'%s' is a var parameter of '%s'.", s.Variable, scope.Name()))
+	}
+	varType, isVariable := scope.TypeOf(s.Variable)
+	if !isVariable || !isOrdinalType(varType) {
+		detail := fmt.Sprintf("'%s' is not a variable.", s.Variable)
+		if isVariable {
+			detail = fmt.Sprintf("'%s' has type %s.", s.Variable, varType)
+		}
+		return &PascalError{
+			Msg:    fmt.Sprintf("For-loop control variable '%s' must be ordinal", s.Variable),
+			Detail: detail,
+			Hint:   "Use an integer, char, boolean or enumerated variable to control a for loop.",
+		}
+	}
+
+	start, err := i.evalForBound(s.Initial, s.Variable, varType)
+	if err != nil {
+		return err
+	}
+	end, err := i.evalForBound(s.Final, s.Variable, varType)
+	if err != nil {
+		return err
+	}
+
+	step := 1
+	if s.Downto {
+		step = -1
+	}
+	if (step > 0 && start <= end) || (step < 0 && start >= end) {
+		for k, bound := range []int{start, end} {
+			if err := checkRange(varType, valueFromOrdinal(varType, bound)); err != nil {
+				return at([]ast.Expr{s.Initial, s.Final}[k], err)
+			}
+		}
+		for n := start; ; n += step {
+			scope.Set(s.Variable, valueFromOrdinal(varType, n))
+			if done, err := loopControl(i.evalOptionalStmt(s.Body)); done {
+				return err
+			}
+			if n == end {
+				break
+			}
 		}
 	}
 
+	scope.Set(s.Variable, &UndefinedValue{Of: valueTypeOf(varType)})
 	return nil
 }
 
-func (i *Interpreter) evalExpr(expr ast.Expr) (Value, error) {
-	switch e := expr.(type) {
-	case *ast.IntegerLiteral:
-		return &IntegerValue{Val: e.Value}, nil
+func nonLocalControlVariableError(name, detail string) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("For-loop control variable '%s' must be a local variable", name),
+		Detail: detail,
+		Hint:   fmt.Sprintf("Declare `var %s: integer;` in the block that contains the loop.", name),
+	}
+}
 
-	case *ast.RealLiteral:
-		return &RealValue{Val: e.Value}, nil
+func (i *Interpreter) evalForBound(expr ast.Expr, variable string, varType Type) (int, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := ordinalOf(val)
+	if !ok || val.Type() != valueTypeOf(varType) {
+		return 0, at(expr, &PascalError{
+			Msg:    "Type mismatch in for-loop bound",
+			Detail: fmt.Sprintf("Control variable '%s' has type %s but the bound has type %s.", variable, varType, val.Type()),
+			Hint:   "The initial and final values must have the same type as the control variable.",
+		})
+	}
+	return n, nil
+}
 
-	case *ast.BooleanLiteral:
-		return &BooleanValue{Val: e.Value}, nil
+func (i *Interpreter) evalCase(s *ast.CaseStmt) error {
+	selector, err := i.evalExpr(s.Selector)
+	if err != nil {
+		return err
+	}
+	ord, ok := ordinalOf(selector)
+	if !ok {
+		return at(s.Selector, &PascalError{
+			Msg:    "Case selector must be ordinal",
+			Detail: fmt.Sprintf("The selector has type %s.", selector.Type()),
+			Hint:   "Use an integer, char, boolean or enumerated expression as the case selector.",
+		})
+	}
 
-	case *ast.CharLiteral:
-		return &CharValue{Val: e.Value}, nil
+	for _, branch := range s.Branches {
+		for _, label := range branch.Labels {
+			matched, err := i.caseLabelMatches(label, selector.Type(), ord)
+			if err != nil {
+				return err
+			}
+			if matched {
+				return i.evalOptionalStmt(branch.Body)
+			}
+		}
+	}
 
-	case *ast.StringLiteral:
-		return &StringValue{Val: e.Value}, nil
+	if s.Else != nil {
+		return i.evalStmt(s.Else)
+	}
+	return &PascalError{
+		Msg:    "No case label matches the selector",
+		Detail: fmt.Sprintf("The selector value %s is not listed among the case labels.", selector),
+		Hint:   "Add a label for this value or an 'else' branch to the case statement.",
+	}
+}
 
-	case *ast.BinaryExpr:
-		left, err := i.evalExpr(e.Left)
-		if err != nil {
-			return nil, err
+func (i *Interpreter) caseLabelMatches(label *ast.CaseLabel, selectorType ValueType, ord int) (bool, error) {
+	low, err := i.evalCaseConstant(label.Low, selectorType)
+	if err != nil {
+		return false, err
+	}
+	if label.High == nil {
+		return ord == low, nil
+	}
+	high, err := i.evalCaseConstant(label.High, selectorType)
+	if err != nil {
+		return false, err
+	}
+	return low <= ord && ord <= high, nil
+}
+
+func (i *Interpreter) evalCaseConstant(expr ast.Expr, selectorType ValueType) (int, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	ord, ok := ordinalOf(val)
+	if !ok || val.Type() != selectorType {
+		return 0, &PascalError{
+			Msg:    "Type mismatch in case label",
+			Detail: fmt.Sprintf("The selector has type %s but a label has type %s.", selectorType, val.Type()),
+			Hint:   "Case labels must have the same type as the selector.",
 		}
+	}
+	return ord, nil
+}
 
-		right, err := i.evalExpr(e.Right)
-		if err != nil {
-			return nil, err
+func (i *Interpreter) evalWith(s *ast.WithStmt) error {
+	loc, _, err := i.locate(s.Record)
+	if err != nil && !errors.Is(err, errNotVariable) {
+		return err
+	}
+	var rec *RecordValue
+	if err == nil {
+		rec, _ = loc.load().(*RecordValue)
+	}
+	if rec == nil {
+		return &PascalError{
+			Msg:    fmt.Sprintf("'with' requires a record variable, got '%s'", variableName(s.Record)),
+			Detail: "Only the fields of a record variable can be opened as a scope.",
+			Hint:   "Name a variable of a record type after 'with'.",
 		}
+	}
 
-		return i.evalBinaryOp(e.Operator, left, right)
+	scope := NewEnclosedEnvironment(i.env)
+	scope.name = i.env.Name()
+	scope.with = true
+	for _, field := range rec.Record.Fields {
+		scope.bindAlias(field.Name, field.Type, fieldLocation{record: loc, field: field})
+	}
 
-	case *ast.Identifier:
-		val, ok := i.env.Get(e.Value)
+	saved := i.env
+	i.env = scope
+	defer func() { i.env = saved }()
+
+	return i.evalOptionalStmt(s.Body)
+}
+
+func (i *Interpreter) evalSequence(stmts []ast.Stmt) error {
+	for k := 0; k < len(stmts); k++ {
+		err := i.evalStmt(stmts[k])
+		if err == nil {
+			continue
+		}
+		jump, ok := i.jump(err)
 		if !ok {
-			return nil, &PascalError{
-				Msg:    fmt.Sprintf("Undefined variable '%s'", e.Value),
-				Detail: "This variable is being used but was never declared or assigned a value.",
-				Hint:   fmt.Sprintf("Declare the variable using `var %s: integer;` and assign it a value before use.", e.Value),
-			}
+			return err
 		}
-		return val, nil
+		next := slices.IndexFunc(stmts, func(stmt ast.Stmt) bool {
+			labeled, ok := stmt.(*ast.LabeledStmt)
+			return ok && labeled.Label == jump.label
+		})
+		if next < 0 {
+			return err
+		}
+		k = next - 1
+	}
+	return nil
+}
 
-	default:
+type gotoSignal struct {
+	label  int
+	target *Environment
+}
+
+func (g *gotoSignal) Error() string {
+	return fmt.Sprintf("goto %d did not reach its label", g.label)
+}
+
+func (i *Interpreter) jump(err error) (*gotoSignal, bool) {
+	var jump *gotoSignal
+	if errors.As(err, &jump) && jump.target == i.env.block() {
+		return jump, true
+	}
+	return nil, false
+}
+
+func (i *Interpreter) evalOptionalStmt(stmt ast.Stmt) error {
+	if stmt == nil {
+		return nil
+	}
+	return i.evalStmt(stmt)
+}
+
+func (i *Interpreter) evalCondition(expr ast.Expr, construct string) (bool, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(*BooleanValue)
+	if !ok {
+		return false, at(expr, &PascalError{
+			Msg:    fmt.Sprintf("Condition of '%s' must be boolean", construct),
+			Detail: fmt.Sprintf("The condition evaluated to a value of type %s.", val.Type()),
+			Hint:   "Use a relational operator such as '=' or '<' to build a boolean condition.",
+		})
+	}
+	return b.Val, nil
+}
+
+func (i *Interpreter) evalLogical(e *ast.BinaryExpr) (Value, error) {
+	left, err := i.evalBooleanOperand(e.Left, e.Operator)
+	if err != nil {
+		return nil, err
+	}
+	if e.Operator.Type == token.AND && !left {
+		return &BooleanValue{Val: false}, nil
+	}
+	if e.Operator.Type == token.OR && left {
+		return &BooleanValue{Val: true}, nil
+	}
+	right, err := i.evalBooleanOperand(e.Right, e.Operator)
+	if err != nil {
+		return nil, err
+	}
+	return &BooleanValue{Val: right}, nil
+}
+
+func (i *Interpreter) evalBooleanOperand(expr ast.Expr, op token.Token) (bool, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(*BooleanValue)
+	if !ok {
+		return false, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in '%s'", op.Literal),
+			Detail: fmt.Sprintf("Operator '%s' requires boolean operands, got %s.", op.Literal, val.Type()),
+			Hint:   "Parenthesize comparisons, e.g. (a < b) and (c < d).",
+		}
+	}
+	return b.Val, nil
+}
+
+func (i *Interpreter) evalUnaryOp(op token.Token, operand Value) (Value, error) {
+	switch op.Type {
+	case token.NOT:
+		if b, ok := operand.(*BooleanValue); ok {
+			return &BooleanValue{Val: !b.Val}, nil
+		}
 		return nil, &PascalError{
-			Msg:    "Unknown expression type",
-			Detail: fmt.Sprintf("Encountered an unsupported expression: %T", expr),
-			Hint:   "Ensure all expressions are valid Pascal constructs.",
+			Msg:    "Type mismatch in 'not'",
+			Detail: fmt.Sprintf("Operator 'not' requires a boolean operand, got %s.", operand.Type()),
+			Hint:   "Apply 'not' only to boolean expressions.",
+		}
+	case token.MINUS, token.PLUS:
+		switch v := operand.(type) {
+		case *IntegerValue:
+			if op.Type == token.MINUS {
+				return &IntegerValue{Val: -v.Val}, nil
+			}
+			return v, nil
+		case *RealValue:
+			if op.Type == token.MINUS {
+				return &RealValue{Val: -v.Val}, nil
+			}
+			return v, nil
 		}
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in unary '%s'", op.Literal),
+			Detail: fmt.Sprintf("A sign requires an integer or real operand, got %s.", operand.Type()),
+			Hint:   "Apply '+' and '-' only to numbers; use 'not' to negate booleans.",
+		}
+	}
+	return nil, &PascalError{
+		Msg:    "Unknown operator",
+		Detail: fmt.Sprintf("Unary operator '%s' is not supported.", op.Literal),
+		Hint:   "Use 'not', '+' or '-' as a prefix operator.",
 	}
 }
 
-func (i *Interpreter) evalBinaryOp(op token.Token, left, right Value) (Value, error) {
+func (i *Interpreter) evalComparison(op token.Token, left, right Value) (Value, error) {
+	if _, ok := left.(*PointerValue); ok {
+		return evalPointerComparison(op, left, right)
+	}
+	cmp, ok := compareValues(left, right)
+	if !ok {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in comparison '%s'", op.Literal),
+			Detail: fmt.Sprintf("Cannot compare %s with %s.", left.Type(), right.Type()),
+			Hint:   "Compare values of the same type; integers and reals may be mixed.",
+		}
+	}
+
+	var result bool
 	switch op.Type {
-	case token.PLUS:
-		return i.evalPlus(left, right)
-	case token.MINUS:
-		return i.evalMinus(left, right)
-	case token.STAR:
-		return i.evalStar(left, right)
-	case token.SLASH:
-		return i.evalSlash(left, right)
-	default:
+	case token.EQUAL:
+		result = cmp == 0
+	case token.NEQ:
+		result = cmp != 0
+	case token.LT:
+		result = cmp < 0
+	case token.LE:
+		result = cmp <= 0
+	case token.GT:
+		result = cmp > 0
+	case token.GE:
+		result = cmp >= 0
+	}
+	return &BooleanValue{Val: result}, nil
+}
+
+func evalPointerComparison(op token.Token, left, right Value) (Value, error) {
+	l := left.(*PointerValue)
+	r, ok := right.(*PointerValue)
+	if !ok || (l.Pointer != nil && r.Pointer != nil && l.Pointer.Target != r.Pointer.Target) {
 		return nil, &PascalError{
-			Msg:    "Unknown operator",
-			Detail: fmt.Sprintf("Operator '%s' is not supported.", op.Literal),
-			Hint:   "Use valid operators such as +, -, *, or /.",
+			Msg:    fmt.Sprintf("Type mismatch in comparison '%s'", op.Literal),
+			Detail: fmt.Sprintf("Cannot compare %s with %s.", left.Type(), right.Type()),
+			Hint:   "Compare a pointer with nil or with a pointer to the same type.",
+		}
+	}
+	switch op.Type {
+	case token.EQUAL:
+		return &BooleanValue{Val: l.Addr == r.Addr}, nil
+	case token.NEQ:
+		return &BooleanValue{Val: l.Addr != r.Addr}, nil
+	}
+	return nil, &PascalError{
+		Msg:    fmt.Sprintf("Pointers cannot be compared with '%s'", op.Literal),
+		Detail: "Pointers are only equal or unequal; they have no order.",
+		Hint:   "Use '=' or '<>' to compare pointers.",
+	}
+}
+
+func compareValues(left, right Value) (int, bool) {
+	left, right = unpackString(left), unpackString(right)
+	switch l := left.(type) {
+	case *IntegerValue:
+		switch r := right.(type) {
+		case *IntegerValue:
+			return cmp.Compare(l.Val, r.Val), true
+		case *RealValue:
+			return cmp.Compare(float64(l.Val), r.Val), true
+		}
+	case *RealValue:
+		switch r := right.(type) {
+		case *IntegerValue:
+			return cmp.Compare(l.Val, float64(r.Val)), true
+		case *RealValue:
+			return cmp.Compare(l.Val, r.Val), true
+		}
+	case *BooleanValue:
+		if r, ok := right.(*BooleanValue); ok {
+			return cmp.Compare(boolOrdinal(l.Val), boolOrdinal(r.Val)), true
+		}
+	case *EnumValue:
+		if r, ok := right.(*EnumValue); ok && l.Enum == r.Enum {
+			return cmp.Compare(l.Ord, r.Ord), true
+		}
+	case *CharValue:
+		switch r := right.(type) {
+		case *CharValue:
+			return cmp.Compare(l.Val, r.Val), true
+		case *StringValue:
+			return cmp.Compare(string(l.Val), r.Val), true
+		}
+	case *StringValue:
+		switch r := right.(type) {
+		case *StringValue:
+			return cmp.Compare(l.Val, r.Val), true
+		case *CharValue:
+			return cmp.Compare(l.Val, string(r.Val)), true
 		}
 	}
+	return 0, false
 }
 
 func (i *Interpreter) evalPlus(left, right Value) (Value, error) {
@@ -164,7 +1671,7 @@ func (i *Interpreter) evalPlus(left, right Value) (Value, error) {
 	case *IntegerValue:
 		switch r := right.(type) {
 		case *IntegerValue:
-			return &IntegerValue{Val: l.Val + r.Val}, nil
+			return integerResult("+", l.Val, r.Val, l.Val+r.Val)
 		case *RealValue:
 			return &RealValue{Val: float64(l.Val) + r.Val}, nil
 		}
@@ -202,7 +1709,7 @@ func (i *Interpreter) evalMinus(left, right Value) (Value, error) {
 	case *IntegerValue:
 		switch r := right.(type) {
 		case *IntegerValue:
-			return &IntegerValue{Val: l.Val - r.Val}, nil
+			return integerResult("-", l.Val, r.Val, l.Val-r.Val)
 		case *RealValue:
 			return &RealValue{Val: float64(l.Val) - r.Val}, nil
 		}
@@ -226,7 +1733,7 @@ func (i *Interpreter) evalStar(left, right Value) (Value, error) {
 	case *IntegerValue:
 		switch r := right.(type) {
 		case *IntegerValue:
-			return &IntegerValue{Val: l.Val * r.Val}, nil
+			return multiplyIntegers(l.Val, r.Val)
 		case *RealValue:
 			return &RealValue{Val: float64(l.Val) * r.Val}, nil
 		}
@@ -253,7 +1760,7 @@ func (i *Interpreter) evalSlash(left, right Value) (Value, error) {
 			if r.Val == 0 {
 				return nil, divisionByZeroError()
 			}
-			return &IntegerValue{Val: l.Val / r.Val}, nil
+			return &RealValue{Val: float64(l.Val) / float64(r.Val)}, nil
 		case *RealValue:
 			if r.Val == 0 {
 				return nil, divisionByZeroError()
@@ -281,6 +1788,66 @@ func (i *Interpreter) evalSlash(left, right Value) (Value, error) {
 	}
 }
 
+func (i *Interpreter) evalIntegerDivision(op token.Token, left, right Value) (Value, error) {
+	l, lok := left.(*IntegerValue)
+	r, rok := right.(*IntegerValue)
+	if !lok || !rok {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Type mismatch in '%s'", op.Literal),
+			Detail: fmt.Sprintf("Operator '%s' requires integer operands, got %s and %s.", op.Literal, left.Type(), right.Type()),
+			Hint:   "Use '/' to divide real numbers, or convert them with trunc or round first.",
+		}
+	}
+	if op.Type == token.DIV {
+		if r.Val == 0 {
+			return nil, divisionByZeroError()
+		}
+		return &IntegerValue{Val: l.Val / r.Val}, nil
+	}
+	if r.Val <= 0 {
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Non-positive divisor in %d mod %d", l.Val, r.Val),
+			Detail: "The right operand of 'mod' must be greater than zero.",
+			Hint:   "Check the divisor before using 'mod', or use abs to make it positive.",
+		}
+	}
+	m := l.Val % r.Val
+	if m < 0 {
+		m += r.Val
+	}
+	return &IntegerValue{Val: m}, nil
+}
+
+func integerResult(op string, l, r, result int) (Value, error) {
+	overflowed := result < -maxint
+	switch op {
+	case "+":
+		overflowed = overflowed || (r > 0 && result < l) || (r < 0 && result > l)
+	case "-":
+		overflowed = overflowed || (r > 0 && result > l) || (r < 0 && result < l)
+	}
+	if overflowed {
+		return nil, overflowError(op, l, r)
+	}
+	return &IntegerValue{Val: result}, nil
+}
+
+func multiplyIntegers(l, r int) (Value, error) {
+	product := l * r
+	if l != 0 && product/l != r {
+		return nil, overflowError("*", l, r)
+	}
+	return integerResult("*", l, r, product)
+}
+
+func overflowError(op string, l, r int) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Integer overflow in %d %s %d", l, op, r),
+		Detail: fmt.Sprintf("The result is outside the integer range -maxint..maxint (maxint = %d).", maxint),
+		Hint:   "Use real arithmetic for values this large, or keep the operands smaller.",
+	}
+}
+
 func divisionByZeroError() *PascalError {
 	return &PascalError{
 		Msg:    "Division by zero",
@@ -2,8 +2,11 @@ package interpreter
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"pastel/ast"
 	"pastel/lexer"
 	"pastel/parser"
 	"strings"
@@ -84,7 +87,7 @@ func TestInterpreter_Division(t *testing.T) {
 	input := `program test;
 var x: integer;
 begin
-  x := 20 / 4;
+  x := 20 div 4;
   writeln(x);
 end.`
 
@@ -186,7 +189,7 @@ func TestInterpreter_ComplexExpression(t *testing.T) {
 	input := `program test;
 var result: integer;
 begin
-  result := (10 + 5) * 2 - 6 / 3;
+  result := (10 + 5) * 2 - 6 div 3;
   writeln(result);
 end.`
 
@@ -195,7 +198,7 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// (10 + 5) * 2 - 6 / 3 = 15 * 2 - 2 = 30 - 2 = 28
+	// (10 + 5) * 2 - 6 div 3 = 15 * 2 - 2 = 30 - 2 = 28
 	expected := "28\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
@@ -307,7 +310,7 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expected := "3.14\n"
+	expected := " 3.1400000000E+00\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
 	}
@@ -326,7 +329,7 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expected := "4\n"
+	expected := " 4.0000000000E+00\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
 	}
@@ -345,7 +348,7 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expected := "10.5\n"
+	expected := " 1.0500000000E+01\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
 	}
@@ -458,7 +461,7 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expected := "0\n"
+	expected := " 0.0000000000E+00\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
 	}
@@ -513,16 +516,2567 @@ end.`
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expected := "3.5\n"
+	expected := " 3.5000000000E+00\n"
 	if output != expected {
 		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
 	}
 }
 
-// runProgram parses and executes a Pascal program, returning its output
-func runProgram(input string) (string, error) {
+func TestInterpreter_IfThenElse(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  x := 5;
+  if x > 3 then writeln('big') else writeln('small');
+  if x < 3 then writeln('big') else writeln('small');
+  if x = 5 then
+    begin
+      x := x + 1;
+      writeln(x)
+    end
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "big\nsmall\n6\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_DanglingElse(t *testing.T) {
+	input := `program test;
+begin
+  if false then if true then writeln('inner then') else writeln('inner else');
+  if true then if false then writeln('inner then') else writeln('inner else')
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "inner else\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_RelationalOperators(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"1 = 1", "true"},
+		{"1 <> 1", "false"},
+		{"1 < 2", "true"},
+		{"2 <= 2", "true"},
+		{"3 > 4", "false"},
+		{"4 >= 5", "false"},
+		{"1 < 1.5", "true"},
+		{"'a' < 'b'", "true"},
+		{"'abc' = 'abc'", "true"},
+		{"false < true", "true"},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nbegin\n  writeln(" + tt.expr + ");\nend."
+		output, err := runProgram(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.expr, err)
+		}
+		if output != tt.expected+"\n" {
+			t.Fatalf("%s: expected %q, got %q", tt.expr, tt.expected, output)
+		}
+	}
+}
+
+func TestInterpreter_BooleanOperators(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"true and false", "false"},
+		{"true or false", "true"},
+		{"not false", "true"},
+		{"not true or true", "true"},
+		{"(1 < 2) and (2 < 3)", "true"},
+		{"not (1 < 2)", "false"},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nbegin\n  writeln(" + tt.expr + ");\nend."
+		output, err := runProgram(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.expr, err)
+		}
+		if output != tt.expected+"\n" {
+			t.Fatalf("%s: expected %q, got %q", tt.expr, tt.expected, output)
+		}
+	}
+}
+
+func TestInterpreter_NonBooleanCondition(t *testing.T) {
+	input := `program test;
+begin
+  if 1 then writeln('yes');
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected error for non-boolean condition, got none")
+	}
+
+	if !strings.Contains(err.Error(), "must be boolean") {
+		t.Fatalf("expected 'must be boolean' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_WhileLoop(t *testing.T) {
+	input := `program test;
+var i: integer;
+var sum: integer;
+begin
+  i := 1;
+  while i <= 10 do
+    begin
+      sum := sum + i;
+      i := i + 1
+    end;
+  writeln(sum)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "55\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_RepeatLoopRunsAtLeastOnce(t *testing.T) {
+	input := `program test;
+var i: integer;
+begin
+  i := 100;
+  repeat
+    writeln(i);
+    i := i + 1
+  until i > 3
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "100\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ForLoop(t *testing.T) {
+	tests := []struct {
+		loop     string
+		expected string
+	}{
+		{"for i := 1 to 3 do writeln(i)", "1\n2\n3\n"},
+		{"for i := 3 downto 1 do writeln(i)", "3\n2\n1\n"},
+		{"for i := 5 to 1 do writeln(i)", ""},
+		{"for c := 'a' to 'c' do writeln(c)", "a\nb\nc\n"},
+		{"for b := false to true do writeln(b)", "false\ntrue\n"},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nvar i: integer;\nvar c: char;\nvar b: boolean;\nbegin\n  " + tt.loop + "\nend."
+		output, err := runProgram(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.loop, err)
+		}
+		if output != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.loop, tt.expected, output)
+		}
+	}
+}
+
+func TestInterpreter_ForLoopBoundsEvaluatedOnce(t *testing.T) {
+	input := `program test;
+var i: integer;
+var n: integer;
+begin
+  n := 3;
+  for i := 1 to n do
+    begin
+      n := n + 1;
+      writeln(i)
+    end;
+  writeln(n)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\n2\n3\n6\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ForLoopVariableUndefinedAfterLoop(t *testing.T) {
+	input := `program test;
+var i: integer;
+begin
+  for i := 1 to 3 do writeln(i);
+  writeln(i)
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected undefined variable error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "is undefined") {
+		t.Fatalf("expected 'is undefined' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_ForLoopRequiresOrdinalVariable(t *testing.T) {
+	input := `program test;
+var x: real;
+begin
+  for x := 1 to 3 do writeln(x)
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected ordinal control variable error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "must be ordinal") {
+		t.Fatalf("expected 'must be ordinal' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_ProcedureCall(t *testing.T) {
+	input := `program test;
+procedure greet(name: string);
+begin
+  writeln('Hello, ' + name)
+end;
+begin
+  greet('Ada');
+  greet('Niklaus')
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Hello, Ada\nHello, Niklaus\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_RecursiveFunction(t *testing.T) {
+	input := `program test;
+function fact(n: integer): integer;
+begin
+  if n <= 1 then fact := 1 else fact := n * fact(n - 1)
+end;
+begin
+  writeln(fact(10))
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "3628800\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_VarParameters(t *testing.T) {
+	input := `program test;
+var x, y: integer;
+procedure swap(var a, b: integer);
+var t: integer;
+begin
+  t := a;
+  a := b;
+  b := t
+end;
+procedure bump(n: integer);
+begin
+  n := n + 100
+end;
+begin
+  x := 1;
+  y := 2;
+  swap(x, y);
+  bump(x);
+  writeln(x);
+  writeln(y)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "2\n1\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_VarParameterPassedThrough(t *testing.T) {
+	input := `program test;
+var total: integer;
+procedure add(var acc: integer; n: integer);
+begin
+  acc := acc + n
+end;
+procedure addTwice(var acc: integer; n: integer);
+begin
+  add(acc, n);
+  add(acc, n)
+end;
+begin
+  addTwice(total, 5);
+  writeln(total)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "10\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_NestedRoutinesUseStaticScope(t *testing.T) {
+	input := `program test;
+var x: integer;
+procedure show;
+begin
+  writeln(x)
+end;
+procedure outer;
+var x: integer;
+  procedure inner;
+  begin
+    x := x + 1
+  end;
+begin
+  x := 10;
+  inner;
+  inner;
+  writeln(x);
+  show
+end;
+begin
+  x := 1;
+  outer
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "12\n1\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ParameterlessFunction(t *testing.T) {
+	input := `program test;
+var calls: integer;
+function next: integer;
+begin
+  calls := calls + 1;
+  next := calls
+end;
+begin
+  writeln(next + next)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "3\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_RoutineErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"missing result",
+			"program test;\nfunction f: integer;\nbegin\nend;\nbegin\n  writeln(f)\nend.",
+			"did not assign a result",
+		},
+		{
+			"wrong argument count",
+			"program test;\nprocedure p(a: integer);\nbegin\nend;\nbegin\n  p(1, 2)\nend.",
+			"Wrong number of arguments",
+		},
+		{
+			"expression for var parameter",
+			"program test;\nprocedure p(var a: integer);\nbegin\nend;\nbegin\n  p(1 + 2)\nend.",
+			"must be a variable",
+		},
+		{
+			"undefined procedure",
+			"program test;\nbegin\n  missing\nend.",
+			"Undefined procedure or function",
+		},
+		{
+			"runaway recursion",
+			"program test;\nprocedure p;\nbegin\n  p\nend;\nbegin\n  p\nend.",
+			"Stack overflow",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := runProgram(tt.input)
+		if err == nil {
+			t.Fatalf("%s: expected error, got none", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("%s: expected %q error, got: %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestInterpreter_StaticLinksReachDefiningActivation(t *testing.T) {
+	input := `program test;
+procedure outer(n: integer);
+  procedure report;
+  begin
+    writeln(n)
+  end;
+  procedure recurse(k: integer);
+  var n: integer;
+  begin
+    n := 0;
+    if k > 0 then recurse(k - 1) else report
+  end;
+begin
+  recurse(3)
+end;
+begin
+  outer(7)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "7\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ForLoopControlVariableMustBeLocal(t *testing.T) {
+	input := `program test;
+var i: integer;
+procedure p;
+begin
+  for i := 1 to 3 do writeln(i)
+end;
+begin
+  p
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected non-local control variable error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "must be a local variable") {
+		t.Fatalf("expected 'must be a local variable' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_DuplicateDeclarationInSameBlock(t *testing.T) {
+	input := `program test;
+procedure p(x: integer);
+var x: integer;
+begin
+end;
+begin
+  p(1)
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected duplicate declaration error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "Duplicate declaration of 'x'") {
+		t.Fatalf("expected duplicate declaration error, got: %v", err)
+	}
+}
+
+func TestInterpreter_CaseStatement(t *testing.T) {
+	input := `program test;
+var i: integer;
+var c: char;
+begin
+  for i := 1 to 6 do
+    case i of
+      1: writeln('one');
+      2, 3: writeln('two or three');
+      4..5: writeln('four to five')
+    else
+      writeln('many')
+    end;
+  c := 'q';
+  case c of
+    'a'..'m': writeln('first half');
+    'n'..'z': writeln('second half')
+  end;
+  case 1 < 2 of
+    true: writeln('yes');
+    false: writeln('no')
+  end
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "one\ntwo or three\ntwo or three\nfour to five\nfour to five\nmany\nsecond half\nyes\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_CaseWithoutMatchingLabel(t *testing.T) {
+	input := `program test;
+var i: integer;
+begin
+  i := 9;
+  case i of
+    1: writeln('one')
+  end
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected unmatched case error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "No case label matches") {
+		t.Fatalf("expected 'No case label matches' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_CaseLabelTypeMismatch(t *testing.T) {
+	input := `program test;
+var i: integer;
+begin
+  case i of
+    'a': writeln('a')
+  end
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected case label type error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "Type mismatch in case label") {
+		t.Fatalf("expected 'Type mismatch in case label' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_Constants(t *testing.T) {
+	input := `program test;
+const
+  Max = 3;
+  Greeting = 'Hello';
+  Scale = 2.5;
+var i: integer;
+procedure show;
+const Greeting = 'Hi';
+begin
+  writeln(Greeting)
+end;
+begin
+  for i := 1 to Max do
+    case i of
+      1..Max - 1: writeln(i * Max);
+      Max: writeln(Greeting)
+    end;
+  writeln(Scale * 2);
+  show
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "3\n6\nHello\n 5.0000000000E+00\nHi\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ConstantCannotBePassedAsVarParameter(t *testing.T) {
+	input := `program test;
+const Max = 3;
+procedure inc(var n: integer);
+begin
+  n := n + 1
+end;
+begin
+  inc(Max)
+end.`
+
+	_, err := runProgram(input)
+	if err == nil {
+		t.Fatalf("expected var parameter error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "must be a variable") {
+		t.Fatalf("expected 'must be a variable' error, got: %v", err)
+	}
+}
+
+func TestInterpreter_EnumeratedTypes(t *testing.T) {
+	input := `program test;
+type
+  Color = (Red, Green, Blue);
+var
+  c: Color;
+  d: (North, East, South, West);
+begin
+  writeln(c);
+  c := succ(Red);
+  writeln(c);
+  writeln(ord(Blue));
+  writeln(pred(Blue) = Green);
+  writeln(Red < Blue);
+  for c := Red to Blue do
+    case c of
+      Red: writeln('stop');
+      Green, Blue: writeln(ord(c))
+    end;
+  for d := West downto South do
+    writeln(d)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "red\ngreen\n2\ntrue\ntrue\nstop\n1\n2\nwest\nsouth\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_SubrangesAndAliases(t *testing.T) {
+	input := `program test;
+const Max = 5;
+type
+  Index = 1..Max;
+  Count = integer;
+  Letter = 'a'..'z';
+var
+  i: Index;
+  n: Count;
+  l: Letter;
+function double(x: Index): Count;
+begin
+  double := x * 2
+end;
+begin
+  writeln(i);
+  writeln(l);
+  for i := 1 to Max do
+    n := n + double(i);
+  writeln(n);
+  l := succ('y');
+  writeln(l)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\na\n30\nz\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_OrdinalRangeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"subrange assignment", "i := 11", "Value out of range"},
+		{"subrange value parameter", "show(0)", "Value out of range"},
+		{"for loop beyond subrange", "for i := 5 to 12 do writeln(i)", "Value out of range"},
+		{"succ of last enumeration value", "c := succ(Blue)", "succ(blue) is out of range"},
+		{"pred of false", "writeln(pred(false))", "pred(false) is out of range"},
+		{"ord of real", "writeln(ord(1.5))", "Argument of 'ord' must be ordinal"},
+		{"unknown type", "show2(1)", "Unknown type 'missing'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+type
+  Color = (Red, Green, Blue);
+  Small = 1..10;
+var
+  i: Small;
+  c: Color;
+procedure show(n: Small);
+begin
+  writeln(n)
+end;
+procedure show2(n: Missing);
+begin
+end;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Arrays(t *testing.T) {
+	input := `program test;
+type
+  Color = (Red, Green, Blue);
+var
+  a: array[1..5] of integer;
+  m: array[1..3, 1..3] of integer;
+  counts: array['a'..'e'] of integer;
+  names: array[Color] of char;
+  seen: array[boolean] of integer;
+  i, j, sum: integer;
+  c: char;
+begin
+  for i := 1 to 5 do
+    a[i] := i * i;
+  sum := 0;
+  for i := 1 to 5 do
+    sum := sum + a[i];
+  writeln(sum);
+  for i := 1 to 3 do
+    for j := 1 to 3 do
+      m[i, j] := i * 10 + j;
+  writeln(m[2][3]);
+  writeln(m[3, 1]);
+  for c := 'a' to 'e' do
+    counts[c] := ord(c) - ord('a');
+  writeln(counts['d']);
+  names[Green] := 'g';
+  writeln(names[Green]);
+  seen[3 > 2] := seen[true] + 1;
+  writeln(seen[true])
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "55\n23\n31\n3\ng\n1\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ArraysHaveValueSemantics(t *testing.T) {
+	input := `program test;
+type Vector = array[1..3] of integer;
+var a, b: Vector;
+procedure clear(v: Vector);
+begin
+  v[1] := 0
+end;
+procedure bump(var n: integer);
+begin
+  n := n + 100
+end;
+begin
+  a[1] := 1;
+  b := a;
+  a[1] := 2;
+  writeln(b[1]);
+  clear(a);
+  writeln(a[1]);
+  bump(a[1]);
+  writeln(a[1])
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\n2\n102\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_PackedArrayOfChar(t *testing.T) {
+	input := `program test;
+var name: packed array[1..5] of char;
+begin
+  name := 'hello';
+  writeln(name);
+  name[1] := 'j';
+  writeln(name);
+  writeln(name = 'jello');
+  writeln(name < 'world')
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "hello\njello\ntrue\ntrue\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_ArrayErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"index above bounds", "i := 11; a[i] := 1", "Index 11 is outside the bounds 1..10 of 'a'"},
+		{"index below bounds", "writeln(a[0])", "Index 0 is outside the bounds 1..10 of 'a'"},
+		{"char index out of range", "writeln(letters['z'])", "Index 'z' is outside the bounds 'a'..'e' of 'letters'"},
+		{"index type mismatch", "writeln(a['x'])", "Type mismatch in array index"},
+		{"indexing a scalar", "i[1] := 2", "Cannot index 'i'"},
+		{"string too long", "word := 'toolong'", "String length does not match"},
+		{"assigning different array types", "a := b", "Type mismatch in array assignment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var
+  a: array[1..10] of integer;
+  b: array[1..10] of integer;
+  letters: array['a'..'e'] of integer;
+  word: packed array[1..4] of char;
+  i: integer;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Records(t *testing.T) {
+	input := `program test;
+type
+  Point = record
+    x, y: integer
+  end;
+  Segment = record
+    ends: array[1..2] of Point;
+    name: char
+  end;
+var
+  p, q: Point;
+  s: Segment;
+procedure move(var pt: Point; dx: integer);
+begin
+  pt.x := pt.x + dx
+end;
+begin
+  p.x := 1;
+  p.y := 2;
+  q := p;
+  p.x := 5;
+  writeln(q.x);
+  move(q, 10);
+  writeln(q.x);
+  s.ends[2] := p;
+  s.ends[2].y := 7;
+  writeln(p.y);
+  writeln(s.ends[2].x + s.ends[2].y);
+  with p do
+  begin
+    x := 3;
+    y := x + 1
+  end;
+  writeln(p.y);
+  with s, ends[1] do
+  begin
+    name := 'a';
+    x := 42
+  end;
+  writeln(s.name);
+  writeln(s.ends[1].x)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\n11\n2\n12\n4\na\n42\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_VariantRecords(t *testing.T) {
+	input := `program test;
+type
+  Kind = (Circle, Square);
+  Shape = record
+    name: char;
+    case kind: Kind of
+      Circle: (radius: integer);
+      Square: (side: integer)
+  end;
+var s: Shape;
+begin
+  s.kind := Circle;
+  s.radius := 3;
+  writeln(s.radius);
+  s.kind := Square;
+  s.side := 4;
+  with s do
+    writeln(side * side)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "3\n16\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_RecordErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"inactive tagged variant", "s.kind := Square; writeln(s.radius)", "Field 'radius' belongs to an inactive variant"},
+		{"inactive variant through with", "s.kind := Circle; with s do writeln(side)", "Field 'side' belongs to an inactive variant"},
+		{"inactive untagged variant", "u.i := 65; writeln(u.c)", "Field 'c' belongs to an inactive variant"},
+		{"unknown field", "s.depth := 1", "Unknown field 'depth'"},
+		{"field of non-record", "writeln(n.x)", "Cannot select field 'x' of 'n'"},
+		{"with on non-record", "with n do writeln(1)", "'with' requires a record variable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+type
+  Kind = (Circle, Square);
+  Shape = record
+    case kind: Kind of
+      Circle: (radius: integer);
+      Square: (side: integer)
+  end;
+var
+  s: Shape;
+  u: record
+    case integer of
+      1: (i: integer);
+      2: (c: char)
+  end;
+  n: integer;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Sets(t *testing.T) {
+	input := `program test;
+type
+  Day = (Mon, Tue, Wed, Thu, Fri, Sat, Sun);
+  Digits = set of 0..9;
+var
+  vowels, letters: set of char;
+  weekend, days: set of Day;
+  odd, small: Digits;
+  c: char;
+  n: integer;
+begin
+  vowels := ['a', 'e', 'i', 'o', 'u'];
+  letters := ['a'..'z'];
+  c := 'e';
+  writeln(c in vowels);
+  writeln('b' in vowels);
+  n := 0;
+  for c := 'a' to 'z' do
+    if c in letters - vowels then
+      n := n + 1;
+  writeln(n);
+  weekend := [Sat, Sun];
+  days := [Mon..Fri] + weekend;
+  writeln(days = [Mon..Sun]);
+  writeln(weekend <= days);
+  writeln(days >= [Wed, Mon]);
+  writeln(weekend * [Fri, Sat]);
+  odd := [1, 3, 5, 7, 9];
+  small := [0..4];
+  writeln(odd * small);
+  writeln(small - odd <> []);
+  writeln(not (9 in small));
+  small := [];
+  writeln(small)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "true\nfalse\n21\ntrue\ntrue\ntrue\n[sat]\n[1, 3]\ntrue\ntrue\n[]\n"
+	if output != expected {
+		t.Fatalf("output wrong. expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestInterpreter_SetErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"element outside base type", "digits := [5, 12]", "Set element out of range"},
+		{"element outside set range", "writeln([300])", "Set element out of range"},
+		{"mixed constructor", "writeln([1, 'a'])", "Type mismatch in set constructor"},
+		{"mismatched operands", "writeln(digits + ['a'])", "Type mismatch in set operation '+'"},
+		{"ordering comparison", "writeln(digits < [1])", "Operator '<' cannot be applied to sets"},
+		{"membership type mismatch", "writeln('a' in digits)", "Type mismatch in 'in'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var digits: set of 0..9;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_LinkedList(t *testing.T) {
+	input := `program test;
+type
+  PNode = ^Node;
+  Node = record
+    val: integer;
+    next: PNode
+  end;
+var
+  head, p: PNode;
+  n: integer;
+begin
+  head := nil;
+  for n := 1 to 3 do
+  begin
+    new(p);
+    p^.val := n * 10;
+    p^.next := head;
+    head := p
+  end;
+  p := head;
+  while p <> nil do
+  begin
+    writeln(p^.val);
+    p := p^.next
+  end;
+  writeln(head^.next^.next^.val);
+  writeln(head^.next^.next^.next = nil)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "30\n20\n10\n10\ntrue\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_BinaryTree(t *testing.T) {
+	input := `program test;
+type
+  Tree = ^TreeNode;
+  TreeNode = record
+    key: integer;
+    left, right: Tree
+  end;
+var
+  root: Tree;
+
+procedure insert(var t: Tree; k: integer);
+begin
+  if t = nil then
+  begin
+    new(t);
+    t^.key := k;
+    t^.left := nil;
+    t^.right := nil
+  end
+  else if k < t^.key then
+    insert(t^.left, k)
+  else
+    insert(t^.right, k)
+end;
+
+procedure inorder(t: Tree);
+begin
+  if t <> nil then
+  begin
+    inorder(t^.left);
+    writeln(t^.key);
+    inorder(t^.right)
+  end
+end;
+
+begin
+  root := nil;
+  insert(root, 5);
+  insert(root, 2);
+  insert(root, 8);
+  insert(root, 1);
+  inorder(root)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1\n2\n5\n8\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_PointerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"nil dereference", "p := nil; writeln(p^)", "Dereference of nil pointer 'p'"},
+		{"nil field chain", "new(r); writeln(r^.next^.val)", "Dereference of nil pointer 'r^.next'"},
+		{"disposed dereference", "new(p); q := p; dispose(p); writeln(q^)", "Dereference of disposed pointer 'q'"},
+		{"double dispose", "new(p); dispose(p); dispose(p)", "Pointer 'p' was already disposed"},
+		{"dispose nil", "p := nil; dispose(p)", "Dispose of nil pointer 'p'"},
+		{"new on non-pointer", "new(n)", "'new' requires one pointer variable"},
+		{"dereference non-pointer", "writeln(n^)", "Cannot dereference 'n'"},
+		{"mismatched pointer assignment", "new(r); p := r", "Type mismatch in pointer assignment"},
+		{"ordered comparison", "new(p); q := p; writeln(p < q)", "Pointers cannot be compared with '<'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+type
+  PNode = ^Node;
+  Node = record
+    val: integer;
+    next: PNode
+  end;
+var
+  p, q: ^integer;
+  r: PNode;
+  n: integer;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_TextFiles(t *testing.T) {
+	input := `program test(output, numbers, totals);
+var
+  numbers, totals, scratch: text;
+  n, sum: integer;
+  c: char;
+begin
+  reset(numbers);
+  rewrite(totals);
+  while not eof(numbers) do
+  begin
+    sum := 0;
+    while not eoln(numbers) do
+    begin
+      read(numbers, n);
+      sum := sum + n
+    end;
+    readln(numbers);
+    writeln(totals, 'sum ', sum)
+  end;
+  close(totals);
+
+  rewrite(scratch);
+  write(scratch, 'ab');
+  writeln(scratch);
+  write(scratch, 'c');
+  reset(scratch);
+  while not eof(scratch) do
+  begin
+    read(scratch, c);
+    writeln(output, '[', c, ']')
+  end
+end.`
+
+	files := MemoryFileSystem{"numbers": "1 2 3\n10 20\n\n"}
+	output, err := runProgramWith(input, func(i *Interpreter) { i.SetFileSystem(files) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "sum 6\nsum 30\nsum 0\n"; files["totals"] != expected {
+		t.Fatalf("expected totals %q, got %q", expected, files["totals"])
+	}
+	if expected := "[a]\n[b]\n[ ]\n[c]\n[ ]\n"; output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_ReadTypedValuesFromText(t *testing.T) {
+	input := `program test(data);
+var
+  data: text;
+  i: integer;
+  x: real;
+  s: string;
+  name: packed array[1..5] of char;
+begin
+  assign(data, 'values.txt');
+  reset(data);
+  read(data, i, x);
+  readln(data, s);
+  readln(data, name);
+  readln(data, x);
+  writeln(i);
+  writeln(x);
+  writeln(s);
+  writeln(name, '|')
+end.`
+
+	files := MemoryFileSystem{"values.txt": "  -42\n 2.5 rest of line\nab\n1e3\n"}
+	output, err := runProgramWith(input, func(i *Interpreter) { i.SetFileSystem(files) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "-42\n 1.0000000000E+03\n rest of line\nab   |\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_TypedFiles(t *testing.T) {
+	input := `program test(points);
+type
+  Point = record x, y: integer; tag: char end;
+var
+  points: file of Point;
+  squares: file of integer;
+  p: Point;
+  n: integer;
+begin
+  rewrite(points);
+  p.x := 1; p.y := 0 - 2; p.tag := 'a';
+  write(points, p);
+  p.x := 3; p.tag := 'b';
+  write(points, p);
+  close(points);
+
+  reset(points);
+  while not eof(points) do
+  begin
+    read(points, p);
+    writeln(p.x + p.y);
+    writeln(p.tag)
+  end;
+
+  rewrite(squares);
+  for n := 1 to 3 do
+    write(squares, n * n);
+  reset(squares);
+  while not eof(squares) do
+  begin
+    read(squares, n);
+    writeln(n)
+  end
+end.`
+
+	files := MemoryFileSystem{}
+	output, err := runProgramWith(input, func(i *Interpreter) { i.SetFileSystem(files) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "-1\na\n1\nb\n1\n4\n9\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+	if len(files["points"]) != 2*(8+8+1) {
+		t.Fatalf("expected two 17-byte components in points, got %d bytes", len(files["points"]))
+	}
+}
+
+func TestInterpreter_FileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"read before reset", "read(f, n)", "File 'f' is not open for reading"},
+		{"write before rewrite", "writeln(f, n)", "File 'f' is not open for writing"},
+		{"read past end", "reset(data); readln(data); readln(data); read(data, n)", "Read past end of file 'data'"},
+		{"invalid integer", "reset(data); readln(data); readln(data, n)", "Invalid integer input 'abc'"},
+		{"missing external file", "assign(f, 'missing.txt'); reset(f)", "Cannot open file 'missing.txt' for reading"},
+		{"file assignment", "f := data", "Files cannot be assigned"},
+		{"readln on typed file", "rewrite(g); reset(g); readln(g, n)", "'readln' requires a text file"},
+		{"wrong component type", "rewrite(g); write(g, 'x')", "Type mismatch"},
+		{"reset of non-file", "reset(n)", "'reset' requires one file variable"},
+		{"eoln on typed file", "rewrite(g); reset(g); writeln(eoln(g))", "'eoln' requires a text file"},
+		{"reset of unwritten internal file", "reset(f)", "File 'f' is undefined"},
+		{"eoln at end of file", "reset(data); readln(data); readln(data); writeln(eoln(data))", "eoln(data) at end of file"},
+		{"eoln at end of internal file", "rewrite(f); reset(f); writeln(eoln(f))", "eoln(f) at end of file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test(data);
+var
+  data, f: text;
+  g: file of integer;
+  n: integer;
+begin
+  ` + tt.body + `
+end.`
+
+			files := MemoryFileSystem{"data": "1\nabc\n"}
+			_, err := runProgramWith(input, func(i *Interpreter) { i.SetFileSystem(files) })
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ProgramParameterMustBeFile(t *testing.T) {
+	input := `program test(input, output, n);
+var n: integer;
+begin
+  writeln(output, 1)
+end.`
+
+	_, err := runProgram(input)
+	if err == nil || !strings.Contains(err.Error(), "Program parameter 'n' must be a file variable") {
+		t.Fatalf("expected program parameter error, got: %v", err)
+	}
+}
+
+func TestInterpreter_ReadFromInput(t *testing.T) {
+	input := `program test(input, output);
+var
+  a, b, count: integer;
+  x: real;
+  c: char;
+  name: string;
+begin
+  readln(name);
+  read(a, b);
+  read(x);
+  writeln(name, ': ', a + b);
+  writeln(x * 2);
+  readln;
+  count := 0;
+  while not eoln do
+  begin
+    read(c);
+    if c <> ' ' then count := count + 1
+  end;
+  readln(input);
+  writeln(count);
+  read(c);
+  writeln('[', c, ']');
+  writeln(eof)
+end.`
+
+	output, err := runProgramWith(input, func(i *Interpreter) {
+		i.SetInput(strings.NewReader("Ada Lovelace\n  12\n\t-5   0.25 ignored\na b c\n\n"))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Ada Lovelace: 7\n 5.0000000000E-01\n3\n[ ]\ntrue\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_UnterminatedLastLine(t *testing.T) {
+	input := `program test(input, output);
+var
+  count: integer;
+  c: char;
+begin
+  count := 0;
+  while not eoln do
+  begin
+    read(c);
+    count := count + 1
+  end;
+  writeln(count, ' ', eof);
+  read(c);
+  writeln('[', c, '] ', eof);
+  writeln(eoln)
+end.`
+
+	output, err := runProgramWith(input, func(i *Interpreter) { i.SetInput(strings.NewReader("abc")) })
+	if expected := "3 false\n[ ] true\n"; output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+	if err == nil || !strings.Contains(err.Error(), "eoln(input) at end of file") {
+		t.Fatalf("expected eoln at end of file error, got: %v", err)
+	}
+}
+
+func TestInterpreter_InputErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		input    string
+		expected []string
+	}{
+		{"letters for integer", "read(n)", "  abc def\n", []string{"Invalid integer input 'abc'", "'abc' on line 1 of 'input'"}},
+		{"bad real on later line", "readln(n); read(x)", "1\n\n x3\n", []string{"Invalid real input 'x3'", "line 3", "Enter a real number"}},
+		{"sign without digits", "read(n)", "- 5\n", []string{"Invalid integer input '-'"}},
+		{"number too large", "read(n)", "99999999999999999999\n", []string{"Invalid integer input '99999999999999999999'"}},
+		{"past end of input", "read(n, n)", "1\n", []string{"Read past end of file 'input'", "Test eof(input)"}},
+		{"out of range", "read(small)", "11\n", []string{"Value out of range"}},
+		{"unreadable type", "read(b)", "true\n", []string{"Cannot read a value of type boolean"}},
+		{"expression target", "read(n + 1)", "1\n", []string{"Argument of 'read' must be a variable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var
+  n: integer;
+  x: real;
+  small: 1..10;
+  b: boolean;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgramWith(input, func(i *Interpreter) { i.SetInput(strings.NewReader(tt.input)) })
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected %q in error, got: %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpreter_InputIsEmptyByDefault(t *testing.T) {
+	input := `program test;
+var n: integer;
+begin
+  writeln(eof);
+  read(n)
+end.`
+
+	output, err := runProgram(input)
+	if output != "true\n" {
+		t.Fatalf("expected eof to be true, got %q", output)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Read past end of file 'input'") {
+		t.Fatalf("expected read past end error, got: %v", err)
+	}
+}
+
+func TestInterpreter_WriteFormatting(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"writeln('x = ', n, '!')", "x = 42!\n"},
+		{"write('a'); write('b', 'c'); writeln", "abc\n"},
+		{"writeln; writeln(1)", "\n1\n"},
+		{"writeln(n:5, '|', n:1, '|', 0 - n:4)", "   42|42| -42\n"},
+		{"writeln(x)", " 1.2345000000E+01\n"},
+		{"writeln(0 - x)", "-1.2345000000E+01\n"},
+		{"writeln(x:12)", " 1.23450E+01\n"},
+		{"writeln(x:1)", " 1.2E+01\n"},
+		{"writeln(x:8:2, '|', x:0:3, '|', 2:5:1)", "   12.35|12.345|  2.0\n"},
+		{"writeln(0.000123)", " 1.2300000000E-04\n"},
+		{"writeln(b, '|', b:6, '|', b:2)", "true|  true|tr\n"},
+		{"writeln('abc':5, '|', 'abc':2, '|', 'z':3)", "  abc|ab|  z\n"},
+		{"writeln(s:7, '|')", "  hello|\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			input := `program test;
+var
+  n: integer;
+  x: real;
+  b: boolean;
+  s: packed array[1..5] of char;
+begin
+  n := 42;
+  x := 12.345;
+  b := true;
+  s := 'hello';
+  ` + tt.body + `
+end.`
+
+			output, err := runProgram(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestInterpreter_WriteFormattingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"decimals for boolean", "writeln(true:5:2)", "Decimal places can only be given for real values"},
+		{"real width", "writeln(1:2.5)", "Field width must be a non-negative integer"},
+		{"negative decimals", "writeln(1.5:5:(0 - 1))", "Number of decimal places must be a non-negative integer"},
+		{"width on typed file", "rewrite(g); write(g, 1:3)", "Field widths can only be used with text files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var g: file of integer;
+begin
+  ` + tt.body + `
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_BuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"abs(0 - 7)", "7"},
+		{"abs(0 - 2.5):0:1", "2.5"},
+		{"sqr(9)", "81"},
+		{"sqr(1.5):0:2", "2.25"},
+		{"sqrt(16)", " 4.0000000000E+00"},
+		{"sqrt(2):0:4", "1.4142"},
+		{"sin(0):0:1", "0.0"},
+		{"cos(0):0:1", "1.0"},
+		{"arctan(1) * 4:0:5", "3.14159"},
+		{"exp(1):0:4", "2.7183"},
+		{"ln(exp(2)):0:1", "2.0"},
+		{"trunc(3.7)", "3"},
+		{"trunc(0 - 3.7)", "-3"},
+		{"round(3.5)", "4"},
+		{"round(0 - 3.5)", "-4"},
+		{"round(2.4)", "2"},
+		{"ord('A')", "65"},
+		{"chr(65)", "A"},
+		{"chr(ord('a') + 1)", "b"},
+		{"succ(5)", "6"},
+		{"pred('b')", "a"},
+		{"odd(3)", "true"},
+		{"odd(0 - 4)", "false"},
+		{"sqr(sqrt(9)) = 9", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			input := `program test;
+begin
+  writeln(` + tt.expr + `)
+end.`
+
+			output, err := runProgram(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected+"\n" {
+				t.Fatalf("expected %q, got %q", tt.expected+"\n", output)
+			}
+		})
+	}
+}
+
+func TestInterpreter_BuiltinFunctionErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"sqrt(0 - 1)", []string{"Square root of negative number -1", "abs(x)"}},
+		{"ln(0)", []string{"Logarithm of non-positive number 0"}},
+		{"chr(300)", []string{"chr(300) is out of range", "between 0 and 255"}},
+		{"exp(1000)", []string{"exp(1000) is too large"}},
+		{"trunc(exp(100))", []string{"is out of the integer range"}},
+		{"sqrt('x')", []string{"Argument of 'sqrt' must be integer or real", "The argument has type char."}},
+		{"abs(true)", []string{"Argument of 'abs' must be integer or real"}},
+		{"round('a')", []string{"Argument of 'round' must be real"}},
+		{"chr(1.5)", []string{"Argument of 'chr' must be integer"}},
+		{"odd(1.5)", []string{"Argument of 'odd' must be integer"}},
+		{"sin(1, 2)", []string{"Wrong number of arguments to 'sin'", "expects 1 argument but was given 2"}},
+		{"eof(1, 2)", []string{"Wrong number of arguments to 'eof'", "expects at most 1 argument"}},
+		{"eof(1)", []string{"'eof' requires one file variable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			input := `program test;
+begin
+  writeln(` + tt.expr + `)
+end.`
+
+			_, err := runProgram(input)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected %q in error, got: %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpreter_UserFunctionShadowsBuiltin(t *testing.T) {
+	input := `program test;
+function sqr(x: integer): integer;
+begin
+  sqr := x + 1
+end;
+begin
+  writeln(sqr(3))
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "4\n" {
+		t.Fatalf("expected %q, got %q", "4\n", output)
+	}
+}
+
+func TestInterpreter_UnaryOperators(t *testing.T) {
+	input := `program test;
+const Limit = -10;
+var
+  a, b: integer;
+  x: real;
+  small: -5..5;
+begin
+  a := 3;
+  b := 4;
+  x := -2.5;
+  small := -5;
+  writeln(-a);
+  writeln(-(a + b));
+  writeln(-a * b);
+  writeln(-a + b);
+  writeln(+a);
+  writeln(a - (-b) = a + b);
+  writeln(-x:0:1);
+  writeln(Limit);
+  writeln(small);
+  writeln(not (a < -b));
+  case -a of
+    -3: writeln('minus three')
+  end
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "-3\n-7\n-12\n1\n3\ntrue\n2.5\n-10\n-5\ntrue\nminus three\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_UnaryOperatorTypeErrors(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"writeln(-true)", "Type mismatch in unary '-'"},
+		{"writeln(+'a')", "Type mismatch in unary '+'"},
+		{"writeln(not 1)", "Type mismatch in 'not'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			_, err := runProgram("program test;\nbegin\n  " + tt.body + "\nend.")
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_IntegerDivision(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"7 div 2", "3"},
+		{"-7 div 2", "-3"},
+		{"7 div (-2)", "-3"},
+		{"7 mod 3", "1"},
+		{"(-7) mod 3", "2"},
+		{"-7 mod 3", "-1"},
+		{"6 mod 3", "0"},
+		{"7 / 2:0:1", "3.5"},
+		{"20 / 4:0:1", "5.0"},
+		{"2 + 3 * 4 div 5 mod 2", "2"},
+		{"maxint - 1 + 1 = maxint", "true"},
+		{"-maxint + maxint", "0"},
+		{"sqr(3037000499)", "9223372030926249001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			output, err := runProgram("program test;\nbegin\n  writeln(" + tt.expr + ")\nend.")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected+"\n" {
+				t.Fatalf("expected %q, got %q", tt.expected+"\n", output)
+			}
+		})
+	}
+}
+
+func TestInterpreter_IntegerDivisionErrors(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"x := 7 div 0", "Division by zero"},
+		{"x := 7 mod 0", "Non-positive divisor in 7 mod 0"},
+		{"x := 7 mod (-2)", "Non-positive divisor in 7 mod -2"},
+		{"x := 7.5 div 2", "Type mismatch in 'div'"},
+		{"x := 7 mod 2.0", "Type mismatch in 'mod'"},
+		{"x := 7 / 2", "Cannot assign a real value to an integer"},
+		{"x := maxint; x := x + 1", "Integer overflow in 9223372036854775807 + 1"},
+		{"x := -maxint; x := x - 1", "Integer overflow in -9223372036854775807 - 1"},
+		{"x := maxint; x := x * 2", "Integer overflow in 9223372036854775807 * 2"},
+		{"x := maxint; x := sqr(x)", "Integer overflow"},
+		{"x := succ(maxint)", "succ(9223372036854775807) is out of range"},
+		{"x := pred(-maxint)", "pred(-9223372036854775807) is out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			_, err := runProgram("program test;\nvar x: integer;\nbegin\n  " + tt.body + "\nend.")
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ProceduralParameters(t *testing.T) {
+	input := `program test;
+var total: integer;
+
+function integrate(function f(x: real): real; a, b: real; n: integer): real;
+var k: integer; h, sum: real;
+begin
+  h := (b - a) / n;
+  sum := 0;
+  for k := 0 to n - 1 do
+    sum := sum + f(a + (k + 0.5) * h) * h;
+  integrate := sum
+end;
+
+function square(x: real): real;
+begin
+  square := x * x
+end;
+
+procedure each(n: integer; procedure visit(k: integer));
+var k: integer;
+begin
+  for k := 1 to n do visit(k)
+end;
+
+procedure sumTo(n: integer);
+var acc: integer;
+  procedure add(k: integer);
+  begin
+    acc := acc + k
+  end;
+begin
+  acc := 0;
+  each(n, add);
+  total := acc
+end;
+
+function twice(function g: integer): integer;
+begin
+  twice := g + g
+end;
+
+function three: integer;
+begin
+  three := 3
+end;
+
+function apply(function h(function g: integer): integer): integer;
+begin
+  apply := h(three)
+end;
+
+begin
+  writeln(integrate(square, 0, 3, 1000):0:3);
+  sumTo(10);
+  writeln(total);
+  writeln(apply(twice))
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "9.000\n55\n6\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_ProceduralParameterErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		expected string
+	}{
+		{"variable", "run(x)", "Argument for procedural parameter 'p' of 'run' must be a procedure"},
+		{"function for procedure", "run(f)", "Argument for procedural parameter 'p' of 'run' must be a procedure"},
+		{"different parameters", "run(q)", "Type mismatch in procedural parameter 'p' of 'run'"},
+		{"required procedure", "run(dispose)", "Required procedure or function 'dispose' cannot be passed as a parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var x: integer;
+procedure run(procedure p(n: integer));
+begin
+  p(1)
+end;
+function f(n: integer): integer;
+begin
+  f := n
+end;
+procedure q(var n: integer);
+begin
+end;
+begin
+  ` + tt.call + `
+end.`
+			_, err := runProgram(input)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ConformantArrays(t *testing.T) {
+	input := `program test;
+type
+  day = (mon, tue, wed, thu, fri);
+  small = array[1..4] of integer;
+  big = array[0..6] of integer;
+  hours = array[mon..fri] of integer;
+  grid = array[1..2, 1..3] of integer;
+var
+  a: small; b: big; h: hours; g: grid; word: packed array[1..5] of char;
+  k, j: integer; d: day;
+
+procedure sort(var a: array[lo..hi: integer] of integer);
+var i, j, t: integer;
+begin
+  for i := lo to hi - 1 do
+    for j := i + 1 to hi do
+      if a[j] < a[i] then
+        begin t := a[i]; a[i] := a[j]; a[j] := t end
+end;
+
+procedure show(a: array[lo..hi: integer] of integer);
+var i: integer;
+begin
+  for i := lo to hi do write(a[i]:2);
+  writeln(' [', lo:0, '..', hi:0, ']')
+end;
+
+function busiest(a: array[first..last: day] of integer): day;
+var d, best: day;
+begin
+  best := first;
+  for d := first to last do
+    if a[d] > a[best] then best := d;
+  busiest := best
+end;
+
+function total(m: array[r1..r2: integer; c1..c2: integer] of integer): integer;
+var i, j, s: integer;
+begin
+  s := 0;
+  for i := r1 to r2 do
+    for j := c1 to c2 do s := s + m[i, j];
+  total := s
+end;
+
+procedure reverse(s: packed array[lo..hi: integer] of char);
+var i: integer;
+begin
+  for i := hi downto lo do write(s[i]);
+  writeln
+end;
+
+begin
+  for k := 1 to 4 do a[k] := 5 - k;
+  for k := 0 to 6 do b[k] := (k * 3) mod 7;
+  sort(a);
+  sort(b);
+  show(a);
+  show(b);
+  for d := mon to fri do h[d] := (ord(d) * 2) mod 5;
+  writeln(ord(busiest(h)));
+  for k := 1 to 2 do
+    for j := 1 to 3 do g[k, j] := k * j;
+  writeln(total(g));
+  sort(g[2]);
+  word := 'hello';
+  reverse(word);
+  reverse('abc')
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := " 1 2 3 4 [1..4]\n 0 1 2 3 4 5 6 [0..6]\n2\n18\nolleh\ncba\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_ConformantArrayErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		expected string
+	}{
+		{"element type", "sum(r, r)", "Type mismatch in argument 'a' of 'sum'"},
+		{"index outside schema", "sum(z, z)", "Type mismatch in argument 'a' of 'sum'"},
+		{"different types for one schema", "sum(x, y)", "Type mismatch in argument 'b' of 'sum'"},
+		{"not an array", "sum(n, n)", "Type mismatch in argument 'a' of 'sum'"},
+		{"assignment to bound", "shrink(x)", "Cannot assign to constant 'hi'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+type index = 1..10;
+var x: array[1..3] of integer; y: array[1..4] of integer; z: array[0..3] of integer;
+    r: array[1..3] of real; n: integer;
+procedure sum(a, b: array[lo..hi: index] of integer);
+begin
+end;
+procedure shrink(var a: array[lo..hi: integer] of integer);
+begin
+  hi := hi - 1
+end;
+begin
+  ` + tt.call + `
+end.`
+			_, err := runProgram(input)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ForwardDeclarations(t *testing.T) {
+	input := `program test;
+var s: packed array[1..10] of char; pos: integer;
+function expression: integer; forward;
+
+function factor: integer;
+begin
+  if s[pos] = '(' then
+    begin
+      pos := pos + 1;
+      factor := expression;
+      pos := pos + 1
+    end
+  else
+    begin
+      factor := ord(s[pos]) - ord('0');
+      pos := pos + 1
+    end
+end;
+
+function term: integer;
+var value: integer;
+begin
+  value := factor;
+  while s[pos] = '*' do
+    begin
+      pos := pos + 1;
+      value := value * factor
+    end;
+  term := value
+end;
+
+function expression;
+var value: integer;
+begin
+  value := term;
+  while s[pos] = '+' do
+    begin
+      pos := pos + 1;
+      value := value + term
+    end;
+  expression := value
+end;
+
+procedure countdown(n: integer); forward;
+procedure step(n: integer);
+begin
+  write(n, ' ');
+  countdown(n - 1)
+end;
+procedure countdown(n: integer);
+begin
+  if n > 0 then step(n) else writeln('liftoff')
+end;
+
+begin
+  s := '2*(3+4)+1.';
+  pos := 1;
+  writeln(expression);
+  countdown(3)
+end.`
+
+	output, err := runProgram(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "15\n3 2 1 liftoff\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_ForwardNeverCompleted(t *testing.T) {
+	input := `program test;
+procedure p; forward;
+begin
+  p
+end.`
+
+	_, err := runProgram(input)
+	if err == nil || !strings.Contains(err.Error(), "Forward declaration of 'p' is never completed") {
+		t.Fatalf("expected incomplete forward declaration error, got: %v", err)
+	}
+}
+
+func TestInterpreter_Goto(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"backward loop", `program test;
+label 10;
+var i: integer;
+begin
+  i := 0;
+  10: i := i + 1;
+  write(i);
+  if i < 3 then goto 10;
+  writeln
+end.`, "123\n"},
+		{"forward out of nested loops", `program test;
+label 99;
+var i, j: integer;
+begin
+  for i := 1 to 3 do
+    for j := 1 to 3 do
+      begin
+        if i * j = 4 then goto 99;
+        write(i, j, ' ')
+      end;
+  99: writeln('i=', i, ' j=', j)
+end.`, "11 12 13 21 i=2 j=2\n"},
+		{"restart the labeled statement", `program test;
+label 5;
+var n: integer;
+begin
+  n := 0;
+  if true then
+    5: begin
+      n := n + 1;
+      if n < 4 then goto 5
+    end;
+  writeln(n)
+end.`, "4\n"},
+		{"within a repeat body", `program test;
+label 1;
+var n: integer;
+begin
+  n := 0;
+  repeat
+    n := n + 1;
+    if odd(n) then goto 1;
+    write(n);
+    1:
+  until n = 6;
+  writeln
+end.`, "246\n"},
+		{"out of nested procedures", `program test;
+label 10;
+procedure inner;
+begin
+  writeln('inner');
+  goto 10;
+  writeln('not reached')
+end;
+procedure outer;
+begin
+  inner;
+  writeln('not reached')
+end;
+begin
+  outer;
+  writeln('not reached');
+  10: writeln('done')
+end.`, "inner\ndone\n"},
+		{"out of a function in an expression", `program test;
+label 10;
+var x: integer;
+function fail(n: integer): integer;
+begin
+  if n < 0 then goto 10;
+  fail := n
+end;
+begin
+  x := fail(1) + fail(-1);
+  writeln('not reached');
+  10: writeln('x=', x)
+end.`, "x=0\n"},
+		{"to the right activation of a recursive procedure", `program test;
+procedure count(n: integer);
+label 1;
+  procedure stop;
+  begin
+    goto 1
+  end;
+begin
+  if n = 3 then stop;
+  if n < 5 then count(n + 1);
+  write(n);
+  1:
+end;
+begin
+  count(1);
+  writeln
+end.`, "21\n"},
+		{"out of a with statement", `program test;
+label 10;
+type point = record x, y: integer end;
+var p: point;
+begin
+  p.x := 1;
+  with p do
+    begin
+      x := x + 1;
+      goto 10
+    end;
+  10: writeln(p.x)
+end.`, "2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runProgram(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+		text   string
+	}{
+		{"division by zero", `program test;
+var x: integer;
+begin
+  x := 1;
+  x := 7 div (x - 1)
+end.`, 5, 8, "7 div (x - 1)"},
+		{"index out of range", `program test;
+var a: array[1..3] of integer; i: integer;
+begin
+  i := 4;
+  a[i] := 1
+end.`, 5, 3, "a[i]"},
+		{"nil dereference", `program test;
+var p: ^integer;
+begin
+  p := nil;
+  writeln(p^)
+end.`, 5, 11, "p^"},
+		{"non-boolean condition", `program test;
+var x: integer;
+begin
+  while x do x := 1
+end.`, 4, 9, "x"},
+		{"argument of builtin", `program test;
+var x: integer;
+begin
+  x := -1;
+  writeln(sqrt(x))
+end.`, 5, 11, "sqrt(x)"},
+		{"undeclared pointer target", `program test;
+type p = ^missing;
+begin
+end.`, 2, 10, "^missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runProgram(tt.input)
+			var pe *PascalError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected a PascalError, got: %v", err)
+			}
+			if pe.Span.Start.Line != tt.line || pe.Span.Start.Column != tt.column {
+				t.Fatalf("wrong position for %q. expected=%d:%d, got=%d:%d",
+					pe.Msg, tt.line, tt.column, pe.Span.Start.Line, pe.Span.Start.Column)
+			}
+			if got := tt.input[pe.Span.Start.Offset:pe.Span.End.Offset]; got != tt.text {
+				t.Errorf("wrong span for %q. expected=%q, got=%q", pe.Msg, tt.text, got)
+			}
+			want := fmt.Sprintf("at line %d, column %d: %s", tt.line, tt.column, pe.Msg)
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not contain %q", err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestInterpreter_TurboExtensions(t *testing.T) {
+	input := `program test;
+uses crt;
+type point = record x, y: integer end;
+const
+  mask = $FF;
+  crlf = #13#10;
+  origin: point = (x: 1; y: 2);
+  primes: array[1..4] of integer = (2, 3, 5, 7);
+var
+  s: string[5];
+  i, k: integer;
+  c: char;
+
+procedure count;
+const calls: integer = 0;
+begin
+  inc(calls);
+  write(calls)
+end;
+
+function find(n: integer): integer;
+var j: integer;
+begin
+  find := 0;
+  for j := 1 to 4 do
+    if primes[j] = n then begin find := j; exit end;
+  find := -1
+end;
+
+begin
+  s := 'Hello, world';
+  writeln(s, ' ', ord(s[0]));
+  s[0] := chr(3);
+  s[1] := 'J';
+  writeln(s, ' ', s[3]);
+  write(mask, ' ', origin.x + origin.y, ' ', 'A'#66'C', crlf);
+  count; count; count;
+  writeln;
+  writeln(find(5), ' ', find(4));
+  k := 0;
+  for i := 1 to 10 do
+    begin
+      if odd(i) then continue;
+      if i > 6 then break;
+      inc(k, i)
+    end;
+  writeln(k, ' ', i);
+  c := 'a';
+  inc(c);
+  dec(k, 2);
+  writeln(c, ' ', k);
+  i := 0;
+  while true do
+    begin
+      inc(i);
+      if i = 3 then break
+    end;
+  repeat
+    dec(i);
+    if i = 1 then continue
+  until i <= 0;
+  writeln(i);
+  exit;
+  writeln('not reached')
+end.`
+
+	output, err := runProgramIn(ast.Turbo, input, func(*Interpreter) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Hello 5\nJel l\n255 3 ABC\r\n123\n3 -1\n12 8\nb 10\n0\n"
+	if output != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestInterpreter_Halt(t *testing.T) {
+	input := `program test;
+procedure check(n: integer);
+begin
+  if n > 2 then halt(3)
+end;
+var k: integer;
+begin
+  for k := 1 to 5 do
+    begin
+      check(k);
+      writeln(k)
+    end
+end.`
+
+	var interp *Interpreter
+	output, err := runProgramIn(ast.Turbo, input, func(i *Interpreter) { interp = i })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "1\n2\n" {
+		t.Fatalf("expected output to stop at halt, got %q", output)
+	}
+	if code := interp.ExitCode(); code != 3 {
+		t.Fatalf("expected exit code 3, got %d", code)
+	}
+}
+
+func TestInterpreter_TurboErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		stmt     string
+		expected string
+	}{
+		{"length beyond maximum", "s[0] := chr(6)", "String length out of range"},
+		{"index beyond length", "c := s[4]", "String index out of range"},
+		{"inc beyond subrange", "inc(d, 5)", "inc(d) is out of range"},
+		{"dec beyond subrange", "dec(d)", "dec(d) is out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `program test;
+var s: string[5]; c: char; d: 1..5;
+begin
+  s := 'abc';
+  d := 1;
+  ` + tt.stmt + `
+end.`
+			_, err := runProgramIn(ast.Turbo, input, func(*Interpreter) {})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q error, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInterpreter_TurboLineComments(t *testing.T) {
+	input := `program test; // line comments end at the end of the line
+var k: integer; // { is not a comment opener here
+begin
+  k := 2; // k := 3;
+  writeln(k * 10) // done
+end.`
+
+	output, err := runProgramIn(ast.Turbo, input, func(*Interpreter) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "20\n" {
+		t.Fatalf("expected %q, got %q", "20\n", output)
+	}
+}
+
+// runProgram parses and executes a Pascal program, returning its output
+func runProgram(input string) (string, error) {
+	return runProgramWith(input, func(*Interpreter) {})
+}
+
+// runProgramWith is runProgram with a hook to configure the interpreter before it runs.
+func runProgramWith(input string, setup func(*Interpreter)) (string, error) {
+	return runProgramIn(ast.ISO, input, setup)
+}
+
+// runProgramIn is runProgramWith for a program written in dialect.
+func runProgramIn(dialect ast.Dialect, input string, setup func(*Interpreter)) (string, error) {
 	l := lexer.New(input)
-	p := parser.New(l)
+	p := parser.New(l, parser.WithDialect(dialect))
 	prog := p.ParseProgram()
 
 	if p.HasErrors() {
@@ -535,6 +3089,7 @@ func runProgram(input string) (string, error) {
 	os.Stdout = w
 
 	interp := New()
+	setup(interp)
 	err := interp.Run(prog)
 
 	w.Close()
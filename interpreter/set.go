@@ -0,0 +1,195 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/bits"
+	"pastel/ast"
+	"pastel/token"
+	"strings"
+)
+
+const maxSetOrdinal = 255
+
+type bitset [(maxSetOrdinal + 1) / 64]uint64
+
+func (b *bitset) add(n int)     { b[n/64] |= 1 << (n % 64) }
+func (b bitset) has(n int) bool { return n >= 0 && n <= maxSetOrdinal && b[n/64]&(1<<(n%64)) != 0 }
+func (b bitset) union(o bitset) bitset {
+	for k := range b {
+		b[k] |= o[k]
+	}
+	return b
+}
+func (b bitset) intersect(o bitset) bitset {
+	for k := range b {
+		b[k] &= o[k]
+	}
+	return b
+}
+func (b bitset) minus(o bitset) bitset {
+	for k := range b {
+		b[k] &^= o[k]
+	}
+	return b
+}
+func (b bitset) subsetOf(o bitset) bool { return b.minus(o) == bitset{} }
+
+func (b bitset) members() []int {
+	var members []int
+	for k, word := range b {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			members = append(members, k*64+bit)
+			word &^= 1 << bit
+		}
+	}
+	return members
+}
+
+// SetValue holds a set of ordinals of one base type. Base is nil for the empty
+// set constructor [], which is compatible with every set type.
+type SetValue struct {
+	Base Type
+	Bits bitset
+}
+
+func (v *SetValue) Type() ValueType {
+	if v.Base == nil {
+		return "set"
+	}
+	return ValueType("set of " + v.Base.String())
+}
+
+func (v *SetValue) String() string {
+	members := v.Bits.members()
+	parts := make([]string, len(members))
+	for k, n := range members {
+		parts[k] = valueFromOrdinal(v.Base, n).String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (i *Interpreter) evalSetConstructor(e *ast.SetExpr) (Value, error) {
+	set := &SetValue{}
+	for _, element := range e.Elements {
+		low, err := i.evalSetMember(element.Low, set)
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if element.High != nil {
+			if high, err = i.evalSetMember(element.High, set); err != nil {
+				return nil, err
+			}
+		}
+		for n := low; n <= high; n++ {
+			set.Bits.add(n)
+		}
+	}
+	return set, nil
+}
+
+func (i *Interpreter) evalSetMember(expr ast.Expr, set *SetValue) (int, error) {
+	val, err := i.evalExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	base, ok := ordinalTypeOf(val)
+	if !ok {
+		return 0, &PascalError{
+			Msg:    "Set elements must be ordinal",
+			Detail: fmt.Sprintf("A set element has type %s.", val.Type()),
+			Hint:   "Build sets from integer, char, boolean or enumerated values.",
+		}
+	}
+	if set.Base == nil {
+		set.Base = base
+	} else if val.Type() != valueTypeOf(set.Base) {
+		return 0, &PascalError{
+			Msg:    "Type mismatch in set constructor",
+			Detail: fmt.Sprintf("The set has elements of type %s and %s.", set.Base, val.Type()),
+			Hint:   "All elements of a set constructor must have the same type.",
+		}
+	}
+	n, _ := ordinalOf(val)
+	if n < 0 || n > maxSetOrdinal {
+		return 0, setRangeError(val.String())
+	}
+	return n, nil
+}
+
+func setRangeError(member string) *PascalError {
+	return &PascalError{
+		Msg:    "Set element out of range",
+		Detail: fmt.Sprintf("%s is outside the ordinals 0..%d that a set can hold.", member, maxSetOrdinal),
+		Hint:   "Sets can only contain values whose ordinal lies between 0 and 255.",
+	}
+}
+
+func setBase(left, right *SetValue) (Type, bool) {
+	switch {
+	case left.Base == nil:
+		return right.Base, true
+	case right.Base == nil:
+		return left.Base, true
+	default:
+		return left.Base, valueTypeOf(left.Base) == valueTypeOf(right.Base)
+	}
+}
+
+func evalSetOp(op token.Token, left, right *SetValue) (Value, error) {
+	base, ok := setBase(left, right)
+	if !ok {
+		return nil, setMismatchError(op, left, right)
+	}
+	switch op.Type {
+	case token.PLUS:
+		return &SetValue{Base: base, Bits: left.Bits.union(right.Bits)}, nil
+	case token.MINUS:
+		return &SetValue{Base: base, Bits: left.Bits.minus(right.Bits)}, nil
+	case token.STAR:
+		return &SetValue{Base: base, Bits: left.Bits.intersect(right.Bits)}, nil
+	case token.EQUAL:
+		return &BooleanValue{Val: left.Bits == right.Bits}, nil
+	case token.NEQ:
+		return &BooleanValue{Val: left.Bits != right.Bits}, nil
+	case token.LE:
+		return &BooleanValue{Val: left.Bits.subsetOf(right.Bits)}, nil
+	case token.GE:
+		return &BooleanValue{Val: right.Bits.subsetOf(left.Bits)}, nil
+	default:
+		return nil, &PascalError{
+			Msg:    fmt.Sprintf("Operator '%s' cannot be applied to sets", op.Literal),
+			Detail: "Sets support + (union), - (difference), * (intersection), = and <> (equality), <= and >= (inclusion).",
+			Hint:   "Use <= or >= to test whether one set contains another.",
+		}
+	}
+}
+
+func evalIn(left Value, right Value) (Value, error) {
+	set, ok := right.(*SetValue)
+	n, isOrdinal := ordinalOf(left)
+	if !ok || !isOrdinal {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in 'in'",
+			Detail: fmt.Sprintf("Cannot test %s for membership in %s.", left.Type(), right.Type()),
+			Hint:   "The left operand of 'in' must be an ordinal value and the right operand a set.",
+		}
+	}
+	if set.Base != nil && left.Type() != valueTypeOf(set.Base) {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in 'in'",
+			Detail: fmt.Sprintf("Cannot test %s for membership in %s.", left.Type(), set.Type()),
+			Hint:   "The value must have the base type of the set.",
+		}
+	}
+	return &BooleanValue{Val: set.Bits.has(n)}, nil
+}
+
+func setMismatchError(op token.Token, left, right *SetValue) *PascalError {
+	return &PascalError{
+		Msg:    fmt.Sprintf("Type mismatch in set operation '%s'", op.Literal),
+		Detail: fmt.Sprintf("Cannot combine %s with %s.", left.Type(), right.Type()),
+		Hint:   "Both operands must be sets of the same base type.",
+	}
+}
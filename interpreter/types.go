@@ -0,0 +1,480 @@
+package interpreter
+
+import (
+	"fmt"
+	"pastel/ast"
+	"slices"
+)
+
+// Type describes a Pascal type at run time.
+type Type interface {
+	String() string
+}
+
+// BasicType is one of the required types integer, real, boolean, char and string.
+type BasicType struct{ Kind ValueType }
+
+func (t *BasicType) String() string { return string(t.Kind) }
+
+// EnumType is an enumerated type. Values lists its constants in ordinal order.
+type EnumType struct {
+	Name   string
+	Values []string
+}
+
+func (t *EnumType) String() string { return t.Name }
+
+// SubrangeType restricts an ordinal host type to the ordinals Low..High.
+type SubrangeType struct {
+	Host Type
+	Low  int
+	High int
+}
+
+func (t *SubrangeType) String() string {
+	return fmt.Sprintf("%s..%s", ordinalLiteral(t.Host, t.Low), ordinalLiteral(t.Host, t.High))
+}
+
+// ArrayType is an array whose components are indexed by the ordinals Low..High of Index.
+type ArrayType struct {
+	Packed  bool
+	Index   Type
+	Element Type
+	Low     int
+	High    int
+}
+
+func (t *ArrayType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sarray[%s] of %s", prefix, t.Index, t.Element)
+}
+
+// IsString reports whether t is a string type in the Standard Pascal sense:
+// a packed array of char indexed from 1.
+func (t *ArrayType) IsString() bool {
+	return t.Packed && valueTypeOf(t.Element) == CharType && valueTypeOf(t.Index) == IntegerType && t.Low == 1
+}
+
+// SetType is a set whose members are values of an ordinal base type.
+type SetType struct {
+	Packed  bool
+	Element Type
+}
+
+func (t *SetType) String() string {
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sset of %s", prefix, t.Element)
+}
+
+// RecordType is a record type. Fields lists every field in declaration order,
+// including the tag fields and the fields of all variants.
+type RecordType struct {
+	Name   string
+	Packed bool
+	Fields []*Field
+}
+
+func (t *RecordType) String() string { return t.Name }
+
+// Field looks up a field by name.
+func (t *RecordType) Field(name string) (*Field, bool) {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// Field is a field of a record. Variant is the variant the field belongs to, or nil for a fixed field.
+type Field struct {
+	Name    string
+	Type    Type
+	Variant *Variant
+}
+
+// VariantPart is the variant part of a record or of an enclosing variant. Tag is empty when the
+// variant part has no tag field; the active variant is then the one whose fields were last assigned.
+type VariantPart struct {
+	Tag      string
+	TagType  Type
+	Variants []*Variant
+	Parent   *Variant
+}
+
+// Variant is one alternative of a variant part, selected by the tag ordinals in Labels.
+type Variant struct {
+	Part   *VariantPart
+	Labels []int
+}
+
+func (part *VariantPart) variantFor(ord int) *Variant {
+	for _, v := range part.Variants {
+		if slices.Contains(v.Labels, ord) {
+			return v
+		}
+	}
+	return nil
+}
+
+// PointerType is a pointer to variables of type Target. TargetName is the type
+// identifier it was declared with; the target is resolved at the end of the
+// declaring block so that it may be declared after the pointer type.
+type PointerType struct {
+	TargetName string
+	Target     Type
+}
+
+func (t *PointerType) String() string { return "^" + t.TargetName }
+
+// FileType is a file of Element components. A text file is a file of char
+// structured into lines.
+type FileType struct {
+	Packed  bool
+	Text    bool
+	Element Type
+}
+
+func (t *FileType) String() string {
+	if t.Text {
+		return "text"
+	}
+	prefix := ""
+	if t.Packed {
+		prefix = "packed "
+	}
+	return fmt.Sprintf("%sfile of %s", prefix, t.Element)
+}
+
+// ShortStringType is a Turbo Pascal string[Max], a string of at most Max
+// characters. Longer values are truncated when they are assigned.
+type ShortStringType struct {
+	Max int
+}
+
+func (t *ShortStringType) String() string { return fmt.Sprintf("string[%d]", t.Max) }
+
+var (
+	integerType = &BasicType{Kind: IntegerType}
+	realType    = &BasicType{Kind: RealType}
+	booleanType = &BasicType{Kind: BooleanType}
+	charType    = &BasicType{Kind: CharType}
+	stringType  = &BasicType{Kind: StringType}
+	textType    = &FileType{Text: true, Element: charType}
+)
+
+var requiredTypes = map[string]Type{
+	"integer": integerType,
+	"real":    realType,
+	"boolean": booleanType,
+	"char":    charType,
+	"string":  stringType,
+	"text":    textType,
+}
+
+var requiredConstants = map[string]Value{
+	"maxint": &IntegerValue{Val: maxint},
+}
+
+func hostType(t Type) Type {
+	switch s := t.(type) {
+	case *SubrangeType:
+		return s.Host
+	case *ShortStringType:
+		return stringType
+	case *lengthByte:
+		return charType
+	}
+	return t
+}
+
+func valueTypeOf(t Type) ValueType {
+	switch t := hostType(t).(type) {
+	case *BasicType:
+		return t.Kind
+	case *EnumType:
+		return ValueType(t.Name)
+	default:
+		return ""
+	}
+}
+
+func isOrdinalType(t Type) bool {
+	switch t := hostType(t).(type) {
+	case *BasicType:
+		return t.Kind == IntegerType || t.Kind == BooleanType || t.Kind == CharType
+	case *EnumType:
+		return true
+	default:
+		return false
+	}
+}
+
+func ordinalTypeOf(v Value) (Type, bool) {
+	switch v := v.(type) {
+	case *IntegerValue:
+		return integerType, true
+	case *BooleanValue:
+		return booleanType, true
+	case *CharValue:
+		return charType, true
+	case *EnumValue:
+		return v.Enum, true
+	default:
+		return nil, false
+	}
+}
+
+func zeroValue(t Type) Value {
+	switch t := t.(type) {
+	case *ArrayType:
+		elems := make([]Value, t.High-t.Low+1)
+		for k := range elems {
+			elems[k] = zeroValue(t.Element)
+		}
+		return &ArrayValue{Array: t, Elems: elems}
+	case *SetType:
+		return &SetValue{Base: hostType(t.Element)}
+	case *RecordType:
+		fields := make(map[string]Value, len(t.Fields))
+		for _, field := range t.Fields {
+			fields[field.Name] = zeroValue(field.Type)
+		}
+		return &RecordValue{Record: t, Fields: fields, active: map[*VariantPart]*Variant{}}
+	case *EnumType:
+		return &EnumValue{Enum: t, Ord: 0}
+	case *SubrangeType:
+		return valueFromOrdinal(t.Host, t.Low)
+	case *PointerType:
+		return &PointerValue{Pointer: t}
+	case *FileType:
+		return &FileValue{File: t}
+	}
+	switch valueTypeOf(t) {
+	case RealType:
+		return &RealValue{Val: 0.0}
+	case BooleanType:
+		return &BooleanValue{Val: false}
+	case CharType:
+		return &CharValue{Val: ' '}
+	case StringType:
+		return &StringValue{Val: ""}
+	default:
+		return &IntegerValue{Val: 0}
+	}
+}
+
+func valueFromOrdinal(t Type, n int) Value {
+	if enum, ok := hostType(t).(*EnumType); ok {
+		return &EnumValue{Enum: enum, Ord: n}
+	}
+	switch valueTypeOf(t) {
+	case CharType:
+		return &CharValue{Val: rune(n)}
+	case BooleanType:
+		return &BooleanValue{Val: n != 0}
+	default:
+		return &IntegerValue{Val: n}
+	}
+}
+
+func ordinalLiteral(t Type, n int) string {
+	if valueTypeOf(t) == CharType {
+		return fmt.Sprintf("'%c'", rune(n))
+	}
+	return valueFromOrdinal(t, n).String()
+}
+
+func ordinalBounds(t Type) (int, int, bool) {
+	switch t := t.(type) {
+	case *SubrangeType:
+		return t.Low, t.High, true
+	case *EnumType:
+		return 0, len(t.Values) - 1, true
+	}
+	switch valueTypeOf(t) {
+	case BooleanType:
+		return 0, 1, true
+	case CharType:
+		return 0, 255, true
+	default:
+		return 0, 0, false
+	}
+}
+
+func coerce(val Value, t Type) Value {
+	if iv, ok := val.(*IntegerValue); ok && valueTypeOf(t) == RealType {
+		return &RealValue{Val: float64(iv.Val)}
+	}
+	return val
+}
+
+func conform(t Type, val Value) (Value, error) {
+	switch t := t.(type) {
+	case *ArrayType:
+		return conformArray(t, val)
+	case *SetType:
+		return conformSet(t, val)
+	case *RecordType:
+		if rec, ok := val.(*RecordValue); ok && rec.Record == t {
+			return copyValue(rec), nil
+		}
+		return nil, &PascalError{
+			Msg:    "Type mismatch in record assignment",
+			Detail: fmt.Sprintf("Cannot assign a value of type %s to %s.", val.Type(), t),
+			Hint:   "Records can only be assigned whole when both have the same type; declare them with one type name.",
+		}
+	case *PointerType:
+		if ptr, ok := val.(*PointerValue); ok && (ptr.Pointer == nil || ptr.Pointer.Target == t.Target) {
+			return &PointerValue{Pointer: t, Addr: ptr.Addr}, nil
+		}
+		return nil, &PascalError{
+			Msg:    "Type mismatch in pointer assignment",
+			Detail: fmt.Sprintf("Cannot assign a value of type %s to %s.", val.Type(), t),
+			Hint:   "Assign nil or a pointer to the same type.",
+		}
+	case *lengthByte:
+		return conformLength(t, val)
+	case *ShortStringType:
+		if text, ok := textOf(val); ok {
+			return &StringValue{Val: text[:min(len(text), t.Max)]}, nil
+		}
+	case *FileType:
+		return nil, &PascalError{
+			Msg:    "Files cannot be assigned",
+			Detail: fmt.Sprintf("A value of type %s cannot be copied into a variable of type %s.", val.Type(), t),
+			Hint:   "Pass files to procedures and functions as var parameters.",
+		}
+	}
+	if _, ok := val.(*RealValue); ok && valueTypeOf(t) == IntegerType {
+		return nil, &PascalError{
+			Msg:    "Cannot assign a real value to an integer",
+			Detail: fmt.Sprintf("The value %s has type real but the target has type %s.", val, t),
+			Hint:   "Convert it with trunc or round, or use 'div' for integer division.",
+		}
+	}
+	val = coerce(val, t)
+	return val, checkRange(t, val)
+}
+
+func conformArray(t *ArrayType, val Value) (Value, error) {
+	if text, ok := textOf(val); ok && t.IsString() {
+		if len(text) != t.High {
+			return nil, &PascalError{
+				Msg:    "String length does not match",
+				Detail: fmt.Sprintf("'%s' has %d character(s) but %s holds exactly %d.", text, len(text), t, t.High),
+				Hint:   "Pad the string with spaces to the declared length.",
+			}
+		}
+		elems := make([]Value, len(text))
+		for k, ch := range []byte(text) {
+			elems[k] = &CharValue{Val: rune(ch)}
+		}
+		return &ArrayValue{Array: t, Elems: elems}, nil
+	}
+
+	arr, ok := val.(*ArrayValue)
+	if !ok || arr.Array != t {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in array assignment",
+			Detail: fmt.Sprintf("Cannot assign a value of type %s to %s.", val.Type(), t),
+			Hint:   "Arrays can only be assigned whole when both have the same type; declare them with one type name.",
+		}
+	}
+	return copyValue(arr), nil
+}
+
+func conformantArray(schema *ast.ConformantArray, env *Environment, val Value) (*ArrayValue, bool, error) {
+	if str, ok := val.(*StringValue); ok {
+		t := &ArrayType{Packed: true, Index: integerType, Element: charType, Low: 1, High: len(str.Val)}
+		arr, err := conformArray(t, str)
+		if err != nil {
+			return nil, false, err
+		}
+		val = arr
+	}
+	arr, ok := val.(*ArrayValue)
+	if !ok {
+		return nil, false, nil
+	}
+
+	t := arr.Array
+	for {
+		index, err := lookupType(env, schema.IndexType)
+		if err != nil {
+			return nil, false, err
+		}
+		if t.Packed != schema.Packed || valueTypeOf(t.Index) != valueTypeOf(index) {
+			return nil, false, nil
+		}
+		if low, high, bounded := ordinalBounds(index); bounded && (t.Low < low || t.High > high) {
+			return nil, false, nil
+		}
+
+		switch elem := schema.Element.(type) {
+		case *ast.ConformantArray:
+			next, ok := t.Element.(*ArrayType)
+			if !ok {
+				return nil, false, nil
+			}
+			schema, t = elem, next
+			continue
+		case *ast.NamedType:
+			element, err := lookupType(env, elem.Name)
+			if err != nil {
+				return nil, false, err
+			}
+			return arr, element == t.Element, nil
+		}
+		return nil, false, nil
+	}
+}
+
+func sameArrayType(a Type, b *ArrayType) bool {
+	arr, ok := a.(*ArrayType)
+	return ok && (arr == b || arr.IsString() && b.IsString() && arr.High == b.High)
+}
+
+func conformSet(t *SetType, val Value) (Value, error) {
+	set, ok := val.(*SetValue)
+	if !ok || (set.Base != nil && valueTypeOf(set.Base) != valueTypeOf(t.Element)) {
+		return nil, &PascalError{
+			Msg:    "Type mismatch in set assignment",
+			Detail: fmt.Sprintf("Cannot assign a value of type %s to %s.", val.Type(), t),
+			Hint:   "Assign a set whose elements have the base type of the set variable.",
+		}
+	}
+	low, high, _ := ordinalBounds(t.Element)
+	for _, n := range set.Bits.members() {
+		if n < low || n > high {
+			return nil, &PascalError{
+				Msg:    "Set element out of range",
+				Detail: fmt.Sprintf("%s is not a value of %s.", ordinalLiteral(t.Element, n), t.Element),
+				Hint:   "Only values of the base type can be members of a set variable.",
+			}
+		}
+	}
+	return &SetValue{Base: hostType(t.Element), Bits: set.Bits}, nil
+}
+
+func checkRange(t Type, val Value) error {
+	s, ok := t.(*SubrangeType)
+	if !ok {
+		return nil
+	}
+	n, ok := ordinalOf(val)
+	if !ok || (s.Low <= n && n <= s.High) {
+		return nil
+	}
+	return &PascalError{
+		Msg:    "Value out of range",
+		Detail: fmt.Sprintf("%s is outside the subrange %s.", ordinalLiteral(s.Host, n), s),
+		Hint:   "Values assigned to a subrange variable must lie between its bounds.",
+	}
+}
@@ -1,6 +1,13 @@
 package interpreter
 
-import "fmt"
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"pastel/ast"
+	"strings"
+)
 
 // ValueType represents the type of a Value.
 type ValueType string
@@ -11,6 +18,9 @@ const (
 	BooleanType ValueType = "boolean"
 	CharType    ValueType = "char"
 	StringType  ValueType = "string"
+
+	ProcedureType ValueType = "procedure"
+	FunctionType  ValueType = "function"
 )
 
 // Value represents a runtime value in the interpreter.
@@ -29,7 +39,7 @@ func (v *IntegerValue) String() string  { return fmt.Sprintf("%d", v.Val) }
 type RealValue struct{ Val float64 }
 
 func (v *RealValue) Type() ValueType { return RealType }
-func (v *RealValue) String() string  { return fmt.Sprintf("%g", v.Val) }
+func (v *RealValue) String() string  { return strings.TrimPrefix(formatReal(v.Val, 0), " ") }
 
 // BooleanValue holds a boolean value.
 type BooleanValue struct{ Val bool }
@@ -53,3 +63,218 @@ type StringValue struct{ Val string }
 
 func (v *StringValue) Type() ValueType { return StringType }
 func (v *StringValue) String() string  { return v.Val }
+
+// EnumValue holds a constant of an enumerated type, identified by its ordinal.
+type EnumValue struct {
+	Enum *EnumType
+	Ord  int
+}
+
+func (v *EnumValue) Type() ValueType { return ValueType(v.Enum.Name) }
+func (v *EnumValue) String() string  { return v.Enum.Values[v.Ord] }
+
+// ArrayValue holds the components of an array in index order.
+// A packed array of char indexed from 1 prints as the string it holds.
+type ArrayValue struct {
+	Array *ArrayType
+	Elems []Value
+}
+
+func (v *ArrayValue) Type() ValueType { return ValueType(v.Array.String()) }
+func (v *ArrayValue) String() string {
+	if text, ok := textOf(v); ok {
+		return text
+	}
+	parts := make([]string, len(v.Elems))
+	for k, elem := range v.Elems {
+		parts[k] = elem.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// RecordValue holds the fields of a record by name. For variant parts without a
+// tag field it remembers which variant was selected by the last field assignment.
+type RecordValue struct {
+	Record *RecordType
+	Fields map[string]Value
+	active map[*VariantPart]*Variant
+}
+
+func (v *RecordValue) Type() ValueType { return ValueType(v.Record.Name) }
+func (v *RecordValue) String() string {
+	parts := make([]string, len(v.Record.Fields))
+	for k, field := range v.Record.Fields {
+		parts[k] = fmt.Sprintf("%s: %s", field.Name, v.Fields[field.Name])
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
+
+func (v *RecordValue) activeVariant(part *VariantPart) *Variant {
+	if part.Tag == "" {
+		return v.active[part]
+	}
+	ord, _ := ordinalOf(v.Fields[part.Tag])
+	return part.variantFor(ord)
+}
+
+func (v *RecordValue) checkActive(field *Field) error {
+	for variant := field.Variant; variant != nil; variant = variant.Part.Parent {
+		if v.activeVariant(variant.Part) == variant {
+			continue
+		}
+		detail := "A different variant was selected by the last assignment to one of its fields."
+		if tag := variant.Part.Tag; tag != "" {
+			detail = fmt.Sprintf("The tag field '%s' is %s, which selects a different variant.", tag, v.Fields[tag])
+		}
+		return &PascalError{
+			Msg:    fmt.Sprintf("Field '%s' belongs to an inactive variant", field.Name),
+			Detail: detail,
+			Hint:   "Select the variant by setting its tag field before reading its fields.",
+		}
+	}
+	return nil
+}
+
+func (v *RecordValue) set(field *Field, value Value) {
+	v.Fields[field.Name] = value
+	for variant := field.Variant; variant != nil; variant = variant.Part.Parent {
+		if variant.Part.Tag == "" {
+			v.active[variant.Part] = variant
+		}
+	}
+}
+
+// PointerValue holds the heap address of a variable created by new; address 0 is nil.
+// Pointer is nil for the untyped constant nil.
+type PointerValue struct {
+	Pointer *PointerType
+	Addr    int
+}
+
+func (v *PointerValue) Type() ValueType {
+	if v.Pointer == nil {
+		return "nil"
+	}
+	return ValueType(v.Pointer.String())
+}
+func (v *PointerValue) String() string {
+	if v.Addr == 0 {
+		return "nil"
+	}
+	return fmt.Sprintf("^%d", v.Addr)
+}
+
+// FileValue is a file variable. A file bound to an external file has that file's
+// Name; an unbound file is internal to the program and kept in memory.
+type FileValue struct {
+	File   *FileType
+	Name   string
+	mode   fileMode
+	line   int
+	reader *bufio.Reader
+	writer io.Writer
+	closer io.Closer
+	buffer *bytes.Buffer
+
+	lineStarted bool
+}
+
+func (v *FileValue) Type() ValueType { return ValueType(v.File.String()) }
+func (v *FileValue) String() string  { return v.File.String() }
+
+// RoutineValue is a procedure or function together with the scope it was declared in.
+type RoutineValue struct {
+	Decl *ast.ProcedureDecl
+	Env  *Environment
+}
+
+func (v *RoutineValue) Type() ValueType {
+	if v.Decl.IsFunction() {
+		return FunctionType
+	}
+	return ProcedureType
+}
+func (v *RoutineValue) String() string { return v.Decl.Name }
+
+// UndefinedValue marks a variable whose value is undefined, such as a for-loop
+// control variable after the loop completes. Of records the variable's type.
+type UndefinedValue struct{ Of ValueType }
+
+func (v *UndefinedValue) Type() ValueType { return v.Of }
+func (v *UndefinedValue) String() string  { return "undefined" }
+
+func ordinalOf(v Value) (int, bool) {
+	switch v := v.(type) {
+	case *IntegerValue:
+		return v.Val, true
+	case *CharValue:
+		return int(v.Val), true
+	case *BooleanValue:
+		return boolOrdinal(v.Val), true
+	case *EnumValue:
+		return v.Ord, true
+	default:
+		return 0, false
+	}
+}
+
+func boolOrdinal(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func copyValue(v Value) Value {
+	switch v := v.(type) {
+	case *ArrayValue:
+		elems := make([]Value, len(v.Elems))
+		for k, elem := range v.Elems {
+			elems[k] = copyValue(elem)
+		}
+		return &ArrayValue{Array: v.Array, Elems: elems}
+	case *RecordValue:
+		fields := make(map[string]Value, len(v.Fields))
+		for name, field := range v.Fields {
+			fields[name] = copyValue(field)
+		}
+		active := make(map[*VariantPart]*Variant, len(v.active))
+		for part, variant := range v.active {
+			active[part] = variant
+		}
+		return &RecordValue{Record: v.Record, Fields: fields, active: active}
+	default:
+		return v
+	}
+}
+
+func unpackString(v Value) Value {
+	if arr, ok := v.(*ArrayValue); ok {
+		if text, ok := textOf(arr); ok {
+			return &StringValue{Val: text}
+		}
+	}
+	return v
+}
+
+func textOf(v Value) (string, bool) {
+	switch v := v.(type) {
+	case *StringValue:
+		return v.Val, true
+	case *CharValue:
+		return string(v.Val), true
+	case *ArrayValue:
+		if !v.Array.IsString() {
+			return "", false
+		}
+		var b strings.Builder
+		for _, elem := range v.Elems {
+			if ch, ok := elem.(*CharValue); ok {
+				b.WriteRune(ch.Val)
+			}
+		}
+		return b.String(), true
+	default:
+		return "", false
+	}
+}
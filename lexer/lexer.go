@@ -14,6 +14,9 @@ type Lexer struct {
 	column       int
 }
 
+// New creates a lexer for input. Standard Pascal comments, { ... } and (* ... *),
+// are skipped; Delphi-style // comments are returned as LINE_COMMENT tokens so
+// that the parser can accept or reject them by dialect.
 func New(input string) *Lexer {
 	l := &Lexer{input: input, line: 1, column: 0}
 	l.readChar()
@@ -53,12 +56,69 @@ func (l *Lexer) newTokenWithPos(tokenType token.TokenType, ch byte, line, column
 	return token.Token{Type: tokenType, Literal: string(ch), Line: line, Column: column}
 }
 
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType, line, column int) token.Token {
+	first := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(first) + string(l.ch), Line: line, Column: column}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
+func (l *Lexer) skipWhitespaceAndComments() *token.Token {
+	for {
+		l.skipWhitespace()
+		switch {
+		case l.ch == '{':
+			if tok := l.skipBlockComment(1); tok != nil {
+				return tok
+			}
+		case l.ch == '(' && l.peekChar() == '*':
+			if tok := l.skipBlockComment(2); tok != nil {
+				return tok
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (l *Lexer) skipBlockComment(openerLen int) *token.Token {
+	line, col, offset := l.line, l.column, l.position
+	opener := l.input[l.position : l.position+openerLen]
+	for range openerLen {
+		l.readChar()
+	}
+
+	for {
+		switch {
+		case l.ch == 0:
+			tok := token.Token{Type: token.ILLEGAL, Literal: opener, Line: line, Column: col, Offset: offset}
+			l.setEnd(&tok, offset+openerLen)
+			return &tok
+		case l.ch == '}':
+			l.readChar()
+			return nil
+		case l.ch == '*' && l.peekChar() == ')':
+			l.readChar()
+			l.readChar()
+			return nil
+		}
+		l.readChar()
+	}
+}
+
+func (l *Lexer) readLineComment() string {
+	start := l.position
+	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
+		l.readChar()
+	}
+	return l.input[start:l.position]
+}
+
 func isLetter(ch byte) bool {
 	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
 }
@@ -91,6 +151,28 @@ func (l *Lexer) readNumber() (string, bool) {
 	return l.input[start:l.position], isReal
 }
 
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func (l *Lexer) readPrefixed() string {
+	start := l.position
+	if l.ch == '#' {
+		l.readChar()
+	}
+	if l.ch == '$' {
+		l.readChar()
+		for isHexDigit(l.ch) {
+			l.readChar()
+		}
+	} else {
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+	return l.input[start:l.position]
+}
+
 func (l *Lexer) readString() string {
 	l.readChar()
 	start := l.position
@@ -102,9 +184,33 @@ func (l *Lexer) readString() string {
 	return str
 }
 
+// NextToken returns the next token, skipping whitespace and comments.
+// An unterminated comment yields an ILLEGAL token whose literal is the comment opener.
 func (l *Lexer) NextToken() token.Token {
-	l.skipWhitespace()
+	if unterminated := l.skipWhitespaceAndComments(); unterminated != nil {
+		return *unterminated
+	}
+
+	offset := min(l.position, len(l.input))
+	tok := l.scanToken()
+	tok.Offset = offset
+	l.setEnd(&tok, min(l.position, len(l.input)))
+	return tok
+}
 
+func (l *Lexer) setEnd(tok *token.Token, end int) {
+	text := l.input[tok.Offset:end]
+	tok.EndOffset = end
+	if nl := strings.LastIndexByte(text, '\n'); nl >= 0 {
+		tok.EndLine = tok.Line + strings.Count(text, "\n")
+		tok.EndColumn = len(text) - nl
+		return
+	}
+	tok.EndLine = tok.Line
+	tok.EndColumn = tok.Column + len(text)
+}
+
+func (l *Lexer) scanToken() token.Token {
 	// Store position before reading token
 	line := l.line
 	col := l.column
@@ -134,6 +240,13 @@ func (l *Lexer) NextToken() token.Token {
 		return token.Token{Type: token.STRING_LIT, Literal: literal, Line: line, Column: col}
 	}
 
+	if l.ch == '$' && isHexDigit(l.peekChar()) {
+		return token.Token{Type: token.HEX_INT, Literal: l.readPrefixed(), Line: line, Column: col}
+	}
+	if l.ch == '#' && (isDigit(l.peekChar()) || l.peekChar() == '$') {
+		return token.Token{Type: token.CHAR_CODE, Literal: l.readPrefixed(), Line: line, Column: col}
+	}
+
 	var tok token.Token
 	switch l.ch {
 	case ':':
@@ -145,6 +258,23 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = l.newTokenWithPos(token.COLON, l.ch, line, col)
 		}
+	case '=':
+		tok = l.newTokenWithPos(token.EQUAL, l.ch, line, col)
+	case '<':
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.LE, line, col)
+		case '>':
+			tok = l.newTwoCharToken(token.NEQ, line, col)
+		default:
+			tok = l.newTokenWithPos(token.LT, l.ch, line, col)
+		}
+	case '>':
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.GE, line, col)
+		} else {
+			tok = l.newTokenWithPos(token.GT, l.ch, line, col)
+		}
 	case ';':
 		tok = l.newTokenWithPos(token.SEMICOLON, l.ch, line, col)
 	case ',':
@@ -156,13 +286,26 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		tok = l.newTokenWithPos(token.STAR, l.ch, line, col)
 	case '/':
+		if l.peekChar() == '/' {
+			return token.Token{Type: token.LINE_COMMENT, Literal: l.readLineComment(), Line: line, Column: col}
+		}
 		tok = l.newTokenWithPos(token.SLASH, l.ch, line, col)
 	case '(':
 		tok = l.newTokenWithPos(token.LPAREN, l.ch, line, col)
 	case ')':
 		tok = l.newTokenWithPos(token.RPAREN, l.ch, line, col)
+	case '[':
+		tok = l.newTokenWithPos(token.LBRACKET, l.ch, line, col)
+	case ']':
+		tok = l.newTokenWithPos(token.RBRACKET, l.ch, line, col)
+	case '^':
+		tok = l.newTokenWithPos(token.CARET, l.ch, line, col)
 	case '.':
-		tok = l.newTokenWithPos(token.DOT, l.ch, line, col)
+		if l.peekChar() == '.' {
+			tok = l.newTwoCharToken(token.DOTDOT, line, col)
+		} else {
+			tok = l.newTokenWithPos(token.DOT, l.ch, line, col)
+		}
 	case 0:
 		tok = token.Token{Type: token.EOF, Literal: "", Line: line, Column: col}
 	default:
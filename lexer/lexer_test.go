@@ -6,7 +6,7 @@ import (
 )
 
 func TestNextToken_SingleCharacterTokens(t *testing.T) {
-	input := `+-*/();:,.`
+	input := `+-*/();:,.[]^`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -22,6 +22,9 @@ func TestNextToken_SingleCharacterTokens(t *testing.T) {
 		{token.COLON, ":"},
 		{token.COMMA, ","},
 		{token.DOT, "."},
+		{token.LBRACKET, "["},
+		{token.RBRACKET, "]"},
+		{token.CARET, "^"},
 		{token.EOF, ""},
 	}
 
@@ -58,7 +61,7 @@ func TestNextToken_MultiCharacterTokens(t *testing.T) {
 }
 
 func TestNextToken_Keywords(t *testing.T) {
-	input := `program var begin end writeln integer`
+	input := `program var begin end write writeln integer`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -68,6 +71,7 @@ func TestNextToken_Keywords(t *testing.T) {
 		{token.VAR, "var"},
 		{token.BEGIN, "begin"},
 		{token.END, "end"},
+		{token.WRITE, "write"},
 		{token.WRITELN, "writeln"},
 		{token.INTEGER, "integer"},
 		{token.EOF, ""},
@@ -378,6 +382,41 @@ func TestNextToken_StringAndCharLiterals(t *testing.T) {
 	}
 }
 
+func TestNextToken_HexLiteralsAndCharCodes(t *testing.T) {
+	input := `$FF $1a #13 #$0D'ab'#10 $ #`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.HEX_INT, "$FF"},
+		{token.HEX_INT, "$1a"},
+		{token.CHAR_CODE, "#13"},
+		{token.CHAR_CODE, "#$0D"},
+		{token.STRING_LIT, "ab"},
+		{token.CHAR_CODE, "#10"},
+		{token.ILLEGAL, "$"},
+		{token.ILLEGAL, "#"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
 func TestNextToken_BooleanKeywords(t *testing.T) {
 	input := `true false TRUE FALSE True`
 
@@ -465,3 +504,216 @@ func TestNextToken_IntegerNotReal(t *testing.T) {
 		t.Fatalf("expected IDENT, got %q", tok.Type)
 	}
 }
+
+func TestNextToken_RelationalOperators(t *testing.T) {
+	input := `= <> < <= > >= a<>b`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.EQUAL, "="},
+		{token.NEQ, "<>"},
+		{token.LT, "<"},
+		{token.LE, "<="},
+		{token.GT, ">"},
+		{token.GE, ">="},
+		{token.IDENT, "a"},
+		{token.NEQ, "<>"},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextToken_Subrange(t *testing.T) {
+	input := `1..10 'a'..'z' end.`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "1"},
+		{token.DOTDOT, ".."},
+		{token.INT, "10"},
+		{token.CHAR_LIT, "a"},
+		{token.DOTDOT, ".."},
+		{token.CHAR_LIT, "z"},
+		{token.END, "end"},
+		{token.DOT, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextToken_Comments(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"braces", "x { a comment } y"},
+		{"paren star", "x (* a comment *) y"},
+		{"mixed brace and star paren", "x { opened one way *) y"},
+		{"mixed paren star and brace", "x (* opened the other way } y"},
+		{"adjacent", "x{c}(*d*)y"},
+		{"star inside", "x (* a * b ) *) y"},
+		{"multi line", "x {\nline one\nline two\n} y"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for _, expected := range []string{"x", "y", ""} {
+			tok := l.NextToken()
+			if tok.Literal != expected {
+				t.Fatalf("%s: expected %q, got %q (%s)", tt.name, expected, tok.Literal, tok.Type)
+			}
+		}
+	}
+}
+
+func TestNextToken_PositionAfterMultiLineComment(t *testing.T) {
+	input := `x (* first
+   second *) y
+{ third }   z`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"x", 1, 1},
+		{"y", 2, 14},
+		{"z", 3, 13},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNextToken_UnterminatedComment(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOpener string
+		expectedColumn int
+	}{
+		{"x { never closed", "{", 3},
+		{"x  (* never closed", "(*", 4},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		l.NextToken()
+
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedOpener {
+			t.Fatalf("%q: expected ILLEGAL %q, got %q (%s)", tt.input, tt.expectedOpener, tok.Literal, tok.Type)
+		}
+		if tok.Line != 1 || tok.Column != tt.expectedColumn {
+			t.Fatalf("%q: expected error at 1:%d, got %d:%d", tt.input, tt.expectedColumn, tok.Line, tok.Column)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("%q: expected EOF after unterminated comment, got %s", tt.input, next.Type)
+		}
+	}
+}
+
+func TestNextToken_LineComments(t *testing.T) {
+	input := "x // rest of line\r\ny / z"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.LINE_COMMENT, "// rest of line"},
+		{token.IDENT, "y"},
+		{token.SLASH, "/"},
+		{token.IDENT, "z"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNextToken_OffsetsAndEnds(t *testing.T) {
+	input := "x := 'ab';\n  writeln(12)"
+
+	tests := []struct {
+		literal                       string
+		offset                        int
+		endLine, endColumn, endOffset int
+	}{
+		{"x", 0, 1, 2, 1},
+		{":=", 2, 1, 5, 4},
+		{"ab", 5, 1, 10, 9},
+		{";", 9, 1, 11, 10},
+		{"writeln", 13, 2, 10, 20},
+		{"(", 20, 2, 11, 21},
+		{"12", 21, 2, 13, 23},
+		{")", 23, 2, 14, 24},
+		{"", 24, 2, 14, 24},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.literal, tok.Literal)
+		}
+		if tok.Offset != tt.offset {
+			t.Fatalf("tests[%d] (%q) - offset wrong. expected=%d, got=%d", i, tok.Literal, tt.offset, tok.Offset)
+		}
+		if tok.EndLine != tt.endLine || tok.EndColumn != tt.endColumn || tok.EndOffset != tt.endOffset {
+			t.Fatalf("tests[%d] (%q) - end wrong. expected=%d:%d@%d, got=%d:%d@%d", i, tok.Literal,
+				tt.endLine, tt.endColumn, tt.endOffset, tok.EndLine, tok.EndColumn, tok.EndOffset)
+		}
+	}
+}
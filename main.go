@@ -1,20 +1,35 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"pastel/ast"
+	"pastel/checker"
 	"pastel/interpreter"
 	"pastel/lexer"
 	"pastel/parser"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: pastel <source-file>")
+	dialectName := flag.String("dialect", "iso", "Pascal dialect of the program: iso, or turbo (alias fpc) for Turbo Pascal extensions")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: pastel [-dialect iso|turbo] <source-file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	dialect, ok := ast.LookupDialect(*dialectName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown dialect %q: use iso or turbo\n", *dialectName)
 		os.Exit(1)
 	}
 
-	filename := os.Args[1]
+	filename := flag.Arg(0)
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
@@ -27,7 +42,7 @@ func main() {
 	l := lexer.New(input)
 
 	// Step 2: Parsing
-	p := parser.New(l)
+	p := parser.New(l, parser.WithDialect(dialect))
 	prog := p.ParseProgram()
 
 	// Step 3: Check for parsing errors
@@ -39,14 +54,28 @@ func main() {
 		return
 	}
 
-	// Step 4: Create interpreter and run the program
+	// Step 4: Check declarations and types before running anything
+	if diagnostics := checker.Check(prog); len(diagnostics) > 0 {
+		fmt.Println("Semantic errors encountered:")
+		for _, d := range diagnostics {
+			fmt.Println(d.Error())
+		}
+		return
+	}
+
+	// Step 5: Create interpreter, connect the program's input to stdin and run it
 	interp := interpreter.New()
+	interp.SetInput(os.Stdin)
 	if err := interp.Run(prog); err != nil {
 		fmt.Println("Runtime error encountered:")
 		fmt.Println(err.Error())
 		return
 	}
 
-	// Step 5: Successful execution
+	// Step 6: Successful execution
+	if code := interp.ExitCode(); code != 0 {
+		fmt.Printf("Program halted with exit code %d.\n", code)
+		os.Exit(code)
+	}
 	fmt.Println("Program executed successfully.")
 }
@@ -0,0 +1,351 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"pastel/ast"
+	"pastel/token"
+)
+
+const maxint = math.MaxInt64
+
+type constantError string
+
+func (e constantError) Error() string { return string(e) }
+
+type symbol struct {
+	value   ast.Expr
+	enum    *ast.EnumType
+	ordinal int
+	forward *ast.ProcedureDecl
+}
+
+func (p *Parser) pushScope() {
+	p.scopes = append(p.scopes, map[string]*symbol{})
+}
+
+func (p *Parser) popScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+func (p *Parser) declareName(name string) {
+	p.scopes[len(p.scopes)-1][name] = nil
+}
+
+func (p *Parser) declareConstant(name string, value ast.Expr) {
+	p.scopes[len(p.scopes)-1][name] = &symbol{value: value}
+}
+
+func (p *Parser) declareEnumConstant(name string, enum *ast.EnumType, ordinal int) {
+	p.scopes[len(p.scopes)-1][name] = &symbol{value: &ast.Identifier{Value: name}, enum: enum, ordinal: ordinal}
+}
+
+func (p *Parser) declareForward(decl *ast.ProcedureDecl) {
+	p.scopes[len(p.scopes)-2][decl.Name] = &symbol{forward: decl}
+}
+
+func (p *Parser) lookupForward(name string) *ast.ProcedureDecl {
+	if sym := p.scopes[len(p.scopes)-1][name]; sym != nil {
+		return sym.forward
+	}
+	return nil
+}
+
+func (p *Parser) lookupSymbol(name string) *symbol {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if sym, ok := p.scopes[i][name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+func (p *Parser) lookupConstant(name string) (ast.Expr, bool) {
+	if sym := p.lookupSymbol(name); sym != nil && sym.value != nil {
+		return sym.value, true
+	}
+	return nil, false
+}
+
+func (p *Parser) parseConstant(context string) (ast.Expr, bool) {
+	start := p.curToken
+	expr := p.ParseExpression()
+	if expr == nil {
+		return nil, false
+	}
+
+	value, err := p.foldConstant(expr)
+	if err != nil {
+		p.addErrorAt(start,
+			fmt.Sprintf("Expected constant expression in %s", context),
+			err.Error(),
+			"Constant expressions may only use literals, constants and operators.",
+		)
+		return nil, false
+	}
+	return positioned(value, p.spanFrom(start)), true
+}
+
+func positioned(value ast.Expr, span ast.Span) ast.Expr {
+	switch v := value.(type) {
+	case *ast.IntegerLiteral:
+		c := *v
+		c.Span = span
+		return &c
+	case *ast.RealLiteral:
+		c := *v
+		c.Span = span
+		return &c
+	case *ast.BooleanLiteral:
+		c := *v
+		c.Span = span
+		return &c
+	case *ast.CharLiteral:
+		c := *v
+		c.Span = span
+		return &c
+	case *ast.StringLiteral:
+		c := *v
+		c.Span = span
+		return &c
+	case *ast.Identifier:
+		c := *v
+		c.Span = span
+		return &c
+	}
+	return value
+}
+
+func (p *Parser) foldConstant(expr ast.Expr) (ast.Expr, error) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.BooleanLiteral, *ast.CharLiteral, *ast.StringLiteral:
+		return e, nil
+
+	case *ast.Identifier:
+		value, ok := p.lookupConstant(e.Value)
+		if !ok {
+			return nil, constantError(fmt.Sprintf("'%s' is not a constant.", e.Value))
+		}
+		return value, nil
+
+	case *ast.UnaryExpr:
+		operand, err := p.foldConstant(e.Operand)
+		if err != nil {
+			return nil, err
+		}
+		return foldUnary(e.Operator, operand)
+
+	case *ast.BinaryExpr:
+		left, err := p.foldConstant(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.foldConstant(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return p.foldBinary(e.Operator, left, right)
+
+	case *ast.CallExpr:
+		return nil, constantError("Function calls cannot appear in a constant expression.")
+
+	default:
+		return nil, constantError("Only literals, constants and operators can appear in a constant expression.")
+	}
+}
+
+func foldUnary(op token.Token, operand ast.Expr) (ast.Expr, error) {
+	switch v := operand.(type) {
+	case *ast.IntegerLiteral:
+		switch op.Type {
+		case token.MINUS:
+			return &ast.IntegerLiteral{Value: -v.Value}, nil
+		case token.PLUS:
+			return v, nil
+		}
+	case *ast.RealLiteral:
+		switch op.Type {
+		case token.MINUS:
+			return &ast.RealLiteral{Value: -v.Value}, nil
+		case token.PLUS:
+			return v, nil
+		}
+	case *ast.BooleanLiteral:
+		if op.Type == token.NOT {
+			return &ast.BooleanLiteral{Value: !v.Value}, nil
+		}
+	}
+	return nil, constantError(fmt.Sprintf("Operator '%s' cannot be applied to this constant.", op.Literal))
+}
+
+func (p *Parser) foldBinary(op token.Token, left, right ast.Expr) (ast.Expr, error) {
+	switch op.Type {
+	case token.EQUAL, token.NEQ, token.LT, token.LE, token.GT, token.GE:
+		return p.foldComparison(op, left, right)
+	case token.AND, token.OR:
+		l, lok := left.(*ast.BooleanLiteral)
+		r, rok := right.(*ast.BooleanLiteral)
+		if !lok || !rok {
+			break
+		}
+		if op.Type == token.AND {
+			return &ast.BooleanLiteral{Value: l.Value && r.Value}, nil
+		}
+		return &ast.BooleanLiteral{Value: l.Value || r.Value}, nil
+	case token.PLUS:
+		if ls, ok := constantText(left); ok {
+			if rs, ok := constantText(right); ok {
+				return &ast.StringLiteral{Value: ls + rs}, nil
+			}
+		}
+		return foldArithmetic(op, left, right)
+	case token.MINUS, token.STAR, token.SLASH, token.DIV, token.MOD:
+		return foldArithmetic(op, left, right)
+	}
+	return nil, constantError(fmt.Sprintf("Operator '%s' cannot be applied to these constants.", op.Literal))
+}
+
+func foldArithmetic(op token.Token, left, right ast.Expr) (ast.Expr, error) {
+	li, lIsInt := left.(*ast.IntegerLiteral)
+	ri, rIsInt := right.(*ast.IntegerLiteral)
+	if lIsInt && rIsInt && op.Type != token.SLASH {
+		return foldInteger(op, li.Value, ri.Value)
+	}
+	if op.Type == token.DIV || op.Type == token.MOD {
+		return nil, constantError(fmt.Sprintf("Operator '%s' requires integer constants.", op.Literal))
+	}
+
+	l, lok := constantReal(left)
+	r, rok := constantReal(right)
+	if !lok || !rok {
+		return nil, constantError(fmt.Sprintf("Operator '%s' requires numeric constants.", op.Literal))
+	}
+	switch op.Type {
+	case token.PLUS:
+		return &ast.RealLiteral{Value: l + r}, nil
+	case token.MINUS:
+		return &ast.RealLiteral{Value: l - r}, nil
+	case token.STAR:
+		return &ast.RealLiteral{Value: l * r}, nil
+	default:
+		if r == 0 {
+			return nil, constantError("Division by zero in constant expression.")
+		}
+		return &ast.RealLiteral{Value: l / r}, nil
+	}
+}
+
+func foldInteger(op token.Token, l, r int) (ast.Expr, error) {
+	var result int
+	overflowed := false
+	switch op.Type {
+	case token.PLUS:
+		result = l + r
+		overflowed = (r > 0 && result < l) || (r < 0 && result > l)
+	case token.MINUS:
+		result = l - r
+		overflowed = (r > 0 && result > l) || (r < 0 && result < l)
+	case token.STAR:
+		result = l * r
+		overflowed = l != 0 && result/l != r
+	case token.DIV:
+		if r == 0 {
+			return nil, constantError("Division by zero in constant expression.")
+		}
+		result = l / r
+	case token.MOD:
+		if r <= 0 {
+			return nil, constantError(fmt.Sprintf("The divisor of 'mod' must be positive, got %d.", r))
+		}
+		result = l % r
+		if result < 0 {
+			result += r
+		}
+	}
+	if overflowed || result < -maxint {
+		return nil, constantError(fmt.Sprintf("Integer overflow in %d %s %d.", l, op.Literal, r))
+	}
+	return &ast.IntegerLiteral{Value: result}, nil
+}
+
+func (p *Parser) foldComparison(op token.Token, left, right ast.Expr) (ast.Expr, error) {
+	cmp, ok := p.compareConstants(left, right)
+	if !ok {
+		return nil, constantError(fmt.Sprintf("Cannot compare these constants with '%s'.", op.Literal))
+	}
+
+	var result bool
+	switch op.Type {
+	case token.EQUAL:
+		result = cmp == 0
+	case token.NEQ:
+		result = cmp != 0
+	case token.LT:
+		result = cmp < 0
+	case token.LE:
+		result = cmp <= 0
+	case token.GT:
+		result = cmp > 0
+	case token.GE:
+		result = cmp >= 0
+	}
+	return &ast.BooleanLiteral{Value: result}, nil
+}
+
+func (p *Parser) compareConstants(left, right ast.Expr) (int, bool) {
+	if l, ok := constantReal(left); ok {
+		r, ok := constantReal(right)
+		return cmp.Compare(l, r), ok
+	}
+	if l, ok := constantText(left); ok {
+		r, ok := constantText(right)
+		return cmp.Compare(l, r), ok
+	}
+	lKind, l, lok := p.constantOrdinal(left)
+	rKind, r, rok := p.constantOrdinal(right)
+	return cmp.Compare(l, r), lok && rok && lKind == rKind
+}
+
+func (p *Parser) constantOrdinal(expr ast.Expr) (any, int, bool) {
+	switch v := expr.(type) {
+	case *ast.Identifier:
+		if sym := p.lookupSymbol(v.Value); sym != nil && sym.enum != nil {
+			return sym.enum, sym.ordinal, true
+		}
+		return nil, 0, false
+	case *ast.IntegerLiteral:
+		return token.INTEGER, v.Value, true
+	case *ast.CharLiteral:
+		return token.CHAR, int(v.Value), true
+	case *ast.BooleanLiteral:
+		if v.Value {
+			return token.BOOLEAN, 1, true
+		}
+		return token.BOOLEAN, 0, true
+	default:
+		return nil, 0, false
+	}
+}
+
+func constantReal(expr ast.Expr) (float64, bool) {
+	switch v := expr.(type) {
+	case *ast.IntegerLiteral:
+		return float64(v.Value), true
+	case *ast.RealLiteral:
+		return v.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func constantText(expr ast.Expr) (string, bool) {
+	switch v := expr.(type) {
+	case *ast.StringLiteral:
+		return v.Value, true
+	case *ast.CharLiteral:
+		return string(v.Value), true
+	default:
+		return "", false
+	}
+}
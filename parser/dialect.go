@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"fmt"
+	"pastel/ast"
+	"pastel/token"
+	"strconv"
+	"strings"
+)
+
+// Option configures optional parser behaviour.
+type Option func(*Parser)
+
+// WithDialect selects the dialect to parse; the default is ISO Pascal.
+// Constructs of another dialect are reported as errors naming that dialect.
+func WithDialect(d ast.Dialect) Option {
+	return func(p *Parser) { p.dialect = d }
+}
+
+func (p *Parser) requireTurbo(tok token.Token, construct, alternative string) {
+	if p.dialect == ast.Turbo {
+		return
+	}
+	p.addErrorAt(tok,
+		fmt.Sprintf("%s requires the %s dialect", construct, ast.Turbo),
+		fmt.Sprintf("%s is a %s extension that %s does not have.", construct, ast.Turbo, ast.ISO),
+		fmt.Sprintf("Select the dialect with -dialect=turbo, or %s.", alternative),
+	)
+}
+
+func (p *Parser) parseUses() []string {
+	p.requireTurbo(p.curToken, "A uses clause", "remove the clause")
+
+	p.nextToken()
+
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(
+			"Expected unit name after 'uses'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"A uses clause has the form: uses crt, dos;",
+		)
+		return nil
+	}
+	units := p.parseIdentList()
+	p.expectCur(token.SEMICOLON, "Expected ';' after uses clause", "A uses clause has the form: uses crt, dos;")
+	return units
+}
+
+func (p *Parser) parseHexLiteral() ast.Expr {
+	tok := p.curToken
+	p.nextToken()
+
+	val, err := strconv.ParseInt(tok.Literal[1:], 16, 64)
+	if err != nil {
+		p.addErrorAt(tok,
+			fmt.Sprintf("Integer literal %s is too large", tok.Literal),
+			fmt.Sprintf("Integers must lie in the range -maxint..maxint, where maxint = %d.", maxint),
+			"Use a real literal such as 1.0 for larger values.",
+		)
+	} else {
+		p.requireTurbo(tok, fmt.Sprintf("Hexadecimal literal %s", tok.Literal), fmt.Sprintf("write it in decimal as %d", val))
+	}
+	return &ast.IntegerLiteral{Span: span(tok), Value: int(val)}
+}
+
+func (p *Parser) parseCharacterString() ast.Expr {
+	start := p.curToken
+	var text strings.Builder
+	reported := false
+	for {
+		tok := p.curToken
+		if tok.Type == token.CHAR_CODE {
+			code, err := strconv.ParseInt(strings.Replace(tok.Literal[1:], "$", "", 1), base(tok.Literal[1:]), 64)
+			if err != nil || code > 255 {
+				p.addErrorAt(tok,
+					fmt.Sprintf("Character code %s is out of range", tok.Literal),
+					"Character codes are ordinals from #0 to #255.",
+					"Use a smaller code.",
+				)
+			} else if !reported {
+				p.requireTurbo(tok, fmt.Sprintf("Character code %s", tok.Literal), fmt.Sprintf("write chr(%d)", code))
+				reported = true
+			}
+			text.WriteByte(byte(code))
+		} else {
+			text.WriteString(tok.Literal)
+		}
+
+		next := p.peekToken
+		joined := next.Offset == tok.EndOffset && (tok.Type == token.CHAR_CODE || next.Type == token.CHAR_CODE) &&
+			(next.Type == token.CHAR_LIT || next.Type == token.STRING_LIT || next.Type == token.CHAR_CODE)
+		p.nextToken()
+		if !joined {
+			break
+		}
+	}
+
+	if text.Len() == 1 {
+		return &ast.CharLiteral{Span: p.spanFrom(start), Value: rune(text.String()[0])}
+	}
+	return &ast.StringLiteral{Span: p.spanFrom(start), Value: text.String()}
+}
+
+func base(digits string) int {
+	if strings.HasPrefix(digits, "$") {
+		return 16
+	}
+	return 10
+}
+
+func (p *Parser) parseStringType() (ast.TypeExpr, bool) {
+	start := p.curToken
+
+	p.nextToken()
+	p.nextToken()
+
+	sizeTok := p.curToken
+	size, ok := p.parseConstant("string length")
+	if !ok {
+		return nil, false
+	}
+	n, isInteger := size.(*ast.IntegerLiteral)
+	if !isInteger || n.Value < 1 || n.Value > 255 {
+		p.addErrorAt(sizeTok,
+			"Invalid string length",
+			"The length of a string type is an integer from 1 to 255, which its length byte can hold.",
+			"Declare the string with a length such as string[80].",
+		)
+		return nil, false
+	}
+	if !p.expectCur(token.RBRACKET, "Expected ']' after string length", "A string type has the form: string[80].") {
+		return nil, false
+	}
+
+	t := &ast.StringType{Span: p.spanFrom(start), Size: n.Value}
+	p.requireTurbo(start, fmt.Sprintf("String type %s", t), fmt.Sprintf("use packed array[1..%d] of char", t.Size))
+	return t, true
+}
+
+func (p *Parser) parseTypedConstant(start token.Token) (ast.Stmt, bool) {
+	name := start.Literal
+	p.requireTurbo(start, fmt.Sprintf("Typed constant '%s'", name), fmt.Sprintf("declare '%s' as a variable and assign it at the start of the block", name))
+
+	p.nextToken()
+
+	constType, ok := p.parseType("typed constant")
+	if !ok {
+		return nil, false
+	}
+	if !p.expectCur(token.EQUAL, "Expected '=' after the type of a typed constant", "Typed constants have the form: const Limit: integer = 10;") {
+		return nil, false
+	}
+	value, ok := p.parseTypedConstantValue()
+	if !ok {
+		return nil, false
+	}
+	span := p.spanFrom(start)
+
+	if !p.expectCur(token.SEMICOLON, "Expected ';' after constant definition", "Constant definitions must end with a semicolon.") {
+		return nil, false
+	}
+
+	p.declareName(name)
+	return &ast.TypedConstDecl{Span: span, Name: name, Type: constType, Value: value}, true
+}
+
+func (p *Parser) parseTypedConstantValue() (ast.Expr, bool) {
+	if !p.curTokenIs(token.LPAREN) {
+		return p.parseConstant("typed constant")
+	}
+	start := p.curToken
+
+	p.nextToken()
+
+	if p.curTokenIs(token.IDENT) && p.peekToken.Type == token.COLON {
+		record := &ast.RecordConstant{}
+		for {
+			if !p.curTokenIs(token.IDENT) {
+				p.addError(
+					"Expected field name in record constant",
+					fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+					"A record constant gives its fields by name, e.g. (x: 1; y: 2).",
+				)
+				return nil, false
+			}
+			fieldStart := p.curToken
+			p.nextToken()
+			if !p.expectCur(token.COLON, "Expected ':' after field name", "A record constant gives its fields by name, e.g. (x: 1; y: 2).") {
+				return nil, false
+			}
+			value, ok := p.parseTypedConstantValue()
+			if !ok {
+				return nil, false
+			}
+			record.Fields = append(record.Fields, &ast.FieldConstant{Span: p.spanFrom(fieldStart), Name: fieldStart.Literal, Value: value})
+
+			if !p.curTokenIs(token.SEMICOLON) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectCur(token.RPAREN, "Expected ')' after record constant", "Separate the fields with ';' and close the list with ')'.") {
+			return nil, false
+		}
+		record.Span = p.spanFrom(start)
+		return record, true
+	}
+
+	array := &ast.ArrayConstant{}
+	for {
+		value, ok := p.parseTypedConstantValue()
+		if !ok {
+			return nil, false
+		}
+		array.Elements = append(array.Elements, value)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectCur(token.RPAREN, "Expected ')' after array constant", "Separate the elements with ',' and close the list with ')'.") {
+		return nil, false
+	}
+	array.Span = p.spanFrom(start)
+	return array, true
+}
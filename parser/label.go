@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"fmt"
+	"pastel/ast"
+	"pastel/token"
+	"slices"
+	"strconv"
+)
+
+const maxLabel = 9999
+
+type labelScope struct {
+	labels             map[int]*label
+	declared           []*label
+	outermostSequence  int
+	enclosingSequences []int
+	openLabels         []int
+	gotos              []*pendingGoto
+}
+
+type label struct {
+	value    int
+	decl     token.Token
+	defined  bool
+	sequence int
+}
+
+type pendingGoto struct {
+	tok                token.Token
+	label              int
+	enclosingSequences []int
+	insideTarget       bool
+	fromInnerRoutine   bool
+}
+
+func (p *Parser) pushLabelScope() {
+	p.labels = append(p.labels, &labelScope{labels: map[int]*label{}})
+}
+
+func (p *Parser) labelScope() *labelScope {
+	return p.labels[len(p.labels)-1]
+}
+
+func (p *Parser) popLabelScope() {
+	scope := p.labelScope()
+	p.labels = p.labels[:len(p.labels)-1]
+
+	for _, lbl := range scope.declared {
+		if !lbl.defined {
+			p.addErrorAt(lbl.decl,
+				fmt.Sprintf("Label %d is declared but does not prefix a statement", lbl.value),
+				"Every declared label must prefix one statement of the block it is declared in.",
+				fmt.Sprintf("Write `%d:` before a statement of the block, or remove the label from the declaration.", lbl.value),
+			)
+		}
+	}
+
+	for _, g := range scope.gotos {
+		lbl := scope.labels[g.label]
+		if !lbl.defined {
+			continue
+		}
+		reachable := slices.Contains(g.enclosingSequences, lbl.sequence)
+		if g.fromInnerRoutine {
+			reachable = lbl.sequence == scope.outermostSequence
+		}
+		if g.insideTarget || (lbl.sequence != 0 && reachable) {
+			continue
+		}
+		detail := fmt.Sprintf("Label %d prefixes a statement inside a structured statement that does not contain the goto.", g.label)
+		if g.fromInnerRoutine {
+			detail = fmt.Sprintf("A goto out of a procedure or function may only jump to a statement of the outermost begin...end of the block that declares label %d.", g.label)
+		}
+		p.addErrorAt(g.tok,
+			fmt.Sprintf("goto %d jumps into a structured statement", g.label),
+			detail,
+			"Jump only to labels on statements of an enclosing statement sequence, never into an if, loop, case, with or compound statement from outside it.",
+		)
+	}
+}
+
+func (p *Parser) parseLabelDecl() []ast.Stmt {
+	p.nextToken()
+
+	scope := p.labelScope()
+	var decls []ast.Stmt
+	for {
+		if !p.curTokenIs(token.INT) {
+			p.addError(
+				"Expected label in label declaration",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Labels are unsigned integers, e.g. label 10, 20;",
+			)
+			return decls
+		}
+		tok := p.curToken
+		value, ok := p.parseLabel()
+		if !ok {
+			return decls
+		}
+		if _, exists := scope.labels[value]; exists {
+			p.addErrorAt(tok,
+				fmt.Sprintf("Duplicate declaration of label %d", value),
+				fmt.Sprintf("Label %d is already declared in this block.", value),
+				"Declare each label only once.",
+			)
+		} else {
+			lbl := &label{value: value, decl: tok}
+			scope.labels[value] = lbl
+			scope.declared = append(scope.declared, lbl)
+			decls = append(decls, &ast.LabelDecl{Span: span(tok), Label: value})
+		}
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	p.expectCur(token.SEMICOLON, "Expected ';' after label declaration", "Separate labels with ',' and end the declaration with ';'.")
+	return decls
+}
+
+func (p *Parser) parseLabel() (int, bool) {
+	tok := p.curToken
+	p.nextToken()
+	value, err := strconv.Atoi(tok.Literal)
+	if err != nil || value > maxLabel {
+		p.addErrorAt(tok,
+			fmt.Sprintf("Label %s is out of range", tok.Literal),
+			fmt.Sprintf("Labels are unsigned integers from 0 to %d.", maxLabel),
+			"Use a smaller number as the label.",
+		)
+		return 0, false
+	}
+	return value, true
+}
+
+func (p *Parser) parseLabeledStatement(sequence int) ast.Stmt {
+	start := p.curToken
+	value, ok := p.parseLabel()
+	if !ok {
+		return nil
+	}
+
+	scope := p.labelScope()
+	switch lbl := scope.labels[value]; {
+	case lbl == nil:
+		p.addErrorAt(start,
+			fmt.Sprintf("Undeclared label %d", value),
+			"A label must be declared in the block whose statement it prefixes.",
+			fmt.Sprintf("Add `label %d;` before the declarations of the block.", value),
+		)
+	case lbl.defined:
+		p.addErrorAt(start,
+			fmt.Sprintf("Label %d prefixes more than one statement", value),
+			"Each label may prefix only one statement, so a goto has a single target.",
+			"Declare a separate label for this statement.",
+		)
+	default:
+		lbl.defined = true
+		lbl.sequence = sequence
+	}
+
+	p.nextToken()
+
+	scope.openLabels = append(scope.openLabels, value)
+	stmt := &ast.LabeledStmt{Label: value, Stmt: p.parseStatement()}
+	scope.openLabels = scope.openLabels[:len(scope.openLabels)-1]
+
+	stmt.Span = p.spanFrom(start)
+	return stmt
+}
+
+func (p *Parser) parseGoto() ast.Stmt {
+	start := p.curToken
+
+	p.nextToken()
+
+	if !p.curTokenIs(token.INT) {
+		p.addError(
+			"Expected label after 'goto'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"A goto statement has the form: goto 10.",
+		)
+		return nil
+	}
+	tok := p.curToken
+	value, ok := p.parseLabel()
+	if !ok {
+		return nil
+	}
+
+	current := p.labelScope()
+	for k := len(p.labels) - 1; k >= 0; k-- {
+		scope := p.labels[k]
+		if _, declared := scope.labels[value]; !declared {
+			continue
+		}
+		g := &pendingGoto{tok: tok, label: value, fromInnerRoutine: scope != current}
+		if !g.fromInnerRoutine {
+			g.enclosingSequences = slices.Clone(scope.enclosingSequences)
+			g.insideTarget = slices.Contains(scope.openLabels, value)
+		}
+		scope.gotos = append(scope.gotos, g)
+		return &ast.GotoStmt{Span: p.spanFrom(start), Label: value}
+	}
+
+	p.addErrorAt(tok,
+		fmt.Sprintf("Undeclared label %d", value),
+		"A goto may only jump to a label declared in its block or an enclosing one.",
+		fmt.Sprintf("Add `label %d;` to the block that contains the target statement.", value),
+	)
+	return nil
+}
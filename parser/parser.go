@@ -10,14 +10,25 @@ import (
 
 type Parser struct {
 	l         *lexer.Lexer
+	prevToken token.Token
 	curToken  token.Token
 	peekToken token.Token
 	errors    []*ParserError
+	scopes    []map[string]*symbol
+	labels    []*labelScope
+	sequences int
+	dialect   ast.Dialect
 }
 
 // New creates a new Parser instance with the given lexer.
-func New(l *lexer.Lexer) *Parser {
+// It parses ISO Pascal unless opts select another dialect.
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{l: l}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.pushScope()
+	p.declareConstant("maxint", &ast.IntegerLiteral{Value: maxint})
 	p.nextToken()
 	p.nextToken()
 	return p
@@ -32,15 +43,20 @@ func (p *Parser) Errors() []*ParserError {
 }
 
 // ParseExpression parses an expression in Pascal.
-// Expressions include arithmetic operations like addition, subtraction, multiplication, and division.
+// Expressions include arithmetic, relational and boolean operations. From lowest to highest
+// precedence the levels are: relational operators, adding operators (including 'or' and a
+// leading sign), multiplying operators (including 'div', 'mod' and 'and'), and factors
+// (including 'not').
+// A sign applies to the whole first term, so -a * b means -(a * b).
 func (p *Parser) ParseExpression() ast.Expr {
-	return p.parseAddition()
+	return p.parseRelational()
 }
 
 // ParseProgram parses a complete Pascal program.
 // A Pascal program starts with the 'program' keyword, followed by declarations and a main compound statement.
 func (p *Parser) ParseProgram() *ast.Program {
-	prog := &ast.Program{}
+	start := p.curToken
+	prog := &ast.Program{Dialect: p.dialect}
 
 	if p.curToken.Type == token.PROGRAM {
 		// Advance to the next token after 'program' keyword
@@ -67,6 +83,22 @@ func (p *Parser) ParseProgram() *ast.Program {
 	prog.Name = p.curToken.Literal
 	p.nextToken()
 
+	if p.curTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) {
+			p.addError(
+				"Expected program parameter",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Program parameters are identifiers, e.g. program copy(input, output);",
+			)
+			return nil
+		}
+		prog.Params = p.parseIdentList()
+		if !p.expectCur(token.RPAREN, "Expected ')' after program parameters", "Separate program parameters with ',' and close the list with ')'.") {
+			return nil
+		}
+	}
+
 	if p.curToken.Type != token.SEMICOLON {
 		p.addError(
 			"Expected semicolon",
@@ -79,37 +111,17 @@ func (p *Parser) ParseProgram() *ast.Program {
 	// Advance to the next token after the semicolon
 	p.nextToken()
 
-	var decls []ast.Stmt
-	for p.curToken.Type == token.VAR {
-		decl := p.parseVarDecl()
-		if decl != nil {
-			decls = append(decls, decl)
-		}
-	}
-	prog.Declarations = decls
-
-	if p.curToken.Type != token.BEGIN {
-		p.addError(
-			"Expected 'begin' block",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"A Pascal program must have a 'begin' block to define its main body.",
-		)
-		return nil
+	if p.curTokenIs(token.IDENT) && p.curToken.Literal == "uses" {
+		prog.Uses = p.parseUses()
 	}
 
-	// Parse the compound statement starting with 'begin'
-	stmt := p.parseCompound()
-	compound, ok := stmt.(*ast.CompoundStmt)
-	if !ok {
-		p.addError(
-			"Expected compound statement",
-			"The main body of the program must be a compound statement.",
-			"Ensure the program's main body starts with 'begin' and ends with 'end'.",
-		)
+	decls, main := p.parseBlock()
+	if main == nil {
 		return nil
 	}
 
-	prog.Main = compound
+	prog.Declarations = decls
+	prog.Main = main
 
 	if p.curToken.Type != token.DOT {
 		p.addError(
@@ -120,32 +132,93 @@ func (p *Parser) ParseProgram() *ast.Program {
 		return nil
 	}
 
+	prog.Span = ast.Span{Start: position(start), End: endPosition(p.curToken)}
 	return prog
 }
 
+func (p *Parser) parseBlock() ([]ast.Stmt, *ast.CompoundStmt) {
+	p.pushLabelScope()
+	defer p.popLabelScope()
+
+	var decls []ast.Stmt
+	for p.curTokenIs(token.LABEL) || p.curTokenIs(token.CONST) || p.curTokenIs(token.TYPE) || p.curTokenIs(token.VAR) || p.curTokenIs(token.PROCEDURE) || p.curTokenIs(token.FUNCTION) {
+		switch p.curToken.Type {
+		case token.LABEL:
+			decls = append(decls, p.parseLabelDecl()...)
+			continue
+		case token.CONST:
+			decls = append(decls, p.parseConstDecl()...)
+			continue
+		case token.TYPE:
+			decls = append(decls, p.parseTypeDecl()...)
+			continue
+		case token.VAR:
+			decls = append(decls, p.parseVarDecl()...)
+			continue
+		}
+		decl := p.parseProcedureDecl()
+		if decl == nil {
+			return decls, nil
+		}
+		decls = append(decls, decl)
+	}
+
+	if p.curToken.Type != token.BEGIN {
+		p.addError(
+			"Expected 'begin' block",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"A Pascal program must have a 'begin' block to define its main body.",
+		)
+		return decls, nil
+	}
+
+	return decls, p.parseCompound()
+}
+
 // ParseStatement parses a single Pascal statement.
-// Statements include assignments, compound statements, and print statements.
 func (p *Parser) parseStatement() ast.Stmt {
 	switch p.curToken.Type {
+	case token.INT:
+		if p.peekToken.Type == token.COLON {
+			return p.parseLabeledStatement(0)
+		}
+		return nil
+
 	case token.IDENT:
 		// Look ahead to see if this is an assignment (IDENT := ...)
-		if p.peekToken.Type == token.ASSIGN {
+		switch p.peekToken.Type {
+		case token.ASSIGN, token.LBRACKET, token.DOT, token.CARET:
 			return p.parseAssignment()
 		}
-		p.addError(
-			fmt.Sprintf("Unexpected identifier '%s'", p.curToken.Literal),
-			"This identifier is not part of an assignment or recognized statement.",
-			"Make sure you're using ':=' for assignments or a known keyword like 'writeln'.",
-		)
-		p.nextToken()
-		return nil
+		return p.parseCallStmt()
 
-	case token.WRITELN:
+	case token.WRITE, token.WRITELN:
 		return p.parsePrint()
 
 	case token.BEGIN:
 		return p.parseCompound()
 
+	case token.IF:
+		return p.parseIf()
+
+	case token.WHILE:
+		return p.parseWhile()
+
+	case token.REPEAT:
+		return p.parseRepeat()
+
+	case token.FOR:
+		return p.parseFor()
+
+	case token.CASE:
+		return p.parseCase()
+
+	case token.WITH:
+		return p.parseWith()
+
+	case token.GOTO:
+		return p.parseGoto()
+
 	default:
 		return nil
 	}
@@ -154,287 +227,1523 @@ func (p *Parser) parseStatement() ast.Stmt {
 // ParseAssignment parses an assignment statement in Pascal.
 // Assignment statements use the ':=' operator to assign values to variables.
 func (p *Parser) parseAssignment() ast.Stmt {
-	name := p.curToken.Literal // We are on IDENT
+	start := p.curToken // We are on IDENT
 
-	if !p.expectPeek(token.ASSIGN) {
+	p.nextToken()
+	target := p.parseSelectors(&ast.Identifier{Span: span(start), Value: start.Literal})
+	if target == nil {
 		return nil
 	}
 
-	p.nextToken()
-	value := p.ParseExpression()
-
-	if !p.curTokenIs(token.SEMICOLON) {
-		p.addError(
-			"Expected semicolon at the end of assignment",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Assignments must end with a semicolon.",
-		)
+	if !p.expectCur(token.ASSIGN, "Expected ':=' in assignment", "An assignment has the form: x := <expression>, or a[i] := <expression>.") {
 		return nil
 	}
 
-	return &ast.AssignStmt{Name: name, Value: value}
-}
-
-// ParseCompound parses a compound statement in Pascal.
-// Compound statements start with 'begin', contain multiple statements, and end with 'end'.
-func (p *Parser) parseCompound() ast.Stmt {
-	stmts := []ast.Stmt{}
+	value := p.ParseExpression()
 
-	p.nextToken()
+	return &ast.AssignStmt{Span: p.spanFrom(start), Target: target, Value: value}
+}
 
-	for p.curToken.Type != token.END && p.curToken.Type != token.EOF && p.curToken.Type != token.DOT {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			stmts = append(stmts, stmt)
+func (p *Parser) parseSelectors(expr ast.Expr) ast.Expr {
+	for p.curTokenIs(token.LBRACKET) || p.curTokenIs(token.CARET) || (p.curTokenIs(token.DOT) && p.peekToken.Type == token.IDENT) {
+		if p.curTokenIs(token.CARET) {
+			p.nextToken()
+			expr = &ast.DerefExpr{Span: p.spanFromNode(expr), Pointer: expr}
+			continue
+		}
+		if p.curTokenIs(token.DOT) {
+			p.nextToken()
+			field := p.curToken.Literal
+			p.nextToken()
+			expr = &ast.FieldExpr{Span: p.spanFromNode(expr), Record: expr, Field: field}
+			continue
 		}
 
 		p.nextToken()
-	}
 
-	// Advance past 'end' token
-	if p.curToken.Type == token.END {
-		p.nextToken()
-	}
+		base := expr
+		indexes := []*ast.IndexExpr{{Array: expr, Index: p.ParseExpression()}}
+		for p.curTokenIs(token.COMMA) {
+			p.nextToken()
+			indexes = append(indexes, &ast.IndexExpr{Array: indexes[len(indexes)-1], Index: p.ParseExpression()})
+		}
 
-	return &ast.CompoundStmt{Statements: stmts}
-}
+		if !p.expectCur(token.RBRACKET, "Expected ']' after array index", "Separate indexes with ',' and close the list with ']', e.g. a[i, j].") {
+			return nil
+		}
 
-// ParsePrint parses a print statement in Pascal.
-// Print statements use the 'writeln' keyword to output values.
-func (p *Parser) parsePrint() ast.Stmt {
-	if !p.curTokenIs(token.WRITELN) {
-		p.addError(
-			"Expected 'writeln' keyword",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Use 'writeln' to print values.",
-		)
-		return nil
+		for _, index := range indexes {
+			index.Span = p.spanFromNode(base)
+		}
+		expr = indexes[len(indexes)-1]
 	}
+	return expr
+}
 
-	// Advance to the next token after 'writeln'
+func (p *Parser) parseCallStmt() ast.Stmt {
+	start := p.curToken
+	stmt := &ast.CallStmt{Name: start.Literal}
 	p.nextToken()
 
-	if !p.curTokenIs(token.LPAREN) {
-		p.addError(
-			"Expected '(' after 'writeln'",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"The 'writeln' keyword must be followed by parentheses containing the argument.",
-		)
-		return nil
+	if p.curTokenIs(token.LPAREN) {
+		args, ok := p.parseArguments()
+		if !ok {
+			return nil
+		}
+		stmt.Args = args
 	}
+	stmt.Span = p.spanFrom(start)
+	return stmt
+}
 
+func (p *Parser) parseArguments() ([]ast.Expr, bool) {
 	// Advance to the next token after '('
 	p.nextToken()
 
-	arg := p.ParseExpression()
-
+	var args []ast.Expr
 	if !p.curTokenIs(token.RPAREN) {
-		p.addError(
-			"Expected ')' after writeln argument",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Ensure the argument to 'writeln' is enclosed in parentheses.",
-		)
-		return nil
+		args = append(args, p.ParseExpression())
+		for p.curTokenIs(token.COMMA) {
+			p.nextToken()
+			args = append(args, p.ParseExpression())
+		}
 	}
 
-	// Advance to the next token after ')'
-	p.nextToken()
+	if !p.expectCur(token.RPAREN, "Expected ')' after arguments", "Separate arguments with ',' and close the list with ')'.") {
+		return nil, false
+	}
+	return args, true
+}
+
+// ParseCompound parses a compound statement in Pascal.
+// Compound statements start with 'begin', contain multiple statements, and end with 'end'.
+func (p *Parser) parseCompound() *ast.CompoundStmt {
+	start := p.curToken
+	stmts := p.parseStatementSequence()
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	if !p.curTokenIs(token.END) {
 		p.addError(
-			"Expected ';' after writeln",
+			"Expected 'end' to close 'begin' block",
 			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Statements must end with a semicolon.",
+			"Separate statements with ';' and close the block with 'end'.",
 		)
-		return nil
+		return &ast.CompoundStmt{Span: p.spanFrom(start), Statements: stmts}
 	}
 
-	return &ast.PrintStmt{Argument: arg}
-}
+	// Advance past 'end' token
+	p.nextToken()
 
-func (p *Parser) nextToken() {
-	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	return &ast.CompoundStmt{Span: p.spanFrom(start), Statements: stmts}
 }
 
-func (p *Parser) curTokenIs(t token.TokenType) bool {
-	return p.curToken.Type == t
-}
+func (p *Parser) parseStatementSequence() []ast.Stmt {
+	stmts := []ast.Stmt{}
 
-func (p *Parser) addError(msg, detail, hint string) {
-	p.errors = append(p.errors, &ParserError{
-		Msg:    msg,
-		Detail: detail,
-		Hint:   hint,
-		Line:   p.curToken.Line,
-		Column: p.curToken.Column,
-	})
-}
+	p.nextToken()
 
-func (p *Parser) expectPeek(t token.TokenType) bool {
-	if p.peekToken.Type == t {
+	scope := p.labelScope()
+	p.sequences++
+	sequence := p.sequences
+	if scope.outermostSequence == 0 {
+		scope.outermostSequence = sequence
+	}
+	scope.enclosingSequences = append(scope.enclosingSequences, sequence)
+	defer func() { scope.enclosingSequences = scope.enclosingSequences[:len(scope.enclosingSequences)-1] }()
+
+	for {
+		var stmt ast.Stmt
+		if p.curTokenIs(token.INT) && p.peekToken.Type == token.COLON {
+			stmt = p.parseLabeledStatement(sequence)
+		} else {
+			stmt = p.parseStatement()
+		}
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		if !p.curTokenIs(token.SEMICOLON) {
+			return stmts
+		}
 		p.nextToken()
-		return true
 	}
-	p.errors = append(p.errors, &ParserError{
-		Msg:    fmt.Sprintf("Expected next token to be %s", t),
-		Detail: fmt.Sprintf("Got %q (%s) instead.", p.peekToken.Literal, p.peekToken.Type),
-		Hint:   "Check the syntax of your program.",
-		Line:   p.peekToken.Line,
-		Column: p.peekToken.Column,
-	})
-	return false
 }
 
-func (p *Parser) parseAddition() ast.Expr {
-	left := p.parseMultiplication()
+func (p *Parser) parseIf() ast.Stmt {
+	start := p.curToken
 
-	for p.curTokenIs(token.PLUS) || p.curTokenIs(token.MINUS) {
-		op := p.curToken
-		p.nextToken()
-		right := p.parseMultiplication()
-		left = &ast.BinaryExpr{Left: left, Operator: op, Right: right}
-	}
+	p.nextToken()
 
-	return left
-}
+	cond := p.ParseExpression()
 
-func (p *Parser) parseMultiplication() ast.Expr {
-	left := p.parsePrimary()
+	if !p.expectCur(token.THEN, "Expected 'then' after if condition", "An if statement has the form: if <condition> then <statement>.") {
+		return nil
+	}
 
-	for p.curTokenIs(token.STAR) || p.curTokenIs(token.SLASH) {
-		op := p.curToken
+	stmt := &ast.IfStmt{Condition: cond, Then: p.parseStatement()}
+
+	if p.curTokenIs(token.ELSE) {
 		p.nextToken()
-		right := p.parsePrimary()
-		left = &ast.BinaryExpr{Left: left, Operator: op, Right: right}
+		stmt.Else = p.parseStatement()
 	}
 
-	return left
+	stmt.Span = p.spanFrom(start)
+	return stmt
 }
 
-func (p *Parser) parsePrimary() ast.Expr {
-	switch p.curToken.Type {
-	case token.LPAREN:
-		p.nextToken() // Advance from '(' to first token inside
+func (p *Parser) parseWhile() ast.Stmt {
+	start := p.curToken
 
-		expr := p.ParseExpression()
+	p.nextToken()
 
-		if !p.curTokenIs(token.RPAREN) {
-			p.addError(
-				"Expected closing parenthesis",
-				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-				"Ensure all opening parentheses have matching closing parentheses.",
-			)
-			return nil
-		}
+	cond := p.ParseExpression()
 
-		p.nextToken() // Consume ')'
-		return expr
+	if !p.expectCur(token.DO, "Expected 'do' after while condition", "A while loop has the form: while <condition> do <statement>.") {
+		return nil
+	}
 
-	case token.INT:
-		val, _ := strconv.Atoi(p.curToken.Literal)
-		lit := &ast.IntegerLiteral{Value: val}
-		p.nextToken()
-		return lit
+	body := p.parseStatement()
+	return &ast.WhileStmt{Span: p.spanFrom(start), Condition: cond, Body: body}
+}
 
-	case token.REAL_LIT:
-		val, _ := strconv.ParseFloat(p.curToken.Literal, 64)
-		lit := &ast.RealLiteral{Value: val}
-		p.nextToken()
-		return lit
+func (p *Parser) parseRepeat() ast.Stmt {
+	start := p.curToken
+	body := p.parseStatementSequence()
 
-	case token.TRUE:
-		lit := &ast.BooleanLiteral{Value: true}
-		p.nextToken()
-		return lit
+	if !p.expectCur(token.UNTIL, "Expected 'until' to close 'repeat' loop", "A repeat loop has the form: repeat <statements> until <condition>.") {
+		return nil
+	}
 
-	case token.FALSE:
-		lit := &ast.BooleanLiteral{Value: false}
-		p.nextToken()
-		return lit
+	cond := p.ParseExpression()
+	return &ast.RepeatStmt{Span: p.spanFrom(start), Body: body, Condition: cond}
+}
 
-	case token.CHAR_LIT:
-		lit := &ast.CharLiteral{Value: rune(p.curToken.Literal[0])}
-		p.nextToken()
-		return lit
+func (p *Parser) parseFor() ast.Stmt {
+	start := p.curToken
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
-	case token.STRING_LIT:
-		lit := &ast.StringLiteral{Value: p.curToken.Literal}
-		p.nextToken()
-		return lit
+	stmt := &ast.ForStmt{Variable: p.curToken.Literal}
 
-	case token.IDENT:
-		ident := &ast.Identifier{Value: p.curToken.Literal}
-		p.nextToken()
-		return ident
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Initial = p.ParseExpression()
 
+	switch p.curToken.Type {
+	case token.TO:
+	case token.DOWNTO:
+		stmt.Downto = true
 	default:
 		p.addError(
-			"Unexpected token in primary expression",
+			"Expected 'to' or 'downto' in for loop",
 			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Check the syntax of your expression.",
+			"A for loop has the form: for i := 1 to 10 do <statement>.",
 		)
 		return nil
 	}
-}
 
-func (p *Parser) parseVarDecl() ast.Stmt {
-	// Advance to the next token after 'var'
 	p.nextToken()
 
-	if p.curToken.Type != token.IDENT {
-		p.addError(
-			"Expected variable name after 'var'",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Variable declarations must start with a valid identifier.",
-		)
+	stmt.Final = p.ParseExpression()
+
+	if !p.expectCur(token.DO, "Expected 'do' after for loop bounds", "A for loop has the form: for i := 1 to 10 do <statement>.") {
 		return nil
 	}
 
-	name := p.curToken.Literal
+	stmt.Body = p.parseStatement()
+	stmt.Span = p.spanFrom(start)
+	return stmt
+}
+
+func (p *Parser) parseCase() ast.Stmt {
+	start := p.curToken
 
-	// Advance to the next token after the variable name
 	p.nextToken()
 
-	if p.curToken.Type != token.COLON {
-		p.addError(
-			"Expected ':' after variable name",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Variable declarations must specify a type after the colon.",
-		)
+	stmt := &ast.CaseStmt{Selector: p.ParseExpression()}
+
+	if !p.expectCur(token.OF, "Expected 'of' after case selector", "A case statement has the form: case <selector> of <labels>: <statement> end.") {
 		return nil
 	}
 
-	// Advance to the next token after ':'
-	p.nextToken()
+	var seen []caseRange
+	for !p.curTokenIs(token.END) && !p.isCaseElse() && !p.curTokenIs(token.EOF) {
+		branch, ok := p.parseCaseBranch(&seen)
+		if !ok {
+			return nil
+		}
+		stmt.Branches = append(stmt.Branches, branch)
 
-	var varType string
-	switch p.curToken.Type {
-	case token.INTEGER, token.REAL, token.BOOLEAN, token.CHAR, token.STRING:
-		varType = p.curToken.Literal
-	default:
-		p.addError(
-			"Expected type for variable",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Supported types are: integer, real, boolean, char, string.",
-		)
-		return nil
+		if !p.curTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
 	}
 
-	// Advance to the next token after the type
-	p.nextToken()
+	if p.isCaseElse() {
+		elseStart := p.curToken
+		stmts := p.parseStatementSequence()
+		stmt.Else = &ast.CompoundStmt{Span: p.spanFrom(elseStart), Statements: stmts}
+	}
 
-	if p.curToken.Type != token.SEMICOLON {
-		p.addError(
-			"Expected ';' after variable declaration",
-			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
-			"Variable declarations must end with a semicolon.",
-		)
+	if !p.expectCur(token.END, "Expected 'end' to close case statement", "Separate case branches with ';' and close the statement with 'end'.") {
 		return nil
 	}
 
-	// Advance to the next token after the semicolon
-	p.nextToken()
+	stmt.Span = p.spanFrom(start)
+	return stmt
+}
+
+func (p *Parser) parseWith() ast.Stmt {
+	start := p.curToken
+
+	p.nextToken()
+
+	var records []ast.Expr
+	for {
+		if !p.curTokenIs(token.IDENT) {
+			p.addError(
+				"Expected record variable after 'with'",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"A with statement has the form: with r do <statement>.",
+			)
+			return nil
+		}
+		name := p.curToken
+		p.nextToken()
+		record := p.parseSelectors(&ast.Identifier{Span: span(name), Value: name.Literal})
+		if record == nil {
+			return nil
+		}
+		records = append(records, record)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.DO, "Expected 'do' after with records", "A with statement has the form: with r do <statement>.") {
+		return nil
+	}
+
+	stmt := p.parseStatement()
+	for i := len(records) - 1; i >= 0; i-- {
+		stmt = &ast.WithStmt{Span: p.spanFrom(start), Record: records[i], Body: stmt}
+	}
+	return stmt
+}
+
+func (p *Parser) isCaseElse() bool {
+	return p.curTokenIs(token.ELSE) || (p.curTokenIs(token.IDENT) && p.curToken.Literal == "otherwise")
+}
+
+type caseRange struct {
+	kind      any
+	low, high int
+}
+
+func (r caseRange) overlaps(other caseRange) bool {
+	return r.kind == other.kind && r.low <= other.high && other.low <= r.high
+}
+
+func (p *Parser) parseCaseBranch(seen *[]caseRange) (*ast.CaseBranch, bool) {
+	start := p.curToken
+	branch := &ast.CaseBranch{}
+	for {
+		label, ok := p.parseCaseLabel(seen)
+		if !ok {
+			return nil, false
+		}
+		branch.Labels = append(branch.Labels, label)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.COLON, "Expected ':' after case labels", "Each case branch has the form: <label>, <label>: <statement>.") {
+		return nil, false
+	}
+
+	branch.Body = p.parseStatement()
+	branch.Span = p.spanFrom(start)
+	return branch, true
+}
+
+func (p *Parser) parseCaseLabel(seen *[]caseRange) (*ast.CaseLabel, bool) {
+	start := p.curToken
+
+	low, lowKind, lowOrd, ok := p.parseOrdinalConstant("case label")
+	if !ok {
+		return nil, false
+	}
+	label := &ast.CaseLabel{Low: low}
+	current := caseRange{kind: lowKind, low: lowOrd, high: lowOrd}
+
+	if p.curTokenIs(token.DOTDOT) {
+		p.nextToken()
+		high, highKind, highOrd, ok := p.parseOrdinalConstant("case label")
+		if !ok {
+			return nil, false
+		}
+		label.High = high
+		label.Span = p.spanFrom(start)
+		if highKind != lowKind || highOrd < lowOrd {
+			p.addErrorAt(start,
+				"Invalid case label range",
+				"Both bounds of a label range must have the same type, and the lower bound must not exceed the upper bound.",
+				"Write ranges as low..high, e.g. 1..5 or 'a'..'z'.",
+			)
+			return label, true
+		}
+		current.high = highOrd
+	}
+	label.Span = p.spanFrom(start)
+
+	for _, prev := range *seen {
+		if prev.overlaps(current) {
+			p.addErrorAt(start,
+				"Duplicate case label",
+				"This label selects a value that an earlier label in the same case statement already selects.",
+				"Each value may appear only once among the labels of a case statement.",
+			)
+			return label, true
+		}
+	}
+	*seen = append(*seen, current)
+	return label, true
+}
+
+func (p *Parser) parseOrdinalConstant(context string) (ast.Expr, any, int, bool) {
+	start := p.curToken
+	value, ok := p.parseConstant(context)
+	if !ok {
+		return nil, nil, 0, false
+	}
+
+	kind, ord, ok := p.constantOrdinal(value)
+	if !ok {
+		p.addErrorAt(start,
+			fmt.Sprintf("Expected ordinal constant in %s", context),
+			"Reals and strings have no ordinal number.",
+			"Use an integer, character, boolean or enumeration constant.",
+		)
+		return nil, nil, 0, false
+	}
+	return value, kind, ord, true
+}
+
+// ParsePrint parses a print statement in Pascal.
+func (p *Parser) parsePrint() ast.Stmt {
+	if !p.curTokenIs(token.WRITE) && !p.curTokenIs(token.WRITELN) {
+		p.addError(
+			"Expected 'write' or 'writeln' keyword",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Use 'write' or 'writeln' to print values.",
+		)
+		return nil
+	}
+	keyword := p.curToken
+	stmt := &ast.PrintStmt{Newline: keyword.Type == token.WRITELN}
+
+	p.nextToken()
+
+	if !p.curTokenIs(token.LPAREN) {
+		if stmt.Newline {
+			stmt.Span = p.spanFrom(keyword)
+			return stmt
+		}
+		p.addError(
+			"Expected '(' after 'write'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"The 'write' keyword must be followed by parentheses containing the arguments.",
+		)
+		return nil
+	}
+
+	// Advance to the next token after '('
+	p.nextToken()
+
+	for {
+		argStart := p.curToken
+		arg := p.ParseExpression()
+		if p.curTokenIs(token.COLON) {
+			p.nextToken()
+			format := &ast.FormatExpr{Value: arg, Width: p.ParseExpression()}
+			if p.curTokenIs(token.COLON) {
+				p.nextToken()
+				format.Decimals = p.ParseExpression()
+			}
+			format.Span = p.spanFrom(argStart)
+			arg = format
+		}
+		stmt.Args = append(stmt.Args, arg)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.RPAREN, fmt.Sprintf("Expected ')' after %s arguments", keyword.Literal), "Separate arguments with ',' and close the list with ')'.") {
+		return nil
+	}
+	stmt.Span = p.spanFrom(keyword)
+	return stmt
+}
+
+func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
+	p.curToken = p.peekToken
+	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.LINE_COMMENT {
+		p.requireTurbo(p.peekToken, "A // comment", "write the comment as { ... }")
+		p.peekToken = p.l.NextToken()
+	}
+	if p.peekToken.Type == token.ILLEGAL {
+		p.addIllegalTokenError(p.peekToken)
+	}
+}
+
+func (p *Parser) addIllegalTokenError(tok token.Token) {
+	if tok.Literal == "{" || tok.Literal == "(*" {
+		p.addErrorAt(tok,
+			"Unterminated comment",
+			fmt.Sprintf("The comment opened with %q is never closed.", tok.Literal),
+			"Close comments with '}' or '*)'.",
+		)
+		return
+	}
+	p.addErrorAt(tok,
+		fmt.Sprintf("Illegal character %q", tok.Literal),
+		"This character is not part of Pascal's syntax.",
+		"Remove the character or place it inside a string literal.",
+	)
+}
+
+func (p *Parser) curTokenIs(t token.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) addError(msg, detail, hint string) {
+	p.addErrorAt(p.curToken, msg, detail, hint)
+}
+
+func position(tok token.Token) ast.Position {
+	return ast.Position{Line: tok.Line, Column: tok.Column, Offset: tok.Offset}
+}
+
+func endPosition(tok token.Token) ast.Position {
+	return ast.Position{Line: tok.EndLine, Column: tok.EndColumn, Offset: tok.EndOffset}
+}
+
+func span(tok token.Token) ast.Span {
+	return ast.Span{Start: position(tok), End: endPosition(tok)}
+}
+
+func (p *Parser) spanFrom(tok token.Token) ast.Span {
+	return ast.Span{Start: position(tok), End: endPosition(p.prevToken)}
+}
+
+func (p *Parser) spanFromNode(node ast.Node) ast.Span {
+	return ast.Span{Start: node.SourceSpan().Start, End: endPosition(p.prevToken)}
+}
+
+func (p *Parser) addErrorAt(tok token.Token, msg, detail, hint string) {
+	p.errors = append(p.errors, &ParserError{
+		Msg:    msg,
+		Detail: detail,
+		Hint:   hint,
+		Line:   tok.Line,
+		Column: tok.Column,
+	})
+}
+
+func (p *Parser) expectCur(t token.TokenType, msg, hint string) bool {
+	if !p.curTokenIs(t) {
+		p.addError(msg, fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type), hint)
+		return false
+	}
+	p.nextToken()
+	return true
+}
+
+func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.peekToken.Type == t {
+		p.nextToken()
+		return true
+	}
+	p.errors = append(p.errors, &ParserError{
+		Msg:    fmt.Sprintf("Expected next token to be %s", t),
+		Detail: fmt.Sprintf("Got %q (%s) instead.", p.peekToken.Literal, p.peekToken.Type),
+		Hint:   "Check the syntax of your program.",
+		Line:   p.peekToken.Line,
+		Column: p.peekToken.Column,
+	})
+	return false
+}
+
+func (p *Parser) parseRelational() ast.Expr {
+	start := p.curToken
+	return p.parseRelationalTail(start, p.parseAddition())
+}
+
+func (p *Parser) parseRelationalTail(start token.Token, left ast.Expr) ast.Expr {
+	if isRelationalOperator(p.curToken.Type) {
+		op := p.curToken
+		p.nextToken()
+		right := p.parseAddition()
+		left = &ast.BinaryExpr{Span: p.spanFrom(start), Left: left, Operator: op, Right: right}
+	}
+
+	return left
+}
+
+func isRelationalOperator(t token.TokenType) bool {
+	switch t {
+	case token.EQUAL, token.NEQ, token.LT, token.LE, token.GT, token.GE, token.IN:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parseAddition() ast.Expr {
+	start := p.curToken
+	if p.curTokenIs(token.MINUS) || p.curTokenIs(token.PLUS) {
+		p.nextToken()
+		operand := p.parseMultiplication()
+		return p.parseAdditionTail(start, &ast.UnaryExpr{Span: p.spanFrom(start), Operator: start, Operand: operand})
+	}
+	return p.parseAdditionTail(start, p.parseMultiplication())
+}
+
+func (p *Parser) parseAdditionTail(start token.Token, left ast.Expr) ast.Expr {
+	for p.curTokenIs(token.PLUS) || p.curTokenIs(token.MINUS) || p.curTokenIs(token.OR) {
+		op := p.curToken
+		p.nextToken()
+		right := p.parseMultiplication()
+		left = &ast.BinaryExpr{Span: p.spanFrom(start), Left: left, Operator: op, Right: right}
+	}
+
+	return left
+}
+
+func (p *Parser) parseMultiplication() ast.Expr {
+	start := p.curToken
+	left := p.parsePrimary()
+
+	for p.curTokenIs(token.STAR) || p.curTokenIs(token.SLASH) || p.curTokenIs(token.DIV) ||
+		p.curTokenIs(token.MOD) || p.curTokenIs(token.AND) {
+		op := p.curToken
+		p.nextToken()
+		right := p.parsePrimary()
+		left = &ast.BinaryExpr{Span: p.spanFrom(start), Left: left, Operator: op, Right: right}
+	}
+
+	return left
+}
+
+func (p *Parser) parsePrimary() ast.Expr {
+	switch p.curToken.Type {
+	case token.LPAREN:
+		p.nextToken() // Advance from '(' to first token inside
+
+		expr := p.ParseExpression()
+
+		if !p.curTokenIs(token.RPAREN) {
+			p.addError(
+				"Expected closing parenthesis",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Ensure all opening parentheses have matching closing parentheses.",
+			)
+			return nil
+		}
+
+		p.nextToken() // Consume ')'
+		return expr
+
+	case token.INT:
+		val, err := strconv.Atoi(p.curToken.Literal)
+		if err != nil || val > maxint {
+			p.addError(
+				fmt.Sprintf("Integer literal %s is too large", p.curToken.Literal),
+				fmt.Sprintf("Integers must lie in the range -maxint..maxint, where maxint = %d.", maxint),
+				"Use a real literal such as 1.0 for larger values.",
+			)
+		}
+		lit := &ast.IntegerLiteral{Span: span(p.curToken), Value: val}
+		p.nextToken()
+		return lit
+
+	case token.REAL_LIT:
+		val, _ := strconv.ParseFloat(p.curToken.Literal, 64)
+		lit := &ast.RealLiteral{Span: span(p.curToken), Value: val}
+		p.nextToken()
+		return lit
+
+	case token.TRUE:
+		lit := &ast.BooleanLiteral{Span: span(p.curToken), Value: true}
+		p.nextToken()
+		return lit
+
+	case token.FALSE:
+		lit := &ast.BooleanLiteral{Span: span(p.curToken), Value: false}
+		p.nextToken()
+		return lit
+
+	case token.NIL:
+		lit := &ast.NilLiteral{Span: span(p.curToken)}
+		p.nextToken()
+		return lit
+
+	case token.HEX_INT:
+		return p.parseHexLiteral()
+
+	case token.CHAR_LIT, token.STRING_LIT, token.CHAR_CODE:
+		return p.parseCharacterString()
+
+	case token.IDENT:
+		name := p.curToken
+		p.nextToken()
+		if !p.curTokenIs(token.LPAREN) {
+			return p.parseSelectors(&ast.Identifier{Span: span(name), Value: name.Literal})
+		}
+		args, ok := p.parseArguments()
+		if !ok {
+			return nil
+		}
+		return &ast.CallExpr{Span: p.spanFrom(name), Name: name.Literal, Args: args}
+
+	case token.NOT:
+		op := p.curToken
+		p.nextToken()
+		operand := p.parsePrimary()
+		return &ast.UnaryExpr{Span: p.spanFrom(op), Operator: op, Operand: operand}
+
+	case token.LBRACKET:
+		return p.parseSetConstructor()
+
+	case token.MINUS, token.PLUS:
+		p.addError(
+			fmt.Sprintf("Unexpected sign '%s' inside a term", p.curToken.Literal),
+			"A sign may only appear at the start of an expression or after an adding or relational operator.",
+			fmt.Sprintf("Parenthesize the signed operand, e.g. a * (%sb).", p.curToken.Literal),
+		)
+		return nil
+
+	default:
+		p.addError(
+			"Unexpected token in primary expression",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Check the syntax of your expression.",
+		)
+		return nil
+	}
+}
+
+func (p *Parser) parseSetConstructor() ast.Expr {
+	start := p.curToken
+
+	p.nextToken()
+
+	set := &ast.SetExpr{}
+	for !p.curTokenIs(token.RBRACKET) {
+		elementStart := p.curToken
+		element := &ast.SetElement{Low: p.ParseExpression()}
+		if p.curTokenIs(token.DOTDOT) {
+			p.nextToken()
+			element.High = p.ParseExpression()
+		}
+		element.Span = p.spanFrom(elementStart)
+		set.Elements = append(set.Elements, element)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.RBRACKET, "Expected ']' after set elements", "Separate set elements with ',' and close the set with ']', e.g. [1, 3..5].") {
+		return nil
+	}
+	set.Span = p.spanFrom(start)
+	return set
+}
+
+func (p *Parser) parseVarDecl() []ast.Stmt {
+	// Advance to the next token after 'var'
+	p.nextToken()
+
+	if p.curToken.Type != token.IDENT {
+		p.addError(
+			"Expected variable name after 'var'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Variable declarations must start with a valid identifier.",
+		)
+		return nil
+	}
+
+	var decls []ast.Stmt
+	for p.curToken.Type == token.IDENT {
+		names := p.parseIdentTokens()
+
+		if p.curToken.Type != token.COLON {
+			p.addError(
+				"Expected ':' after variable name",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Variable declarations must specify a type after the colon.",
+			)
+			return decls
+		}
+
+		// Advance to the next token after ':'
+		p.nextToken()
+
+		varType, ok := p.parseType("variable")
+		if !ok {
+			return decls
+		}
+		end := endPosition(p.prevToken)
+
+		if p.curToken.Type != token.SEMICOLON {
+			p.addError(
+				"Expected ';' after variable declaration",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Variable declarations must end with a semicolon.",
+			)
+			return decls
+		}
+
+		// Advance to the next token after the semicolon
+		p.nextToken()
+
+		for _, name := range names {
+			p.declareName(name.Literal)
+			decls = append(decls, &ast.VarDecl{Span: ast.Span{Start: position(name), End: end}, Name: name.Literal, Type: varType})
+		}
+	}
+
+	return decls
+}
+
+func (p *Parser) parseConstDecl() []ast.Stmt {
+	p.nextToken()
+
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(
+			"Expected constant name after 'const'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Constant definitions have the form: const Max = 100;",
+		)
+		return nil
+	}
+
+	var decls []ast.Stmt
+	for p.curTokenIs(token.IDENT) {
+		start := p.curToken
+		name := start.Literal
+		p.nextToken()
+
+		if p.curTokenIs(token.COLON) {
+			decl, ok := p.parseTypedConstant(start)
+			if !ok {
+				return decls
+			}
+			decls = append(decls, decl)
+			continue
+		}
+
+		if !p.expectCur(token.EQUAL, "Expected '=' after constant name", "Constant definitions use '=', e.g. const Max = 100;") {
+			return decls
+		}
+
+		value, ok := p.parseConstant("constant definition")
+		if !ok {
+			return decls
+		}
+		span := p.spanFrom(start)
+
+		if !p.expectCur(token.SEMICOLON, "Expected ';' after constant definition", "Constant definitions must end with a semicolon.") {
+			return decls
+		}
+
+		p.declareConstant(name, value)
+		decls = append(decls, &ast.ConstDecl{Span: span, Name: name, Value: value})
+	}
+
+	return decls
+}
+
+func (p *Parser) parseIdentList() []string {
+	var names []string
+	for _, tok := range p.parseIdentTokens() {
+		names = append(names, tok.Literal)
+	}
+	return names
+}
+
+func (p *Parser) parseIdentTokens() []token.Token {
+	toks := []token.Token{p.curToken}
+	p.nextToken()
+
+	for p.curTokenIs(token.COMMA) && p.peekToken.Type == token.IDENT {
+		p.nextToken()
+		toks = append(toks, p.curToken)
+		p.nextToken()
+	}
+
+	return toks
+}
+
+func (p *Parser) parseTypeDecl() []ast.Stmt {
+	p.nextToken()
+
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(
+			"Expected type name after 'type'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Type definitions have the form: type Color = (Red, Green, Blue);",
+		)
+		return nil
+	}
+
+	var decls []ast.Stmt
+	for p.curTokenIs(token.IDENT) {
+		start := p.curToken
+		name := start.Literal
+		p.nextToken()
+
+		if !p.expectCur(token.EQUAL, "Expected '=' after type name", "Type definitions use '=', e.g. type Digit = 0..9;") {
+			return decls
+		}
+
+		typ, ok := p.parseType("type definition")
+		if !ok {
+			return decls
+		}
+		span := p.spanFrom(start)
+
+		if !p.expectCur(token.SEMICOLON, "Expected ';' after type definition", "Type definitions must end with a semicolon.") {
+			return decls
+		}
+
+		p.declareName(name)
+		decls = append(decls, &ast.TypeDecl{Span: span, Name: name, Type: typ})
+	}
+
+	return decls
+}
+
+func (p *Parser) parseType(context string) (ast.TypeExpr, bool) {
+	start := p.curToken
+	switch p.curToken.Type {
+	case token.LPAREN:
+		return p.parseEnumType()
+	case token.PACKED, token.ARRAY, token.RECORD, token.SET, token.FILE:
+		return p.parseStructuredType()
+	case token.CARET:
+		p.nextToken()
+		target, ok := p.parseTypeName("pointer target")
+		return &ast.PointerType{Span: p.spanFrom(start), Target: target}, ok
+	case token.INTEGER, token.REAL, token.BOOLEAN, token.CHAR, token.STRING:
+		if p.curTokenIs(token.STRING) && p.peekToken.Type == token.LBRACKET {
+			return p.parseStringType()
+		}
+		name, ok := p.parseTypeName(context)
+		return &ast.NamedType{Span: span(start), Name: name}, ok
+	case token.IDENT:
+		if _, isConst := p.lookupConstant(p.curToken.Literal); !isConst {
+			name, ok := p.parseTypeName(context)
+			return &ast.NamedType{Span: span(start), Name: name}, ok
+		}
+	}
+	return p.parseSubrangeType()
+}
+
+func (p *Parser) parseTypeName(context string) (string, bool) {
+	switch p.curToken.Type {
+	case token.INTEGER, token.REAL, token.BOOLEAN, token.CHAR, token.STRING, token.IDENT:
+		name := p.curToken.Literal
+		p.nextToken()
+		return name, true
+	default:
+		p.addError(
+			fmt.Sprintf("Expected type for %s", context),
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Use integer, real, boolean, char, string or a type declared in a 'type' section.",
+		)
+		return "", false
+	}
+}
+
+func (p *Parser) parseStructuredType() (ast.TypeExpr, bool) {
+	start := p.curToken
+	if p.curTokenIs(token.PACKED) {
+		p.nextToken()
+	}
+
+	switch p.curToken.Type {
+	case token.ARRAY:
+		return p.parseArrayType(start)
+	case token.RECORD:
+		return p.parseRecordType(start)
+	case token.SET:
+		return p.parseSetType(start)
+	case token.FILE:
+		return p.parseFileType(start)
+	default:
+		p.addError(
+			"Expected structured type after 'packed'",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"Only arrays, records, sets and files can be packed, e.g. packed array[1..10] of char.",
+		)
+		return nil, false
+	}
+}
+
+func (p *Parser) parseArrayType(start token.Token) (ast.TypeExpr, bool) {
+	p.nextToken()
+
+	if !p.expectCur(token.LBRACKET, "Expected '[' after 'array'", "An array type has the form: array[1..10] of integer.") {
+		return nil, false
+	}
+
+	var indexes []ast.TypeExpr
+	for {
+		index, ok := p.parseType("array index")
+		if !ok {
+			return nil, false
+		}
+		indexes = append(indexes, index)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.RBRACKET, "Expected ']' after array index types", "Separate index types with ',' and close the list with ']'.") {
+		return nil, false
+	}
+	if !p.expectCur(token.OF, "Expected 'of' after array index types", "An array type has the form: array[1..10] of integer.") {
+		return nil, false
+	}
+
+	element, ok := p.parseType("array element")
+	if !ok {
+		return nil, false
+	}
+
+	for i := len(indexes) - 1; i >= 0; i-- {
+		element = &ast.ArrayType{Span: p.spanFrom(start), Packed: start.Type == token.PACKED, Index: indexes[i], Element: element}
+	}
+	return element, true
+}
+
+func (p *Parser) parseSetType(start token.Token) (ast.TypeExpr, bool) {
+	p.nextToken()
+
+	if !p.expectCur(token.OF, "Expected 'of' after 'set'", "A set type has the form: set of char, or set of 1..10.") {
+		return nil, false
+	}
+
+	element, ok := p.parseType("set base")
+	if !ok {
+		return nil, false
+	}
+	return &ast.SetType{Span: p.spanFrom(start), Packed: start.Type == token.PACKED, Element: element}, true
+}
+
+func (p *Parser) parseFileType(start token.Token) (ast.TypeExpr, bool) {
+	p.nextToken()
+
+	if !p.expectCur(token.OF, "Expected 'of' after 'file'", "A file type has the form: file of integer. Use text for files of lines.") {
+		return nil, false
+	}
+
+	element, ok := p.parseType("file component")
+	if !ok {
+		return nil, false
+	}
+	return &ast.FileType{Span: p.spanFrom(start), Packed: start.Type == token.PACKED, Element: element}, true
+}
+
+func (p *Parser) parseRecordType(start token.Token) (ast.TypeExpr, bool) {
+	p.nextToken()
+
+	fields, variant, ok := p.parseFieldList()
+	if !ok {
+		return nil, false
+	}
+
+	if !p.expectCur(token.END, "Expected 'end' to close record type", "Separate fields with ';' and close the record with 'end'.") {
+		return nil, false
+	}
+	return &ast.RecordType{Span: p.spanFrom(start), Packed: start.Type == token.PACKED, Fields: fields, Variant: variant}, true
+}
+
+func (p *Parser) parseFieldList() ([]*ast.FieldDecl, *ast.VariantPart, bool) {
+	var fields []*ast.FieldDecl
+	for p.curTokenIs(token.IDENT) {
+		names := p.parseIdentTokens()
+
+		if !p.expectCur(token.COLON, "Expected ':' after field name", "Record fields have the form: x, y: real;") {
+			return nil, nil, false
+		}
+		fieldType, ok := p.parseType("record field")
+		if !ok {
+			return nil, nil, false
+		}
+		for _, name := range names {
+			fields = append(fields, &ast.FieldDecl{Span: ast.Span{Start: position(name), End: endPosition(p.prevToken)}, Name: name.Literal, Type: fieldType})
+		}
+
+		if !p.curTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(token.CASE) {
+		return fields, nil, true
+	}
+	variant, ok := p.parseVariantPart()
+	return fields, variant, ok
+}
+
+func (p *Parser) parseVariantPart() (*ast.VariantPart, bool) {
+	start := p.curToken
+
+	p.nextToken()
+
+	part := &ast.VariantPart{}
+	if p.curTokenIs(token.IDENT) && p.peekToken.Type == token.COLON {
+		part.Tag = p.curToken.Literal
+		p.nextToken()
+		p.nextToken()
+	}
+
+	tagType, ok := p.parseTypeName("variant tag")
+	if !ok {
+		return nil, false
+	}
+	part.TagType = tagType
+
+	if !p.expectCur(token.OF, "Expected 'of' after variant tag", "A variant part has the form: case kind: Shape of Circle: (radius: real); ...") {
+		return nil, false
+	}
+
+	var seen []caseRange
+	for !p.curTokenIs(token.END) && !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.EOF) {
+		variant, ok := p.parseVariant(&seen)
+		if !ok {
+			return nil, false
+		}
+		part.Variants = append(part.Variants, variant)
+
+		if !p.curTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
+	}
+
+	part.Span = p.spanFrom(start)
+	return part, true
+}
+
+func (p *Parser) parseVariant(seen *[]caseRange) (*ast.Variant, bool) {
+	variantStart := p.curToken
+	variant := &ast.Variant{}
+	for {
+		start := p.curToken
+		label, kind, ord, ok := p.parseOrdinalConstant("variant label")
+		if !ok {
+			return nil, false
+		}
+		current := caseRange{kind: kind, low: ord, high: ord}
+		for _, prev := range *seen {
+			if prev.overlaps(current) {
+				p.addErrorAt(start,
+					"Duplicate variant label",
+					"This label selects a variant that an earlier label in the same variant part already selects.",
+					"Each tag value may select only one variant.",
+				)
+				return nil, false
+			}
+		}
+		*seen = append(*seen, current)
+		variant.Labels = append(variant.Labels, label)
+
+		if !p.curTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.COLON, "Expected ':' after variant labels", "Each variant has the form: <label>: (<fields>).") {
+		return nil, false
+	}
+	if !p.expectCur(token.LPAREN, "Expected '(' before variant fields", "Each variant has the form: <label>: (<fields>).") {
+		return nil, false
+	}
+
+	fields, nested, ok := p.parseFieldList()
+	if !ok {
+		return nil, false
+	}
+	variant.Fields = fields
+	variant.Variant = nested
+
+	if !p.expectCur(token.RPAREN, "Expected ')' after variant fields", "Close the fields of each variant with ')'.") {
+		return nil, false
+	}
+	variant.Span = p.spanFrom(variantStart)
+	return variant, true
+}
+
+func (p *Parser) parseEnumType() (ast.TypeExpr, bool) {
+	start := p.curToken
+
+	// Advance to the next token after '('
+	p.nextToken()
+
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(
+			"Expected identifier in enumerated type",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"An enumerated type lists its values, e.g. (Red, Green, Blue).",
+		)
+		return nil, false
+	}
+
+	enum := &ast.EnumType{Values: p.parseIdentList()}
+	if !p.expectCur(token.RPAREN, "Expected ')' after enumerated type", "Separate the values with ',' and close the list with ')'.") {
+		return nil, false
+	}
+	enum.Span = p.spanFrom(start)
+
+	for ord, name := range enum.Values {
+		p.declareEnumConstant(name, enum, ord)
+	}
+	return enum, true
+}
+
+func (p *Parser) parseSubrangeType() (ast.TypeExpr, bool) {
+	start := p.curToken
+
+	low, lowKind, lowOrd, ok := p.parseOrdinalConstant("subrange bound")
+	if !ok {
+		return nil, false
+	}
+
+	if !p.expectCur(token.DOTDOT, "Expected '..' in subrange type", "A subrange type has the form low..high, e.g. 1..10 or 'a'..'z'.") {
+		return nil, false
+	}
+
+	high, highKind, highOrd, ok := p.parseOrdinalConstant("subrange bound")
+	if !ok {
+		return nil, false
+	}
+
+	if highKind != lowKind || highOrd < lowOrd {
+		p.addErrorAt(start,
+			"Invalid subrange type",
+			"Both bounds of a subrange must have the same ordinal type, and the lower bound must not exceed the upper bound.",
+			"Write subranges as low..high, e.g. 1..10 or 'a'..'z'.",
+		)
+		return nil, false
+	}
+
+	return &ast.SubrangeType{Span: p.spanFrom(start), Low: low, High: high}, true
+}
+
+func (p *Parser) parseProcedureDecl() ast.Stmt {
+	start := p.curToken
+	isFunction := p.curTokenIs(token.FUNCTION)
+	kind := p.curToken.Literal
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	decl := &ast.ProcedureDecl{Name: p.curToken.Literal, Function: isFunction}
+	forward := p.lookupForward(decl.Name)
+	p.declareName(decl.Name)
+	p.nextToken()
+
+	p.pushScope()
+	defer p.popScope()
+
+	if p.curTokenIs(token.LPAREN) {
+		params, ok := p.parseParams()
+		if !ok {
+			return nil
+		}
+		decl.Params = params
+	} else if forward != nil {
+		for _, param := range forward.Params {
+			p.declareName(param.Name)
+			p.declareBounds(param.Schema)
+		}
+	}
+
+	if isFunction && !(decl.Params == nil && p.curTokenIs(token.SEMICOLON)) {
+		if !p.expectCur(token.COLON, "Expected ':' and result type after function heading", "A function heading has the form: function f(x: integer): integer;") {
+			return nil
+		}
+		returnType, ok := p.parseTypeName("function result")
+		if !ok {
+			return nil
+		}
+		decl.ReturnType = returnType
+	}
+
+	if !p.expectCur(token.SEMICOLON, fmt.Sprintf("Expected ';' after %s heading", kind), "Terminate the heading with ';' before the routine's declarations and body.") {
+		return nil
+	}
+
+	if p.curTokenIs(token.FORWARD) {
+		p.nextToken()
+		decl.Forward = true
+		decl.Span = p.spanFrom(start)
+		p.declareForward(decl)
+		if !p.expectCur(token.SEMICOLON, "Expected ';' after 'forward'", "A forward declaration has the form: procedure p(x: integer); forward;") {
+			return nil
+		}
+		return decl
+	}
+
+	decls, body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	decl.Declarations = decls
+	decl.Body = body
+	decl.Span = p.spanFrom(start)
+
+	if !p.expectCur(token.SEMICOLON, fmt.Sprintf("Expected ';' after %s body", kind), "A procedure or function body must be followed by ';'.") {
+		return nil
+	}
+
+	return decl
+}
+
+func (p *Parser) parseParams() ([]*ast.Param, bool) {
+	// Advance to the next token after '('
+	p.nextToken()
+
+	var params []*ast.Param
+	for {
+		if p.curTokenIs(token.PROCEDURE) || p.curTokenIs(token.FUNCTION) {
+			param, ok := p.parseRoutineParam()
+			if !ok {
+				return nil, false
+			}
+			params = append(params, param)
+			if !p.curTokenIs(token.SEMICOLON) {
+				break
+			}
+			p.nextToken()
+			continue
+		}
+
+		isVar := p.curTokenIs(token.VAR)
+		if isVar {
+			p.nextToken()
+		}
+
+		if !p.curTokenIs(token.IDENT) {
+			p.addError(
+				"Expected parameter name",
+				fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+				"Parameters have the form: (a, b: integer; var c: real).",
+			)
+			return nil, false
+		}
+		names := p.parseIdentTokens()
+
+		if !p.expectCur(token.COLON, "Expected ':' after parameter name", "Every parameter group needs a type, e.g. (a, b: integer).") {
+			return nil, false
+		}
+		var paramType string
+		var schema *ast.ConformantArray
+		ok := false
+		if p.curTokenIs(token.ARRAY) || p.curTokenIs(token.PACKED) {
+			schema, ok = p.parseConformantArray()
+		} else {
+			paramType, ok = p.parseTypeName("parameter")
+		}
+		if !ok {
+			return nil, false
+		}
+
+		for _, name := range names {
+			p.declareName(name.Literal)
+			params = append(params, &ast.Param{Span: ast.Span{Start: position(name), End: endPosition(p.prevToken)}, Name: name.Literal, Type: paramType, IsVar: isVar, Schema: schema})
+		}
+		p.declareBounds(schema)
+
+		if !p.curTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.RPAREN, "Expected ')' after parameter list", "Separate parameter groups with ';' and close the list with ')'.") {
+		return nil, false
+	}
+	return params, true
+}
+
+func (p *Parser) parseConformantArray() (*ast.ConformantArray, bool) {
+	start := p.curToken
+	packed := p.curTokenIs(token.PACKED)
+	if packed {
+		p.nextToken()
+	}
+
+	if !p.expectCur(token.ARRAY, "Expected 'array' after 'packed'", "A packed conformant array has the form: packed array[lo..hi: integer] of char.") {
+		return nil, false
+	}
+	if !p.expectCur(token.LBRACKET, "Expected '[' after 'array'", "A conformant array has the form: array[lo..hi: integer] of real.") {
+		return nil, false
+	}
+
+	var schemas []*ast.ConformantArray
+	for {
+		low, ok := p.parseBoundIdentifier()
+		if !ok {
+			return nil, false
+		}
+		if !p.expectCur(token.DOTDOT, "Expected '..' between bound identifiers", "An index of a conformant array has the form lo..hi: integer.") {
+			return nil, false
+		}
+		high, ok := p.parseBoundIdentifier()
+		if !ok {
+			return nil, false
+		}
+		if !p.expectCur(token.COLON, "Expected ':' and index type after bound identifiers", "An index of a conformant array has the form lo..hi: integer.") {
+			return nil, false
+		}
+		indexType, ok := p.parseTypeName("conformant array index")
+		if !ok {
+			return nil, false
+		}
+		schemas = append(schemas, &ast.ConformantArray{Packed: packed, Low: low, High: high, IndexType: indexType})
+
+		if !p.curTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if packed && len(schemas) > 1 {
+		p.addErrorAt(start,
+			"Packed conformant array may have only one index",
+			"Only the last dimension of a conformant array may be packed.",
+			"Write array[i..j: integer] of packed array[k..l: integer] of char.",
+		)
+		return nil, false
+	}
+	if !p.expectCur(token.RBRACKET, "Expected ']' after conformant array indexes", "Separate the indexes of a conformant array with ';' and close the list with ']'.") {
+		return nil, false
+	}
+	if !p.expectCur(token.OF, "Expected 'of' after conformant array indexes", "A conformant array has the form: array[lo..hi: integer] of real.") {
+		return nil, false
+	}
+
+	var element ast.TypeExpr
+	if p.curTokenIs(token.ARRAY) || p.curTokenIs(token.PACKED) {
+		if packed {
+			p.addError(
+				"Element of a packed conformant array must be a type identifier",
+				"Only the last dimension of a conformant array may be packed.",
+				"Write array[i..j: integer] of packed array[k..l: integer] of char.",
+			)
+			return nil, false
+		}
+		schema, ok := p.parseConformantArray()
+		if !ok {
+			return nil, false
+		}
+		element = schema
+	} else {
+		tok := p.curToken
+		name, ok := p.parseTypeName("conformant array element")
+		if !ok {
+			return nil, false
+		}
+		element = &ast.NamedType{Span: span(tok), Name: name}
+	}
+
+	for i := len(schemas) - 1; i >= 0; i-- {
+		schemas[i].Span = p.spanFrom(start)
+		schemas[i].Element = element
+		element = schemas[i]
+	}
+	return schemas[0], true
+}
+
+func (p *Parser) parseBoundIdentifier() (string, bool) {
+	if !p.curTokenIs(token.IDENT) {
+		p.addError(
+			"Expected bound identifier in conformant array",
+			fmt.Sprintf("Got %q (%s) instead.", p.curToken.Literal, p.curToken.Type),
+			"The bounds of a conformant array are named, not given: array[lo..hi: integer] of real.",
+		)
+		return "", false
+	}
+	name := p.curToken.Literal
+	p.nextToken()
+	return name, true
+}
+
+func (p *Parser) declareBounds(schema *ast.ConformantArray) {
+	for schema != nil {
+		p.declareName(schema.Low)
+		p.declareName(schema.High)
+		schema, _ = schema.Element.(*ast.ConformantArray)
+	}
+}
+
+func (p *Parser) parseRoutineParam() (*ast.Param, bool) {
+	start := p.curToken
+	isFunction := p.curTokenIs(token.FUNCTION)
+
+	if !p.expectPeek(token.IDENT) {
+		return nil, false
+	}
+	heading := &ast.ProcedureDecl{Name: p.curToken.Literal, Function: isFunction}
+	p.nextToken()
+
+	if p.curTokenIs(token.LPAREN) {
+		p.pushScope()
+		params, ok := p.parseParams()
+		p.popScope()
+		if !ok {
+			return nil, false
+		}
+		heading.Params = params
+	}
+
+	if isFunction {
+		if !p.expectCur(token.COLON, "Expected ':' and result type after function heading", "A functional parameter has the form: function f(x: real): real.") {
+			return nil, false
+		}
+		returnType, ok := p.parseTypeName("function result")
+		if !ok {
+			return nil, false
+		}
+		heading.ReturnType = returnType
+	}
 
-	return &ast.VarDecl{Name: name, Type: varType}
+	heading.Span = p.spanFrom(start)
+	p.declareName(heading.Name)
+	return &ast.Param{Span: heading.Span, Name: heading.Name, Routine: heading}, true
 }
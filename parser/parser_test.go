@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"fmt"
+	"math"
 	"pastel/ast"
 	"pastel/lexer"
+	"strings"
 	"testing"
 )
 
@@ -63,7 +66,7 @@ end.`
 		t.Fatalf("variable name wrong. expected=%q, got=%q", "x", varDecl.Name)
 	}
 
-	if varDecl.Type != "integer" {
+	if varDecl.Type.String() != "integer" {
 		t.Fatalf("variable type wrong. expected=%q, got=%q", "integer", varDecl.Type)
 	}
 }
@@ -94,8 +97,9 @@ end.`
 		t.Fatalf("expected *ast.AssignStmt, got %T", prog.Main.Statements[0])
 	}
 
-	if assignStmt.Name != "x" {
-		t.Fatalf("assignment name wrong. expected=%q, got=%q", "x", assignStmt.Name)
+	target, ok := assignStmt.Target.(*ast.Identifier)
+	if !ok || target.Value != "x" {
+		t.Fatalf("assignment target wrong. expected=%q, got=%+v", "x", assignStmt.Target)
 	}
 
 	intLit, ok := assignStmt.Value.(*ast.IntegerLiteral)
@@ -135,9 +139,13 @@ end.`
 		t.Fatalf("expected *ast.PrintStmt, got %T", prog.Main.Statements[1])
 	}
 
-	ident, ok := printStmt.Argument.(*ast.Identifier)
+	if len(printStmt.Args) != 1 {
+		t.Fatalf("expected 1 argument, got %d", len(printStmt.Args))
+	}
+
+	ident, ok := printStmt.Args[0].(*ast.Identifier)
 	if !ok {
-		t.Fatalf("expected *ast.Identifier, got %T", printStmt.Argument)
+		t.Fatalf("expected *ast.Identifier, got %T", printStmt.Args[0])
 	}
 
 	if ident.Value != "x" {
@@ -421,7 +429,7 @@ end.`
 		t.Fatalf("expected VarDecl, got %T", prog.Declarations[0])
 	}
 
-	if decl.Type != "real" {
+	if decl.Type.String() != "real" {
 		t.Fatalf("expected type 'real', got %q", decl.Type)
 	}
 }
@@ -447,7 +455,7 @@ end.`
 		t.Fatalf("expected VarDecl, got %T", prog.Declarations[0])
 	}
 
-	if decl.Type != "boolean" {
+	if decl.Type.String() != "boolean" {
 		t.Fatalf("expected type 'boolean', got %q", decl.Type)
 	}
 }
@@ -473,7 +481,7 @@ end.`
 		t.Fatalf("expected VarDecl, got %T", prog.Declarations[0])
 	}
 
-	if decl.Type != "string" {
+	if decl.Type.String() != "string" {
 		t.Fatalf("expected type 'string', got %q", decl.Type)
 	}
 }
@@ -499,7 +507,7 @@ end.`
 		t.Fatalf("expected VarDecl, got %T", prog.Declarations[0])
 	}
 
-	if decl.Type != "char" {
+	if decl.Type.String() != "char" {
 		t.Fatalf("expected type 'char', got %q", decl.Type)
 	}
 }
@@ -604,3 +612,1780 @@ end.`
 		t.Fatalf("expected 'X', got %c", lit.Value)
 	}
 }
+
+func TestParser_IfThenElse(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  if x < 10 then x := 1 else x := 2
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	ifStmt, ok := prog.Main.Statements[0].(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("expected IfStmt, got %T", prog.Main.Statements[0])
+	}
+
+	cond, ok := ifStmt.Condition.(*ast.BinaryExpr)
+	if !ok {
+		t.Fatalf("expected BinaryExpr condition, got %T", ifStmt.Condition)
+	}
+	if cond.Operator.Literal != "<" {
+		t.Fatalf("expected '<' operator, got %q", cond.Operator.Literal)
+	}
+
+	if _, ok := ifStmt.Then.(*ast.AssignStmt); !ok {
+		t.Fatalf("expected then branch to be AssignStmt, got %T", ifStmt.Then)
+	}
+	if _, ok := ifStmt.Else.(*ast.AssignStmt); !ok {
+		t.Fatalf("expected else branch to be AssignStmt, got %T", ifStmt.Else)
+	}
+}
+
+func TestParser_DanglingElseBindsToNearestIf(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  if true then if false then x := 1 else x := 2;
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	outer := prog.Main.Statements[0].(*ast.IfStmt)
+	if outer.Else != nil {
+		t.Fatalf("expected outer if to have no else branch, got %T", outer.Else)
+	}
+
+	inner, ok := outer.Then.(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("expected inner IfStmt, got %T", outer.Then)
+	}
+	if inner.Else == nil {
+		t.Fatalf("expected inner if to own the else branch")
+	}
+}
+
+func TestParser_RelationalAndBooleanPrecedence(t *testing.T) {
+	input := `program test;
+var b: boolean;
+begin
+  b := 1 + 2 < 4;
+  b := not true or false;
+  b := (1 < 2) and (3 < 4);
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	rel := prog.Main.Statements[0].(*ast.AssignStmt).Value.(*ast.BinaryExpr)
+	if rel.Operator.Literal != "<" {
+		t.Fatalf("expected '<' at the top level, got %q", rel.Operator.Literal)
+	}
+	if _, ok := rel.Left.(*ast.BinaryExpr); !ok {
+		t.Fatalf("expected left of '<' to be 1 + 2, got %T", rel.Left)
+	}
+
+	or := prog.Main.Statements[1].(*ast.AssignStmt).Value.(*ast.BinaryExpr)
+	if or.Operator.Literal != "or" {
+		t.Fatalf("expected 'or' at the top level, got %q", or.Operator.Literal)
+	}
+	if _, ok := or.Left.(*ast.UnaryExpr); !ok {
+		t.Fatalf("expected 'not' to bind tighter than 'or', got %T", or.Left)
+	}
+
+	and := prog.Main.Statements[2].(*ast.AssignStmt).Value.(*ast.BinaryExpr)
+	if and.Operator.Literal != "and" {
+		t.Fatalf("expected 'and' at the top level, got %q", and.Operator.Literal)
+	}
+}
+
+func TestParser_LastStatementWithoutSemicolon(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  x := 1;
+  writeln(x)
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(prog.Main.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(prog.Main.Statements))
+	}
+}
+
+func TestParserErrors_IfWithoutThen(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  if x < 1 x := 2;
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+}
+
+func TestParser_WhileLoop(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  while x < 10 do x := x + 1;
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	loop, ok := prog.Main.Statements[0].(*ast.WhileStmt)
+	if !ok {
+		t.Fatalf("expected WhileStmt, got %T", prog.Main.Statements[0])
+	}
+	if _, ok := loop.Body.(*ast.AssignStmt); !ok {
+		t.Fatalf("expected AssignStmt body, got %T", loop.Body)
+	}
+}
+
+func TestParser_RepeatLoop(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  repeat
+    x := x + 1;
+    writeln(x)
+  until x = 3
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	loop, ok := prog.Main.Statements[0].(*ast.RepeatStmt)
+	if !ok {
+		t.Fatalf("expected RepeatStmt, got %T", prog.Main.Statements[0])
+	}
+	if len(loop.Body) != 2 {
+		t.Fatalf("expected 2 body statements, got %d", len(loop.Body))
+	}
+	if loop.Condition == nil {
+		t.Fatalf("expected until condition, got nil")
+	}
+}
+
+func TestParser_ForLoop(t *testing.T) {
+	tests := []struct {
+		input  string
+		downto bool
+	}{
+		{"for i := 1 to 10 do writeln(i)", false},
+		{"for i := 10 downto 1 do writeln(i)", true},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nvar i: integer;\nbegin\n  " + tt.input + "\nend."
+
+		l := lexer.New(input)
+		p := New(l)
+		prog := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		loop, ok := prog.Main.Statements[0].(*ast.ForStmt)
+		if !ok {
+			t.Fatalf("expected ForStmt, got %T", prog.Main.Statements[0])
+		}
+		if loop.Variable != "i" {
+			t.Fatalf("expected control variable 'i', got %q", loop.Variable)
+		}
+		if loop.Downto != tt.downto {
+			t.Fatalf("expected Downto=%v, got %v", tt.downto, loop.Downto)
+		}
+		if _, ok := loop.Body.(*ast.PrintStmt); !ok {
+			t.Fatalf("expected PrintStmt body, got %T", loop.Body)
+		}
+	}
+}
+
+func TestParserErrors_ForWithoutDo(t *testing.T) {
+	input := `program test;
+var i: integer;
+begin
+  for i := 1 to 10 writeln(i);
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+}
+
+func TestParser_MultipleVariablesPerDeclaration(t *testing.T) {
+	input := `program test;
+var a, b: integer;
+    c: real;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []struct{ name, typ string }{{"a", "integer"}, {"b", "integer"}, {"c", "real"}}
+	if len(prog.Declarations) != len(expected) {
+		t.Fatalf("expected %d declarations, got %d", len(expected), len(prog.Declarations))
+	}
+	for i, want := range expected {
+		decl := prog.Declarations[i].(*ast.VarDecl)
+		if decl.Name != want.name || decl.Type.String() != want.typ {
+			t.Fatalf("declaration %d wrong. expected=%s: %s, got=%s: %s", i, want.name, want.typ, decl.Name, decl.Type)
+		}
+	}
+}
+
+func TestParser_ProcedureDeclaration(t *testing.T) {
+	input := `program test;
+procedure swap(var a, b: integer; verbose: boolean);
+var t: integer;
+begin
+  t := a;
+  a := b;
+  b := t
+end;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl, ok := prog.Declarations[0].(*ast.ProcedureDecl)
+	if !ok {
+		t.Fatalf("expected ProcedureDecl, got %T", prog.Declarations[0])
+	}
+	if decl.Name != "swap" || decl.IsFunction() {
+		t.Fatalf("expected procedure 'swap', got name=%q function=%v", decl.Name, decl.IsFunction())
+	}
+
+	expected := []ast.Param{
+		{Name: "a", Type: "integer", IsVar: true},
+		{Name: "b", Type: "integer", IsVar: true},
+		{Name: "verbose", Type: "boolean", IsVar: false},
+	}
+	if len(decl.Params) != len(expected) {
+		t.Fatalf("expected %d params, got %d", len(expected), len(decl.Params))
+	}
+	for i, want := range expected {
+		got := *decl.Params[i]
+		got.Span = ast.Span{}
+		if got != want {
+			t.Fatalf("param %d wrong. expected=%+v, got=%+v", i, want, got)
+		}
+	}
+
+	if len(decl.Declarations) != 1 {
+		t.Fatalf("expected 1 local declaration, got %d", len(decl.Declarations))
+	}
+	if len(decl.Body.Statements) != 3 {
+		t.Fatalf("expected 3 body statements, got %d", len(decl.Body.Statements))
+	}
+}
+
+func TestParser_FunctionDeclarationAndCalls(t *testing.T) {
+	input := `program test;
+var x: integer;
+function double(n: integer): integer;
+begin
+  double := n * 2
+end;
+procedure greet;
+begin
+  writeln('hi')
+end;
+begin
+  greet;
+  x := double(double(3)) + 1
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := prog.Declarations[1].(*ast.ProcedureDecl)
+	if !fn.IsFunction() || fn.ReturnType != "integer" {
+		t.Fatalf("expected integer function, got return type %q", fn.ReturnType)
+	}
+
+	call, ok := prog.Main.Statements[0].(*ast.CallStmt)
+	if !ok {
+		t.Fatalf("expected CallStmt, got %T", prog.Main.Statements[0])
+	}
+	if call.Name != "greet" || len(call.Args) != 0 {
+		t.Fatalf("expected call to greet with no args, got %q with %d args", call.Name, len(call.Args))
+	}
+
+	sum := prog.Main.Statements[1].(*ast.AssignStmt).Value.(*ast.BinaryExpr)
+	outer, ok := sum.Left.(*ast.CallExpr)
+	if !ok {
+		t.Fatalf("expected CallExpr, got %T", sum.Left)
+	}
+	if _, ok := outer.Args[0].(*ast.CallExpr); !ok {
+		t.Fatalf("expected nested CallExpr argument, got %T", outer.Args[0])
+	}
+}
+
+func TestParser_ProceduralParameters(t *testing.T) {
+	input := `program test;
+function integrate(function f(x: real): real; procedure log; a, b: real): real;
+begin
+  integrate := f(a)
+end;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := prog.Declarations[0].(*ast.ProcedureDecl)
+	if len(decl.Params) != 4 {
+		t.Fatalf("expected 4 parameters, got %d", len(decl.Params))
+	}
+
+	f := decl.Params[0]
+	if f.Name != "f" || f.Type != "" || f.Routine == nil {
+		t.Fatalf("expected functional parameter f, got %#v", f)
+	}
+	if !f.Routine.IsFunction() || f.Routine.ReturnType != "real" || len(f.Routine.Params) != 1 || f.Routine.Params[0].Type != "real" {
+		t.Fatalf("wrong heading for f: %#v", f.Routine)
+	}
+	if got := input[f.Start.Offset:f.End.Offset]; got != "function f(x: real): real" {
+		t.Fatalf("wrong span for f, got %q", got)
+	}
+
+	log := decl.Params[1]
+	if log.Routine == nil || log.Routine.IsFunction() || log.Routine.Params != nil {
+		t.Fatalf("expected procedural parameter log, got %#v", log)
+	}
+}
+
+func TestParser_ForwardDeclarations(t *testing.T) {
+	input := `program test;
+const n = 10;
+function isOdd(n: integer): boolean; forward;
+function isEven(n: integer): boolean;
+begin
+  if n = 0 then isEven := true else isEven := isOdd(n - 1)
+end;
+function isOdd;
+begin
+  n := n; { the parameter, not the constant }
+  if n = 0 then isOdd := false else isOdd := isEven(n - 1)
+end;
+begin
+  writeln(isOdd(n))
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	forward := prog.Declarations[1].(*ast.ProcedureDecl)
+	if !forward.Forward || forward.Body != nil || len(forward.Params) != 1 || forward.ReturnType != "boolean" {
+		t.Fatalf("expected forward heading of isOdd, got %#v", forward)
+	}
+	if got := input[forward.Start.Offset:forward.End.Offset]; got != "function isOdd(n: integer): boolean; forward" {
+		t.Fatalf("wrong span for forward declaration, got %q", got)
+	}
+
+	body := prog.Declarations[3].(*ast.ProcedureDecl)
+	if body.Forward || body.Body == nil || body.Params != nil || body.ReturnType != "" || !body.IsFunction() {
+		t.Fatalf("expected block of isOdd without a heading, got %#v", body)
+	}
+}
+
+func TestParserErrors_ForwardDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"missing semicolon after forward",
+			"program test;\nprocedure p; forward\nprocedure p;\nbegin\nend;\nbegin\nend.",
+			"Expected ';' after 'forward'",
+		},
+		{
+			"function with parameters but no result type",
+			"program test;\nfunction f(n: integer); forward;\nbegin\nend.",
+			"Expected ':' and result type after function heading",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParserErrors_FunctionWithoutResultType(t *testing.T) {
+	input := `program test;
+function f(n: integer);
+begin
+end;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+}
+
+func TestParser_CaseStatement(t *testing.T) {
+	input := `program test;
+var n: integer;
+begin
+  case n of
+    1, 3: writeln('odd');
+    -2, 4..8: writeln('other');
+  else
+    writeln('none');
+    n := 0
+  end
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := prog.Main.Statements[0].(*ast.CaseStmt)
+	if !ok {
+		t.Fatalf("expected CaseStmt, got %T", prog.Main.Statements[0])
+	}
+	if len(stmt.Branches) != 2 {
+		t.Fatalf("expected 2 branches, got %d", len(stmt.Branches))
+	}
+	if len(stmt.Branches[0].Labels) != 2 {
+		t.Fatalf("expected 2 labels in first branch, got %d", len(stmt.Branches[0].Labels))
+	}
+
+	negative := stmt.Branches[1].Labels[0].Low.(*ast.IntegerLiteral)
+	if negative.Value != -2 {
+		t.Fatalf("expected signed label -2, got %d", negative.Value)
+	}
+
+	rng := stmt.Branches[1].Labels[1]
+	if rng.High == nil {
+		t.Fatalf("expected 4..8 to be parsed as a range")
+	}
+
+	if stmt.Else == nil || len(stmt.Else.Statements) != 2 {
+		t.Fatalf("expected else part with 2 statements, got %+v", stmt.Else)
+	}
+}
+
+func TestParser_CaseOtherwise(t *testing.T) {
+	input := `program test;
+var c: char;
+begin
+  case c of
+    'a'..'z': writeln('lower')
+  otherwise writeln('other')
+  end
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := prog.Main.Statements[0].(*ast.CaseStmt)
+	if stmt.Else == nil {
+		t.Fatalf("expected otherwise part")
+	}
+}
+
+func TestParserErrors_DuplicateCaseLabel(t *testing.T) {
+	tests := []struct {
+		labels         string
+		expectedColumn int
+	}{
+		{"1: x := 1; 2, 1: x := 2", 27},
+		{"1..5: x := 1; 3: x := 2", 27},
+		{"'a': x := 1; 'b'..'d': x := 2; 'c': x := 3", 44},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nvar x: integer;\nbegin\n  case x of " + tt.labels + " end\nend."
+
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected duplicate label error, got none", tt.labels)
+		}
+		err := p.Errors()[0]
+		if err.Msg != "Duplicate case label" {
+			t.Fatalf("%s: expected duplicate label error, got %q", tt.labels, err.Msg)
+		}
+		if err.Line != 4 || err.Column != tt.expectedColumn {
+			t.Fatalf("%s: expected error at 4:%d, got %d:%d", tt.labels, tt.expectedColumn, err.Line, err.Column)
+		}
+	}
+}
+
+func TestParserErrors_InvalidCaseLabelsRecover(t *testing.T) {
+	tests := []struct {
+		labels   string
+		expected string
+	}{
+		{"1: x := 1; 1: x := 2; 3: x := 3", "Duplicate case label"},
+		{"5..1: x := 1; 6: x := 2", "Invalid case label range"},
+		{"1..'z': x := 1; 6: x := 2", "Invalid case label range"},
+	}
+
+	for _, tt := range tests {
+		input := "program test;\nvar x: integer;\nbegin\n  case x of " + tt.labels + " end;\n  x := 0\nend."
+
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Fatalf("%s: expected exactly one error, got %d: %v", tt.labels, len(p.Errors()), p.Errors())
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.labels, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_Comments(t *testing.T) {
+	input := `{ Sums two numbers }
+program test;
+var x: integer; (* the result *)
+begin
+  x := 1 { first } + 2; (* second
+  spans lines *)
+  writeln(x)
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(prog.Main.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(prog.Main.Statements))
+	}
+}
+
+func TestParserErrors_UnterminatedComment(t *testing.T) {
+	input := `program test;
+begin
+  (* forgot to close
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+
+	err := p.Errors()[0]
+	if err.Msg != "Unterminated comment" {
+		t.Fatalf("expected 'Unterminated comment' error, got %q", err.Msg)
+	}
+	if err.Line != 3 || err.Column != 3 {
+		t.Fatalf("expected error at 3:3, got %d:%d", err.Line, err.Column)
+	}
+}
+
+func TestParser_ConstDeclarations(t *testing.T) {
+	input := `program test;
+const
+  Max = 100;
+  Pi = 3.14159;
+  Greeting = 'hi';
+  Initial = 'G';
+  Size = Max * 2 + 1;
+  Low = -Max;
+  Half = Pi / 2;
+  Debug = Max > 50;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		name     string
+		expected ast.Expr
+	}{
+		{"max", &ast.IntegerLiteral{Value: 100}},
+		{"pi", &ast.RealLiteral{Value: 3.14159}},
+		{"greeting", &ast.StringLiteral{Value: "hi"}},
+		{"initial", &ast.CharLiteral{Value: 'G'}},
+		{"size", &ast.IntegerLiteral{Value: 201}},
+		{"low", &ast.IntegerLiteral{Value: -100}},
+		{"half", &ast.RealLiteral{Value: 3.14159 / 2}},
+		{"debug", &ast.BooleanLiteral{Value: true}},
+	}
+
+	if len(prog.Declarations) != len(tests) {
+		t.Fatalf("expected %d declarations, got %d", len(tests), len(prog.Declarations))
+	}
+	for i, tt := range tests {
+		decl, ok := prog.Declarations[i].(*ast.ConstDecl)
+		if !ok {
+			t.Fatalf("expected ConstDecl, got %T", prog.Declarations[i])
+		}
+		if decl.Name != tt.name {
+			t.Fatalf("constant %d name wrong. expected=%q, got=%q", i, tt.name, decl.Name)
+		}
+		if value := positioned(decl.Value, ast.Span{}); fmt.Sprintf("%#v", value) != fmt.Sprintf("%#v", tt.expected) {
+			t.Fatalf("constant %s value wrong. expected=%#v, got=%#v", tt.name, tt.expected, value)
+		}
+	}
+}
+
+func TestParser_ConstantsAsCaseLabels(t *testing.T) {
+	input := `program test;
+const Low = 1; High = 5;
+var n: integer;
+begin
+  case n of
+    Low..High: writeln('in range');
+    High + 1: writeln('just above')
+  end
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := prog.Main.Statements[0].(*ast.CaseStmt)
+	high := stmt.Branches[1].Labels[0].Low.(*ast.IntegerLiteral)
+	if high.Value != 6 {
+		t.Fatalf("expected folded label 6, got %d", high.Value)
+	}
+}
+
+func TestParserErrors_Constants(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"variable in constant expression",
+			"program test;\nvar n: integer;\nconst Max = n + 1;\nbegin\nend.",
+			"Expected constant expression in constant definition",
+		},
+		{
+			"duplicate label through constant",
+			"program test;\nconst One = 1;\nvar n: integer;\nbegin\n  case n of 1: n := 0; One: n := 1 end\nend.",
+			"Duplicate case label",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_LocalVariableShadowsConstant(t *testing.T) {
+	input := `program test;
+const n = 1;
+procedure p;
+var n: integer;
+begin
+  n := 2
+end;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
+func TestParser_TypeDeclarations(t *testing.T) {
+	input := `program test;
+const Max = 10;
+type
+  Color = (Red, Green, Blue);
+  Index = 1..Max;
+  Letter = 'a'..'z';
+  Warm = Red..Green;
+  Count = integer;
+var
+  c: Color;
+  d: (Up, Down);
+begin
+  case c of
+    Red: d := Up;
+    Green, Blue: d := Down
+  end
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []struct{ name, typ string }{
+		{"color", "(red, green, blue)"},
+		{"index", "1..10"},
+		{"letter", "'a'..'z'"},
+		{"warm", "red..green"},
+		{"count", "integer"},
+	}
+	for i, want := range expected {
+		decl, ok := prog.Declarations[i+1].(*ast.TypeDecl)
+		if !ok {
+			t.Fatalf("declaration %d: expected *ast.TypeDecl, got %T", i+1, prog.Declarations[i+1])
+		}
+		if decl.Name != want.name || decl.Type.String() != want.typ {
+			t.Fatalf("declaration %d wrong. expected=%s = %s, got=%s = %s", i+1, want.name, want.typ, decl.Name, decl.Type)
+		}
+	}
+
+	decl := prog.Declarations[7].(*ast.VarDecl)
+	if _, ok := decl.Type.(*ast.EnumType); !ok {
+		t.Fatalf("expected anonymous enumerated type, got %T", decl.Type)
+	}
+}
+
+func TestParserErrors_TypeDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"reversed subrange",
+			"program test;\ntype Bad = 10..1;\nbegin\nend.",
+			"Invalid subrange type",
+		},
+		{
+			"mixed subrange bounds",
+			"program test;\ntype Bad = 1..'z';\nbegin\nend.",
+			"Invalid subrange type",
+		},
+		{
+			"real subrange bound",
+			"program test;\ntype Bad = 1.5..2;\nbegin\nend.",
+			"Expected ordinal constant in subrange bound",
+		},
+		{
+			"missing equals",
+			"program test;\ntype Color (Red, Green);\nbegin\nend.",
+			"Expected '=' after type name",
+		},
+		{
+			"duplicate enumeration label",
+			"program test;\ntype Color = (Red, Green);\nvar c: Color;\nbegin\n  case c of Red, Green: c := Red; Red: c := Green end\nend.",
+			"Duplicate case label",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_ArrayTypesAndIndexing(t *testing.T) {
+	input := `program test;
+var
+  m: array[1..3, 'a'..'c'] of real;
+  s: packed array[1..5] of char;
+begin
+  m[1, 'b'] := m[2]['c']
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	m := prog.Declarations[0].(*ast.VarDecl)
+	if m.Type.String() != "array[1..3] of array['a'..'c'] of real" {
+		t.Fatalf("wrong type for m, got %s", m.Type)
+	}
+	s := prog.Declarations[1].(*ast.VarDecl)
+	if s.Type.String() != "packed array[1..5] of char" {
+		t.Fatalf("wrong type for s, got %s", s.Type)
+	}
+
+	assign := prog.Main.Statements[0].(*ast.AssignStmt)
+	for _, expr := range []ast.Expr{assign.Target, assign.Value} {
+		outer, ok := expr.(*ast.IndexExpr)
+		if !ok {
+			t.Fatalf("expected *ast.IndexExpr, got %T", expr)
+		}
+		inner, ok := outer.Array.(*ast.IndexExpr)
+		if !ok {
+			t.Fatalf("expected nested *ast.IndexExpr, got %T", outer.Array)
+		}
+		if ident, ok := inner.Array.(*ast.Identifier); !ok || ident.Value != "m" {
+			t.Fatalf("expected indexed variable m, got %+v", inner.Array)
+		}
+		if _, ok := outer.Index.(*ast.CharLiteral); !ok {
+			t.Fatalf("expected char index in second dimension, got %T", outer.Index)
+		}
+	}
+}
+
+func TestParserErrors_Arrays(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"missing of",
+			"program test;\nvar a: array[1..3] integer;\nbegin\nend.",
+			"Expected 'of' after array index types",
+		},
+		{
+			"unclosed index type list",
+			"program test;\nvar a: array[1..3 of integer;\nbegin\nend.",
+			"Expected ']' after array index types",
+		},
+		{
+			"unclosed index",
+			"program test;\nvar a: array[1..3] of integer;\nbegin\n  a[1 := 2\nend.",
+			"Expected ']' after array index",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_RecordTypesAndWith(t *testing.T) {
+	input := `program test;
+type
+  Kind = (Circle, Square);
+  Shape = record
+    x, y: real;
+    case kind: Kind of
+      Circle: (radius: real);
+      Square: (side: real; case boolean of true: (filled: char))
+  end;
+var a, b: Shape;
+begin
+  a.x := b.y;
+  with a, b do
+    x := 1
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	record, ok := prog.Declarations[1].(*ast.TypeDecl).Type.(*ast.RecordType)
+	if !ok {
+		t.Fatalf("expected *ast.RecordType, got %T", prog.Declarations[1].(*ast.TypeDecl).Type)
+	}
+	if len(record.Fields) != 2 || record.Fields[0].Name != "x" || record.Fields[1].Name != "y" {
+		t.Fatalf("wrong fixed fields: %+v", record.Fields)
+	}
+	part := record.Variant
+	if part == nil || part.Tag != "kind" || len(part.Variants) != 2 {
+		t.Fatalf("wrong variant part: %+v", part)
+	}
+	if nested := part.Variants[1].Variant; nested == nil || nested.Tag != "" || nested.TagType != "boolean" {
+		t.Fatalf("expected untagged nested variant part, got %+v", nested)
+	}
+
+	assign := prog.Main.Statements[0].(*ast.AssignStmt)
+	if field, ok := assign.Target.(*ast.FieldExpr); !ok || field.Field != "x" {
+		t.Fatalf("expected field target x, got %+v", assign.Target)
+	}
+
+	outer, ok := prog.Main.Statements[1].(*ast.WithStmt)
+	if !ok {
+		t.Fatalf("expected *ast.WithStmt, got %T", prog.Main.Statements[1])
+	}
+	inner, ok := outer.Body.(*ast.WithStmt)
+	if !ok || inner.Record.(*ast.Identifier).Value != "b" {
+		t.Fatalf("expected nested with over b, got %+v", outer.Body)
+	}
+}
+
+func TestParserErrors_Records(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"unclosed record",
+			"program test;\ntype R = record x: integer;\nbegin\nend.",
+			"Expected 'end' to close record type",
+		},
+		{
+			"duplicate variant label",
+			"program test;\ntype R = record case k: integer of 1: (a: integer); 1: (b: char) end;\nbegin\nend.",
+			"Duplicate variant label",
+		},
+		{
+			"variant without parentheses",
+			"program test;\ntype R = record case k: integer of 1: a: integer end;\nbegin\nend.",
+			"Expected '(' before variant fields",
+		},
+		{
+			"with without do",
+			"program test;\nvar r: record x: integer end;\nbegin\n  with r x := 1\nend.",
+			"Expected 'do' after with records",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_SetTypesAndConstructors(t *testing.T) {
+	input := `program test;
+var s: set of 'a'..'z';
+begin
+  s := [];
+  s := ['a', 'c'..'f'];
+  if 'b' in s + ['b'] then s := s * ['a']
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := prog.Declarations[0].(*ast.VarDecl)
+	if decl.Type.String() != "set of 'a'..'z'" {
+		t.Fatalf("wrong set type, got %s", decl.Type)
+	}
+
+	empty := prog.Main.Statements[0].(*ast.AssignStmt).Value.(*ast.SetExpr)
+	if len(empty.Elements) != 0 {
+		t.Fatalf("expected empty set, got %d elements", len(empty.Elements))
+	}
+
+	set := prog.Main.Statements[1].(*ast.AssignStmt).Value.(*ast.SetExpr)
+	if len(set.Elements) != 2 || set.Elements[0].High != nil || set.Elements[1].High == nil {
+		t.Fatalf("expected one member and one range, got %+v", set.Elements)
+	}
+
+	cond := prog.Main.Statements[2].(*ast.IfStmt).Condition.(*ast.BinaryExpr)
+	if cond.Operator.Literal != "in" {
+		t.Fatalf("expected 'in' at the top of the condition, got %s", cond.Operator.Type)
+	}
+	if _, ok := cond.Right.(*ast.BinaryExpr); !ok {
+		t.Fatalf("expected union as right operand of 'in', got %T", cond.Right)
+	}
+}
+
+func TestParser_PointerTypesAndDereference(t *testing.T) {
+	input := `program test;
+type
+  PNode = ^Node;
+  Node = record val: integer; next: PNode end;
+var p: PNode;
+begin
+  new(p);
+  p^.next^.val := 1;
+  p := nil
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := prog.Declarations[0].(*ast.TypeDecl)
+	if decl.Type.String() != "^node" {
+		t.Fatalf("wrong pointer type, got %s", decl.Type)
+	}
+
+	// p^.next^.val parses as ((p^).next)^.val
+	target := prog.Main.Statements[1].(*ast.AssignStmt).Target.(*ast.FieldExpr)
+	inner := target.Record.(*ast.DerefExpr).Pointer.(*ast.FieldExpr)
+	if inner.Field != "next" {
+		t.Fatalf("expected field 'next', got %s", inner.Field)
+	}
+	if _, ok := inner.Record.(*ast.DerefExpr).Pointer.(*ast.Identifier); !ok {
+		t.Fatalf("expected p^ at the root of the chain, got %T", inner.Record)
+	}
+
+	if _, ok := prog.Main.Statements[2].(*ast.AssignStmt).Value.(*ast.NilLiteral); !ok {
+		t.Fatalf("expected nil literal")
+	}
+}
+
+func TestParser_ProgramParametersAndFiles(t *testing.T) {
+	input := `program copy(input, output, data);
+var
+  data: text;
+  log: packed file of integer;
+begin
+  writeln(data, 'x', 1)
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if strings.Join(prog.Params, ",") != "input,output,data" {
+		t.Fatalf("wrong program parameters, got %v", prog.Params)
+	}
+
+	decl := prog.Declarations[1].(*ast.VarDecl)
+	if decl.Type.String() != "packed file of integer" {
+		t.Fatalf("wrong file type, got %s", decl.Type)
+	}
+
+	stmt := prog.Main.Statements[0].(*ast.PrintStmt)
+	if len(stmt.Args) != 3 {
+		t.Fatalf("expected 3 writeln arguments, got %d", len(stmt.Args))
+	}
+}
+
+func TestParserErrors_Files(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"file without of",
+			"program test;\nvar f: file integer;\nbegin\nend.",
+			"Expected 'of' after 'file'",
+		},
+		{
+			"unclosed program parameters",
+			"program test(input, output;\nbegin\nend.",
+			"Expected ')' after program parameters",
+		},
+		{
+			"empty program parameters",
+			"program test();\nbegin\nend.",
+			"Expected program parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_WriteStatements(t *testing.T) {
+	input := `program test;
+begin
+  write('x = ', x:8:2, n:4);
+  writeln;
+  writeln(f, 1)
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	write := prog.Main.Statements[0].(*ast.PrintStmt)
+	if write.Newline || len(write.Args) != 3 {
+		t.Fatalf("expected write with 3 arguments, got %+v", write)
+	}
+	fixed := write.Args[1].(*ast.FormatExpr)
+	if fixed.Width == nil || fixed.Decimals == nil {
+		t.Fatalf("expected width and decimals, got %+v", fixed)
+	}
+	if integer := write.Args[2].(*ast.FormatExpr); integer.Decimals != nil {
+		t.Fatalf("expected no decimals, got %+v", integer)
+	}
+
+	bare := prog.Main.Statements[1].(*ast.PrintStmt)
+	if !bare.Newline || len(bare.Args) != 0 {
+		t.Fatalf("expected bare writeln, got %+v", bare)
+	}
+}
+
+func TestParserErrors_WriteStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"write without arguments",
+			"program test;\nbegin\n  write\nend.",
+			"Expected '(' after 'write'",
+		},
+		{
+			"unclosed writeln",
+			"program test;\nbegin\n  writeln(1:2 3)\nend.",
+			"Expected ')' after writeln arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_UnaryOperatorPrecedence(t *testing.T) {
+	input := `program test;
+begin
+  x := -a * b;
+  x := -a + b;
+  b := x < -1;
+  x := +5
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	value := func(i int) ast.Expr { return prog.Main.Statements[i].(*ast.AssignStmt).Value }
+
+	neg := value(0).(*ast.UnaryExpr)
+	if mul, ok := neg.Operand.(*ast.BinaryExpr); !ok || mul.Operator.Literal != "*" {
+		t.Fatalf("expected -a * b to negate the product, got %T", neg.Operand)
+	}
+
+	sum := value(1).(*ast.BinaryExpr)
+	if _, ok := sum.Left.(*ast.UnaryExpr); sum.Operator.Literal != "+" || !ok {
+		t.Fatalf("expected -a + b to add b to -a, got %q with %T", sum.Operator.Literal, sum.Left)
+	}
+
+	rel := value(2).(*ast.BinaryExpr)
+	if _, ok := rel.Right.(*ast.UnaryExpr); !ok {
+		t.Fatalf("expected a signed right operand of '<', got %T", rel.Right)
+	}
+
+	if plus := value(3).(*ast.UnaryExpr); plus.Operator.Literal != "+" {
+		t.Fatalf("expected unary '+', got %q", plus.Operator.Literal)
+	}
+}
+
+func TestParserErrors_SignInsideTerm(t *testing.T) {
+	input := "program test;\nbegin\n  x := 2 * -3\nend."
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+	if expected := "Unexpected sign '-' inside a term"; p.Errors()[0].Msg != expected {
+		t.Fatalf("expected %q, got %q", expected, p.Errors()[0].Msg)
+	}
+}
+
+func TestParser_IntegerDivisionConstants(t *testing.T) {
+	input := `program test;
+const
+  Quotient = 7 div 2;
+  Remainder = (-7) mod 3;
+  Ratio = 7 / 2;
+  Largest = maxint;
+type
+  Natural = 0..maxint;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []ast.Expr{
+		&ast.IntegerLiteral{Value: 3},
+		&ast.IntegerLiteral{Value: 2},
+		&ast.RealLiteral{Value: 3.5},
+		&ast.IntegerLiteral{Value: math.MaxInt64},
+	}
+	for i, want := range expected {
+		decl := prog.Declarations[i].(*ast.ConstDecl)
+		if value := positioned(decl.Value, ast.Span{}); fmt.Sprintf("%#v", value) != fmt.Sprintf("%#v", want) {
+			t.Fatalf("constant %s value wrong. expected=%#v, got=%#v", decl.Name, want, value)
+		}
+	}
+
+	natural := prog.Declarations[4].(*ast.TypeDecl).Type.(*ast.SubrangeType)
+	if high := natural.High.(*ast.IntegerLiteral); high.Value != math.MaxInt64 {
+		t.Fatalf("expected subrange up to maxint, got %d", high.Value)
+	}
+}
+
+func TestParserErrors_IntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const C = 7 mod 0;", "The divisor of 'mod' must be positive, got 0."},
+		{"const C = 7 div 0;", "Division by zero in constant expression."},
+		{"const C = 7.5 div 2;", "Operator 'div' requires integer constants."},
+		{"const C = maxint + 1;", "Integer overflow in 9223372036854775807 + 1."},
+		{"const C = -maxint - 2;", "Integer overflow in -9223372036854775807 - 2."},
+		{"const C = 99999999999999999999;", "Integers must lie in the range -maxint..maxint, where maxint = 9223372036854775807."},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New("program test;\n" + tt.input + "\nbegin\nend.")
+		p := New(l)
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.input)
+		}
+		if p.Errors()[0].Detail != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.input, tt.expected, p.Errors()[0].Detail)
+		}
+	}
+}
+
+func TestParser_LabelsAndGoto(t *testing.T) {
+	input := `program test;
+label 10, 020;
+var i: integer;
+procedure bail;
+begin
+  goto 20
+end;
+begin
+  i := 0;
+  10: i := i + 1;
+  if i < 3 then goto 10;
+  while i < 5 do
+    begin
+      if i = 4 then goto 20;
+      i := i + 1
+    end;
+  bail;
+  20:
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl, ok := prog.Declarations[1].(*ast.LabelDecl)
+	if !ok || decl.Label != 20 {
+		t.Fatalf("expected declaration of label 20, got %#v", prog.Declarations[1])
+	}
+
+	labeled, ok := prog.Main.Statements[1].(*ast.LabeledStmt)
+	if !ok || labeled.Label != 10 {
+		t.Fatalf("expected statement labeled 10, got %T", prog.Main.Statements[1])
+	}
+	if _, ok := labeled.Stmt.(*ast.AssignStmt); !ok {
+		t.Fatalf("expected labeled assignment, got %T", labeled.Stmt)
+	}
+
+	jump := prog.Main.Statements[2].(*ast.IfStmt).Then.(*ast.GotoStmt)
+	if jump.Label != 10 {
+		t.Fatalf("expected goto 10, got goto %d", jump.Label)
+	}
+
+	last := prog.Main.Statements[len(prog.Main.Statements)-1].(*ast.LabeledStmt)
+	if last.Label != 20 || last.Stmt != nil {
+		t.Fatalf("expected label 20 on an empty statement, got %d: %T", last.Label, last.Stmt)
+	}
+}
+
+func TestParserErrors_LabelsAndGoto(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		line     int
+		column   int
+	}{
+		{"undeclared label", `program test;
+begin
+  10: writeln
+end.`, "Undeclared label 10", 3, 3},
+		{"goto undeclared label", `program test;
+begin
+  goto 10
+end.`, "Undeclared label 10", 3, 8},
+		{"label out of range", `program test;
+label 10000;
+begin
+end.`, "Label 10000 is out of range", 2, 7},
+		{"duplicate declaration", `program test;
+label 10, 10;
+begin
+  10:
+end.`, "Duplicate declaration of label 10", 2, 11},
+		{"label prefixes two statements", `program test;
+label 10;
+begin
+  10: writeln;
+  10: writeln
+end.`, "Label 10 prefixes more than one statement", 5, 3},
+		{"declared label never used", `program test;
+label 10, 20;
+begin
+  10: writeln
+end.`, "Label 20 is declared but does not prefix a statement", 2, 11},
+		{"label of another routine", `program test;
+procedure p;
+label 10;
+begin
+  10: writeln
+end;
+begin
+  goto 10
+end.`, "Undeclared label 10", 8, 8},
+		{"into a compound statement", `program test;
+label 10;
+begin
+  goto 10;
+  begin
+    10: writeln
+  end
+end.`, "goto 10 jumps into a structured statement", 4, 8},
+		{"into an if branch", `program test;
+label 10;
+var b: boolean;
+begin
+  if b then 10: writeln;
+  goto 10
+end.`, "goto 10 jumps into a structured statement", 6, 8},
+		{"between case branches", `program test;
+label 10;
+var i: integer;
+begin
+  case i of
+    1: goto 10;
+    2: 10: writeln
+  end
+end.`, "goto 10 jumps into a structured statement", 6, 13},
+		{"out of a routine into a loop", `program test;
+label 10;
+var b: boolean;
+procedure p;
+begin
+  goto 10
+end;
+begin
+  while b do
+    begin
+      10: p
+    end
+end.`, "goto 10 jumps into a structured statement", 6, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			p.ParseProgram()
+
+			if !p.HasErrors() {
+				t.Fatalf("expected parser errors, got none")
+			}
+			err := p.Errors()[0]
+			if err.Msg != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, err.Msg)
+			}
+			if err.Line != tt.line || err.Column != tt.column {
+				t.Fatalf("wrong position for %q. expected=%d:%d, got=%d:%d", err.Msg, tt.line, tt.column, err.Line, err.Column)
+			}
+		})
+	}
+}
+
+func TestParser_NodePositions(t *testing.T) {
+	input := `program test;
+var x: integer;
+begin
+  x := 1;
+  if x > 0 then
+    writeln(x)
+end.`
+
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if prog.SourceSpan().Start != (ast.Position{Line: 1, Column: 1, Offset: 0}) {
+		t.Errorf("program start wrong: %+v", prog.SourceSpan().Start)
+	}
+	if end := prog.SourceSpan().End; end != (ast.Position{Line: 7, Column: 5, Offset: len(input)}) {
+		t.Errorf("program end wrong: %+v", end)
+	}
+	decl := prog.Declarations[0].(*ast.VarDecl)
+	if decl.Start != (ast.Position{Line: 2, Column: 5, Offset: 18}) {
+		t.Errorf("var declaration position wrong: %+v", decl.Start)
+	}
+	ifStmt := prog.Main.Statements[1].(*ast.IfStmt)
+	if ifStmt.Start.Line != 5 || ifStmt.Start.Column != 3 || ifStmt.End.Line != 6 || ifStmt.End.Column != 15 {
+		t.Errorf("if span wrong: %+v", ifStmt.Span)
+	}
+}
+
+func TestParser_NodeSpans(t *testing.T) {
+	input := `program test;
+const limit = 2 * 5;
+type vec = packed array[1..3, 1..2] of real;
+var a: vec; p: ^integer; i: integer;
+procedure show(var v: vec; n: integer);
+begin
+  writeln(v[n, 1]:8:2)
+end;
+begin
+  a[1, 2] := -a[2, 1] * 3 + 1;
+  p^ := i mod 2;
+  for i := 1 to limit do
+    case i of
+      1, 2: i := i
+    else
+      show(a, i)
+    end
+end.`
+
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	limit := prog.Declarations[0].(*ast.ConstDecl)
+	vec := prog.Declarations[1].(*ast.TypeDecl)
+	show := prog.Declarations[5].(*ast.ProcedureDecl)
+	assign := prog.Main.Statements[0].(*ast.AssignStmt)
+	sum := assign.Value.(*ast.BinaryExpr)
+	sign := sum.Left.(*ast.UnaryExpr)
+	forStmt := prog.Main.Statements[2].(*ast.ForStmt)
+	caseStmt := forStmt.Body.(*ast.CaseStmt)
+	print := show.Body.Statements[0].(*ast.PrintStmt)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{limit, "limit = 2 * 5"},
+		{limit.Value, "2 * 5"},
+		{vec, "vec = packed array[1..3, 1..2] of real"},
+		{vec.Type.(*ast.ArrayType).Index, "1..3"},
+		{prog.Declarations[3].(*ast.VarDecl).Type, "^integer"},
+		{show, "procedure show(var v: vec; n: integer);\nbegin\n  writeln(v[n, 1]:8:2)\nend"},
+		{print, "writeln(v[n, 1]:8:2)"},
+		{print.Args[0], "v[n, 1]:8:2"},
+		{assign, "a[1, 2] := -a[2, 1] * 3 + 1"},
+		{assign.Target, "a[1, 2]"},
+		{assign.Target.(*ast.IndexExpr).Array, "a[1, 2]"},
+		{sum, "-a[2, 1] * 3 + 1"},
+		{sign, "-a[2, 1] * 3"},
+		{sign.Operand, "a[2, 1] * 3"},
+		{prog.Main.Statements[1].(*ast.AssignStmt).Target, "p^"},
+		{forStmt.Final, "limit"},
+		{caseStmt.Branches[0].Labels[1].Low, "2"},
+		{caseStmt.Else, "else\n      show(a, i)"},
+		{caseStmt.Else.Statements[0], "show(a, i)"},
+	}
+
+	for i, tt := range tests {
+		span := tt.node.SourceSpan()
+		if got := input[span.Start.Offset:span.End.Offset]; got != tt.expected {
+			t.Errorf("tests[%d] - %T spans %q, expected %q", i, tt.node, got, tt.expected)
+		}
+	}
+}
+
+func TestParser_ConformantArrays(t *testing.T) {
+	input := `program test;
+procedure p(var a, b: array[lo..hi: integer; l2..h2: char] of real; s: packed array[i..j: integer] of char);
+begin
+  a[lo, l2] := b[hi, h2]
+end;
+begin
+end.`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := prog.Declarations[0].(*ast.ProcedureDecl)
+	if len(decl.Params) != 3 {
+		t.Fatalf("expected 3 parameters, got %d", len(decl.Params))
+	}
+
+	a, b := decl.Params[0], decl.Params[1]
+	if a.Schema == nil || a.Type != "" || !a.IsVar || b.Schema != a.Schema {
+		t.Fatalf("expected a and b to share one schema, got %#v and %#v", a, b)
+	}
+	if got := a.Schema.String(); got != "array[lo..hi: integer] of array[l2..h2: char] of real" {
+		t.Fatalf("wrong schema for a, got %q", got)
+	}
+	inner, ok := a.Schema.Element.(*ast.ConformantArray)
+	if !ok || inner.Low != "l2" || inner.High != "h2" || inner.IndexType != "char" {
+		t.Fatalf("expected nested schema for second dimension, got %#v", a.Schema.Element)
+	}
+
+	s := decl.Params[2]
+	if s.Schema == nil || !s.Schema.Packed || s.Schema.Element.String() != "char" {
+		t.Fatalf("expected packed schema for s, got %#v", s.Schema)
+	}
+	if got := input[s.Schema.Start.Offset:s.Schema.End.Offset]; got != "packed array[i..j: integer] of char" {
+		t.Fatalf("wrong span for schema of s, got %q", got)
+	}
+}
+
+func TestParserErrors_ConformantArrays(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"constant bounds",
+			"program test;\nprocedure p(a: array[1..10: integer] of real);\nbegin\nend;\nbegin\nend.",
+			"Expected bound identifier in conformant array",
+		},
+		{
+			"missing index type",
+			"program test;\nprocedure p(a: array[lo..hi] of real);\nbegin\nend;\nbegin\nend.",
+			"Expected ':' and index type after bound identifiers",
+		},
+		{
+			"packed with two indexes",
+			"program test;\nprocedure p(a: packed array[i..j: integer; k..l: integer] of char);\nbegin\nend;\nbegin\nend.",
+			"Packed conformant array may have only one index",
+		},
+		{
+			"packed with schema element",
+			"program test;\nprocedure p(a: packed array[i..j: integer] of array[k..l: integer] of char);\nbegin\nend;\nbegin\nend.",
+			"Element of a packed conformant array must be a type identifier",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_TurboDialect(t *testing.T) {
+	input := `program test;
+uses crt, dos;
+const
+  mask = $FF;
+  crlf = #13#10;
+  greeting = 'Hi'#33;
+  limit: integer = 10;
+  primes: array[1..3] of integer = (2, 3, 5);
+  origin: point = (x: 0; y: -1);
+var s: string[80];
+begin
+end.`
+
+	p := New(lexer.New(input), WithDialect(ast.Turbo))
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if prog.Dialect != ast.Turbo {
+		t.Fatalf("expected program in the Turbo Pascal dialect, got %s", prog.Dialect)
+	}
+	if strings.Join(prog.Uses, ",") != "crt,dos" {
+		t.Fatalf("expected uses crt, dos, got %v", prog.Uses)
+	}
+
+	constants := map[string]ast.Expr{}
+	for _, decl := range prog.Declarations {
+		if d, ok := decl.(*ast.ConstDecl); ok {
+			constants[d.Name] = d.Value
+		}
+	}
+	if lit, ok := constants["mask"].(*ast.IntegerLiteral); !ok || lit.Value != 255 {
+		t.Fatalf("expected mask = 255, got %#v", constants["mask"])
+	}
+	if lit, ok := constants["crlf"].(*ast.StringLiteral); !ok || lit.Value != "\r\n" {
+		t.Fatalf("expected crlf = \"\\r\\n\", got %#v", constants["crlf"])
+	}
+	if lit, ok := constants["greeting"].(*ast.StringLiteral); !ok || lit.Value != "Hi!" {
+		t.Fatalf("expected greeting = \"Hi!\", got %#v", constants["greeting"])
+	}
+
+	limit := prog.Declarations[3].(*ast.TypedConstDecl)
+	if limit.Name != "limit" || limit.Type.String() != "integer" {
+		t.Fatalf("wrong typed constant, got %#v", limit)
+	}
+	primes := prog.Declarations[4].(*ast.TypedConstDecl)
+	if arr, ok := primes.Value.(*ast.ArrayConstant); !ok || len(arr.Elements) != 3 {
+		t.Fatalf("expected array constant with 3 elements, got %#v", primes.Value)
+	}
+	origin := prog.Declarations[5].(*ast.TypedConstDecl)
+	rec, ok := origin.Value.(*ast.RecordConstant)
+	if !ok || len(rec.Fields) != 2 || rec.Fields[1].Name != "y" {
+		t.Fatalf("expected record constant with fields x and y, got %#v", origin.Value)
+	}
+
+	s := prog.Declarations[6].(*ast.VarDecl)
+	if st, ok := s.Type.(*ast.StringType); !ok || st.Size != 80 {
+		t.Fatalf("expected string[80], got %#v", s.Type)
+	}
+}
+
+func TestParserErrors_TurboDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  ast.Dialect
+		expected string
+	}{
+		{"uses in ISO", "program test;\nuses crt;\nbegin\nend.", ast.ISO, "A uses clause requires the Turbo Pascal dialect"},
+		{"hex literal in ISO", "program test;\nconst m = $FF;\nbegin\nend.", ast.ISO, "Hexadecimal literal $FF requires the Turbo Pascal dialect"},
+		{"char code in ISO", "program test;\nconst cr = #13;\nbegin\nend.", ast.ISO, "Character code #13 requires the Turbo Pascal dialect"},
+		{"typed constant in ISO", "program test;\nconst n: integer = 1;\nbegin\nend.", ast.ISO, "Typed constant 'n' requires the Turbo Pascal dialect"},
+		{"string type in ISO", "program test;\nvar s: string[10];\nbegin\nend.", ast.ISO, "String type string[10] requires the Turbo Pascal dialect"},
+		{"line comment in ISO", "program test; // note\nbegin\nend.", ast.ISO, "A // comment requires the Turbo Pascal dialect"},
+		{"string too long", "program test;\nvar s: string[256];\nbegin\nend.", ast.Turbo, "Invalid string length"},
+		{"char code out of range", "program test;\nconst c = #300;\nbegin\nend.", ast.Turbo, "Character code #300 is out of range"},
+		{"lone dollar", "program test;\nconst m = $;\nbegin\nend.", ast.Turbo, "Illegal character \"$\""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), WithDialect(tt.dialect))
+		p.ParseProgram()
+
+		if !p.HasErrors() {
+			t.Fatalf("%s: expected parser errors, got none", tt.name)
+		}
+		if p.Errors()[0].Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.expected, p.Errors()[0].Msg)
+		}
+	}
+}
+
+func TestParser_LineCommentsFollowDialect(t *testing.T) {
+	input := "program test; // heading\nvar x: integer; // counter\nbegin\n  x := 1 // done\nend."
+
+	p := New(lexer.New(input), WithDialect(ast.Turbo))
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(prog.Main.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(prog.Main.Statements))
+	}
+
+	p = New(lexer.New(input))
+	p.ParseProgram()
+	if len(p.Errors()) != 3 {
+		t.Fatalf("expected one error for each // comment in ISO Pascal, got %d: %v", len(p.Errors()), p.Errors())
+	}
+	for _, err := range p.Errors() {
+		if err.Msg != "A // comment requires the Turbo Pascal dialect" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
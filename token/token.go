@@ -4,17 +4,26 @@ import "strings"
 
 type TokenType string
 
+// Token is a lexical token. Line and Column give the position of its first
+// character and Offset its byte offset in the input; the End fields give the
+// position just past its last character.
 type Token struct {
 	Type    TokenType
 	Literal string
 	Line    int
 	Column  int
+	Offset  int
+
+	EndLine   int
+	EndColumn int
+	EndOffset int
 }
 
 const (
 	// Special
-	ILLEGAL = "ILLEGAL"
-	EOF     = "EOF"
+	ILLEGAL      = "ILLEGAL"
+	EOF          = "EOF"
+	LINE_COMMENT = "LINE_COMMENT" // e.g., // note (Turbo Pascal)
 
 	// Identifiers + literals
 	IDENT      = "IDENT"      // e.g., variable names
@@ -22,6 +31,8 @@ const (
 	REAL_LIT   = "REAL_LIT"   // e.g., 3.14
 	CHAR_LIT   = "CHAR_LIT"   // e.g., 'a'
 	STRING_LIT = "STRING_LIT" // e.g., 'hello'
+	HEX_INT    = "HEX_INT"    // e.g., $FF (Turbo Pascal)
+	CHAR_CODE  = "CHAR_CODE"  // e.g., #13 or #$0D (Turbo Pascal)
 
 	// Operators
 	ASSIGN = "ASSIGN" // :=
@@ -43,7 +54,11 @@ const (
 	COLON     = "COLON"     // :
 	LPAREN    = "LPAREN"    // (
 	RPAREN    = "RPAREN"    // )
+	LBRACKET  = "LBRACKET"  // [
+	RBRACKET  = "RBRACKET"  // ]
+	CARET     = "CARET"     // ^
 	DOT       = "DOT"       // .
+	DOTDOT    = "DOTDOT"    // ..
 
 	// Keywords
 	AND       = "AND"
@@ -82,6 +97,7 @@ const (
 	VAR       = "VAR"
 	WHILE     = "WHILE"
 	WITH      = "WITH"
+	WRITE     = "WRITE"
 	WRITELN   = "WRITELN"
 
 	// Types
@@ -133,6 +149,7 @@ var keywords = map[string]TokenType{
 	"var":       VAR,
 	"while":     WHILE,
 	"with":      WITH,
+	"write":     WRITE,
 	"writeln":   WRITELN,
 	"integer":   INTEGER,
 	"real":      REAL,